@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Ways of writing aliases to the language files.
+const (
+	aliasDuplicate = "duplicate" // repeat the value of the aliased key
+	aliasReference = "reference" // reference the aliased key, where the format can
+)
+
+// Ways of extracting the names of the values of enums with allow_alias.
+const (
+	enumAliasesCanonical = "canonical" // only the first name of every number
+	enumAliasesAll       = "all"       // every name, the later ones written as aliases of the first
+)
+
+// referenceFormats lists the formats able to write an alias as a reference.
+var referenceFormats = map[string]bool{"fluent": true, "i18next": true, "i18next-ns": true}
+
+// alias keeps a renamed key resolving for clients that still use it.
+type alias struct {
+	Key   string `json:"key"`             // key replacing the alias
+	Until string `json:"until,omitempty"` // last day the alias is written, YYYY-MM-DD
+
+	comment string // comment of the alias, if not that of a deprecated key
+}
+
+// UnmarshalJSON also accepts the bare key, so that the rename map written by
+// the migrate command can be used as an aliases file.
+func (a *alias) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Key); err == nil {
+		return nil
+	}
+	type plain alias
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// loadAliases reads an aliases file mapping every deprecated key to the key
+// replacing it, either as a string or as {"key": ..., "until": ...}.
+func loadAliases(filePath string) (map[string]alias, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]alias)
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(filePath), err)
+	}
+	for from, a := range aliases {
+		if a.Key == "" {
+			return nil, fmt.Errorf("%s: alias %s has no key", filepath.Base(filePath), from)
+		}
+		if a.Until != "" {
+			if _, err := time.Parse(time.DateOnly, a.Until); err != nil {
+				return nil, fmt.Errorf("%s: alias %s: until must be a YYYY-MM-DD date", filepath.Base(filePath), from)
+			}
+		}
+	}
+	return aliases, nil
+}
+
+// activeAliases returns the deprecated keys whose alias is written today,
+// sorted, along with warnings about expired aliases and aliases that cannot be
+// written because their key is still extracted or their target no longer is.
+func activeAliases(aliases map[string]alias, entries []extract.Entry, today string) ([]string, []string) {
+	extracted := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		extracted[e.Key] = true
+	}
+	var active, warnings []string
+	for from, a := range aliases {
+		switch {
+		case a.Until != "" && a.Until < today:
+			warnings = append(warnings, fmt.Sprintf("alias %s of %s expired on %s and is no longer written; remove it", from, a.Key, a.Until))
+		case extracted[from]:
+			warnings = append(warnings, fmt.Sprintf("alias %s of %s is skipped: the key is still extracted", from, a.Key))
+		case !extracted[a.Key]:
+			warnings = append(warnings, fmt.Sprintf("alias %s is skipped: its key %s is not extracted", from, a.Key))
+		default:
+			active = append(active, from)
+		}
+	}
+	sort.Strings(active)
+	sort.Strings(warnings)
+	return active, warnings
+}
+
+// appendAliases returns entries followed by an entry for every active alias,
+// a copy of the aliased entry under the deprecated key. Its fallback is the
+// value the aliased key is written with: its translation in existing, if any,
+// or else its own fallback.
+func appendAliases(entries []extract.Entry, aliases map[string]alias, active []string, existing map[string]string, mode string) []extract.Entry {
+	byKey := make(map[string]extract.Entry, len(entries))
+	for _, e := range entries {
+		byKey[e.Key] = e
+	}
+	for _, from := range active {
+		e := byKey[aliases[from].Key]
+		e.Alias = e.Key
+		e.Key, e.Name = from, from
+		e.Comment = aliases[from].comment
+		if e.Comment == "" {
+			e.Comment = fmt.Sprintf("Deprecated alias of %s", e.Alias)
+		}
+		e.Reference = mode == aliasReference
+		if value := existing[e.Alias]; value != "" {
+			e.Fallback = value
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// splitEnumAliases moves the later names of the values of enums with
+// allow_alias out of the entries, into aliases of the first name of their
+// number, so that they carry its translations instead of being translated
+// apart. It returns the remaining entries, the aliases and the keys of those
+// added, sorted.
+func splitEnumAliases(entries []extract.Entry, aliases map[string]alias) ([]extract.Entry, map[string]alias, []string) {
+	var kept []extract.Entry
+	var keys []string
+	for _, e := range entries {
+		if e.Canonical == "" {
+			kept = append(kept, e)
+			continue
+		}
+		if aliases == nil {
+			aliases = make(map[string]alias)
+		}
+		aliases[e.Key] = alias{Key: e.Canonical, comment: fmt.Sprintf("Alias of %s (allow_alias)", e.Canonical)}
+		keys = append(keys, e.Key)
+	}
+	sort.Strings(keys)
+	return kept, aliases, keys
+}
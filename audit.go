@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// errBundleNotFound reports a language file missing from the deployed bundles.
+var errBundleNotFound = errors.New("not deployed")
+
+// auditCommand implements the audit command, which compares the language files
+// deployed in a directory or at a URL with the keys of the current protos,
+// without writing anything, and fails listing the drift: keys missing from the
+// bundles, keys translated from a source message that has changed since, and
+// keys the protos no longer define.
+func auditCommand(fs *flag.FlagSet) func(args []string) error {
+	ef := addExtractFlags(fs)
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the deployed language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout of each request for bundles deployed at a URL")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: i18n-gen audit [flags] <bundle directory or URL>\n")
+		fs.PrintDefaults()
+	}
+
+	return func(args []string) error {
+		if len(args) != 1 {
+			fs.Usage()
+			return errUsage
+		}
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		x, err := ef.extract(false, nil)
+		if err != nil {
+			return err
+		}
+		entries := x.entries
+
+		bundles := args[0]
+		if strings.HasPrefix(bundles, "http://") || strings.HasPrefix(bundles, "https://") {
+			// Bundles at a URL are fetched to a scratch directory the loaders read
+			dir, err := os.MkdirTemp("", "i18n-gen-audit-")
+			if err != nil {
+				return fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+			defer os.RemoveAll(dir)
+			client := &http.Client{Timeout: *timeout}
+			for _, lang := range splitLanguages(*languages) {
+				rel := inFormat.Path("", lang)
+				if err := fetchBundle(client, bundles, rel, filepath.Join(dir, rel)); err != nil && !errors.Is(err, errBundleNotFound) {
+					return fmt.Errorf("failed to fetch %s: %w", rel, err)
+				}
+			}
+			bundles = dir
+		}
+
+		var problems []string
+		for _, lang := range splitLanguages(*languages) {
+			langPath := inFormat.Path(bundles, lang)
+			name, _ := filepath.Rel(bundles, langPath)
+			if _, err := os.Stat(langPath); os.IsNotExist(err) {
+				problems = append(problems, fmt.Sprintf("%s: %v", name, errBundleNotFound))
+				continue
+			}
+			values, err := loadLanguage(inFormat, langPath, nil)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: unreadable: %v", name, err))
+				continue
+			}
+			var hashes map[string]string
+			if *format == "toml" {
+				if hashes, err = emit.TOMLHashes(langPath); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: unreadable: %v", name, err))
+					continue
+				}
+			}
+			problems = append(problems, auditBundle(entries, x.aliasKeys, values, hashes, name)...)
+		}
+
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				log.Println(problem)
+			}
+			return errFailed
+		}
+		log.Printf("The deployed bundles match the %d key(s) of the protos.\n", len(entries))
+		return nil
+	}
+}
+
+// auditBundle compares a deployed language file with the extracted entries and
+// returns a message for each kind of drift: keys missing from the file, keys
+// whose hash is not that of their current source message, and extra keys the
+// protos no longer define. Hashes are only compared when the format has them.
+// The keys of aliases gen writes are not extra.
+func auditBundle(entries []extract.Entry, aliasKeys []string, values, hashes map[string]string, name string) []string {
+	expected := make(map[string]bool, len(entries)+len(aliasKeys))
+	for _, key := range aliasKeys {
+		expected[key] = true
+	}
+	var missing, stale []string
+	for _, e := range entries {
+		expected[e.Key] = true
+		if _, ok := values[e.Key]; !ok {
+			missing = append(missing, e.Key)
+			continue
+		}
+		if hash, ok := hashes[e.Key]; ok && hash != emit.MessageHash(e) {
+			stale = append(stale, e.Key)
+		}
+	}
+	var extra []string
+	for _, key := range sortedKeys(values) {
+		if !expected[key] {
+			extra = append(extra, key)
+		}
+	}
+
+	var problems []string
+	for _, group := range []struct {
+		kind string
+		keys []string
+	}{{"missing", missing}, {"stale", stale}, {"extra", extra}} {
+		if len(group.keys) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d %s key(s): %s", name, len(group.keys), group.kind, strings.Join(group.keys, ", ")))
+		}
+	}
+	return problems
+}
+
+// fetchBundle downloads the language file at the path rel below the base URL
+// to filePath. A file the server does not have is errBundleNotFound.
+func fetchBundle(client *http.Client, baseURL, rel, filePath string) error {
+	url := strings.TrimSuffix(baseURL, "/") + "/" + filepath.ToSlash(rel)
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errBundleNotFound
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
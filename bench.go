@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// benchResult is the measurement of one benchmark, the median of its runs.
+type benchResult struct {
+	Name       string  `json:"name"`
+	Items      int     `json:"items"`       // files parsed, keys merged or language files written
+	NsPerItem  float64 `json:"ns_per_item"` // median time per item
+	AllocBytes uint64  `json:"alloc_bytes"` // bytes allocated by the median run
+	TotalNs    int64   `json:"total_ns"`    // median time of a run
+}
+
+// benchCommand implements the bench command, which times the hot paths of
+// generation, parsing the proto files, merging their keys and writing the
+// language files, on a synthetic corpus or the given proto files, and prints
+// metrics comparable across versions. With a baseline it fails on a
+// regression.
+func benchCommand(fs *flag.FlagSet) func(args []string) error {
+	var protoPatterns stringList
+	fs.Var(&protoPatterns, "P", "Proto files to benchmark on: a glob, ** matching any number of directories, e.g. api/**/*.proto, a directory or a file; may be repeated (default: a synthetic corpus)")
+	files := fs.Int("files", 1000, "Number of proto files of the synthetic corpus")
+	keys := fs.Int("keys", 20, "Number of keys per proto file of the synthetic corpus")
+	languages := fs.Int("langs", 5, "Number of language files written")
+	format := fs.String("format", "toml", "Output format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	runs := fs.Int("runs", 5, "Number of runs of each benchmark; the median is reported")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "Number of proto files parsed concurrently")
+	jsonName := fs.String("json", "", "Write the results as JSON to this file, to use as a later baseline (optional)")
+	baselineFile := fs.String("baseline", "", "JSON results of an earlier run to compare with (optional)")
+	maxRegression := fs.Float64("max-regression", 10, "Fail when a benchmark is this many percent slower per item than the baseline")
+
+	return func(_ []string) error {
+		outFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown output format: %s", *format)
+		}
+		if *runs < 1 {
+			return usageErrorf("invalid number of runs: %d", *runs)
+		}
+
+		dir, err := os.MkdirTemp("", "i18n-gen-bench")
+		if err != nil {
+			return fmt.Errorf("failed to create benchmark directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		var protoFiles []string
+		if len(protoPatterns) > 0 {
+			if protoFiles, err = findProtoFiles(protoPatterns); err != nil {
+				return fmt.Errorf("failed to find proto files: %w", err)
+			}
+		} else if protoFiles, err = writeBenchCorpus(filepath.Join(dir, "proto"), *files, *keys); err != nil {
+			return fmt.Errorf("failed to write benchmark corpus: %w", err)
+		}
+		if len(protoFiles) == 0 {
+			return fmt.Errorf("no proto files match %s", strings.Join(protoPatterns, ", "))
+		}
+
+		var parsed []extract.File
+		parse := bench("parse", len(protoFiles), *runs, func() {
+			parsed = extract.ParseFiles(protoFiles, extract.Options{Workers: *parallel})
+		})
+		for _, p := range parsed {
+			if p.Err != nil {
+				return fmt.Errorf("failed to parse proto file %s: %w", p.Path, p.Err)
+			}
+		}
+
+		var entries []extract.Entry
+		total := 0
+		for _, p := range parsed {
+			total += len(p.Entries)
+		}
+		merge := bench("merge", total, *runs, func() {
+			entries = sortEntries(extract.Unique(extract.Merge(parsed)), sortAlpha, "en", false)
+		})
+		if err := applyEmptyValuePolicy(entries, emptySource, true); err != nil {
+			return err
+		}
+
+		langs := make([]string, *languages)
+		for i := range langs {
+			langs[i] = fmt.Sprintf("l%d", i)
+		}
+		outputDir := filepath.Join(dir, "out")
+		var writeErr error
+		write := func() {
+			for _, lang := range langs {
+				if err := outFormat.Write(entries, lang, outFormat.Path(outputDir, lang), false, emit.Encoding{}, nil); err != nil && writeErr == nil {
+					writeErr = err
+				}
+			}
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		write() // create the files, so that the runs measure updating them
+		update := bench("write", len(langs), *runs, write)
+		if writeErr != nil {
+			return fmt.Errorf("failed to write language files: %w", writeErr)
+		}
+
+		results := []benchResult{parse, merge, update}
+		for _, r := range results {
+			fmt.Printf("%-6s %8d items %12s %14.0f ns/item %12d B alloc\n", r.Name, r.Items, time.Duration(r.TotalNs).Round(time.Microsecond), r.NsPerItem, r.AllocBytes)
+		}
+
+		if *jsonName != "" {
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to write results: %w", err)
+			}
+			if err := os.WriteFile(*jsonName, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write results: %w", err)
+			}
+		}
+
+		if *baselineFile != "" {
+			data, err := os.ReadFile(*baselineFile)
+			if err != nil {
+				return fmt.Errorf("failed to read baseline: %w", err)
+			}
+			var baseline []benchResult
+			if err := json.Unmarshal(data, &baseline); err != nil {
+				return fmt.Errorf("failed to parse baseline: %w", err)
+			}
+			if regressions := benchRegressions(results, baseline, *maxRegression); len(regressions) > 0 {
+				for _, r := range regressions {
+					log.Println(r)
+				}
+				return errFailed
+			}
+		}
+		return nil
+	}
+}
+
+// bench runs f the given number of times and returns the median run.
+func bench(name string, items, runs int, f func()) benchResult {
+	results := make([]benchResult, runs)
+	for i := range results {
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		f()
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		results[i] = benchResult{Name: name, Items: items, TotalNs: elapsed.Nanoseconds(), AllocBytes: after.TotalAlloc - before.TotalAlloc}
+	}
+	slices.SortFunc(results, func(a, b benchResult) int { return int(a.TotalNs - b.TotalNs) })
+	median := results[len(results)/2]
+	median.NsPerItem = float64(median.TotalNs) / float64(max(items, 1))
+	return median
+}
+
+// benchRegressions returns a message for every benchmark that is more than
+// maxPercent slower per item than in the baseline.
+func benchRegressions(results, baseline []benchResult, maxPercent float64) []string {
+	var regressions []string
+	for _, r := range results {
+		i := slices.IndexFunc(baseline, func(b benchResult) bool { return b.Name == r.Name })
+		if i < 0 || baseline[i].NsPerItem == 0 {
+			continue
+		}
+		change := 100 * (r.NsPerItem - baseline[i].NsPerItem) / baseline[i].NsPerItem
+		if change > maxPercent {
+			regressions = append(regressions, fmt.Sprintf("%s: %.0f ns/item is %.1f%% slower than the baseline of %.0f ns/item, more than %.1f%%", r.Name, r.NsPerItem, change, baseline[i].NsPerItem, maxPercent))
+		}
+	}
+	return regressions
+}
+
+// writeBenchCorpus writes proto files with an enum of commented values and a
+// message with cel rules, half of the keys each, and returns their paths.
+func writeBenchCorpus(dir string, files, keys int) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	paths := make([]string, files)
+	for f := range paths {
+		var b strings.Builder
+		fmt.Fprintf(&b, "syntax = \"proto3\";\n\npackage bench.f%d.v1;\n\nimport \"buf/validate/validate.proto\";\n\nenum ErrorReason%d {\n", f, f)
+		for k := 0; k < (keys+1)/2; k++ {
+			fmt.Fprintf(&b, "  // Error %d of file %d, with {{.Name}} as placeholder\n  F%d_ERROR_%d = %d;\n", k, f, f, k, k)
+		}
+		fmt.Fprintf(&b, "}\n\nmessage Request%d {\n", f)
+		for k := 0; k < keys/2; k++ {
+			fmt.Fprintf(&b, "  string field_%d = %d [(buf.validate.field).cel = {\n    id: \"f%d.field_%d.invalid\"\n    message: \"field %d must not be empty\"\n    expression: \"this != ''\"\n  }];\n", k, k+1, f, k, k)
+		}
+		b.WriteString("}\n")
+		paths[f] = filepath.Join(dir, fmt.Sprintf("f%d.proto", f))
+		if err := os.WriteFile(paths[f], []byte(b.String()), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// bundleBudget limits the size of every language file. Zero values disable a
+// limit.
+type bundleBudget struct {
+	MaxBytes int64 // maximum file size in bytes
+	MaxKeys  int   // maximum number of keys
+}
+
+// namespaceWeight is the share of a language file taken by one namespace.
+type namespaceWeight struct {
+	name  string
+	keys  int
+	bytes int64
+}
+
+// checkBundleBudget returns a message for every language file over budget,
+// suggesting the largest namespaces to split into a separate bundle. The
+// namespace of a key is its first dot separated segment, or the enum or
+// message it comes from when it has none.
+func checkBundleBudget(entries []extract.Entry, outFormat emit.Format, outputDir string, langs []string, budget bundleBudget) ([]string, error) {
+	var violations []string
+	for _, lang := range langs {
+		langPath := outFormat.Path(outputDir, lang)
+		info, err := os.Stat(langPath)
+		if err != nil {
+			return nil, err
+		}
+		values, err := loadLanguage(outFormat, langPath, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var over []string
+		if budget.MaxBytes > 0 && info.Size() > budget.MaxBytes {
+			over = append(over, fmt.Sprintf("%d bytes exceed the budget of %d", info.Size(), budget.MaxBytes))
+		}
+		if budget.MaxKeys > 0 && len(values) > budget.MaxKeys {
+			over = append(over, fmt.Sprintf("%d keys exceed the budget of %d", len(values), budget.MaxKeys))
+		}
+		if len(over) == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("%s: %s", filepath.Base(langPath), strings.Join(over, ", "))
+		if split := splitSuggestion(entries, values, info.Size(), budget); len(split) > 0 {
+			message += "; consider splitting out " + strings.Join(split, ", ")
+		}
+		violations = append(violations, message)
+	}
+	return violations, nil
+}
+
+// splitSuggestion returns the largest namespaces of a language file, by
+// estimated size, whose removal brings it within the budget. The namespace of
+// a key is its i18n-group, or else the first segment of the key or its path.
+func splitSuggestion(entries []extract.Entry, values map[string]string, size int64, budget bundleBudget) []string {
+	weights := make(map[string]*namespaceWeight)
+	var total int64
+	for _, e := range entries {
+		value, ok := values[e.Key]
+		if !ok {
+			continue
+		}
+		name, _, dotted := strings.Cut(e.Key, ".")
+		switch {
+		case e.Group != "":
+			name = e.Group
+		case !dotted:
+			name, _, _ = strings.Cut(e.Path, ".")
+		}
+		w := weights[name]
+		if w == nil {
+			w = &namespaceWeight{name: name}
+			weights[name] = w
+		}
+		w.keys++
+		w.bytes += int64(len(e.Key) + len(value))
+		total += int64(len(e.Key) + len(value))
+	}
+	if len(weights) < 2 || total == 0 {
+		return nil // nothing to split
+	}
+
+	sorted := make([]*namespaceWeight, 0, len(weights))
+	for _, w := range weights {
+		sorted = append(sorted, w)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].bytes != sorted[j].bytes {
+			return sorted[i].bytes > sorted[j].bytes
+		}
+		return sorted[i].name < sorted[j].name
+	})
+
+	// File overhead, such as comments and syntax, is spread evenly over the keys
+	keys := len(values)
+	var split []string
+	for _, w := range sorted[:len(sorted)-1] {
+		estimate := w.bytes * size / total
+		split = append(split, fmt.Sprintf("%s (%d keys, ~%d bytes)", w.name, w.keys, estimate))
+		size -= estimate
+		keys -= w.keys
+		if (budget.MaxBytes == 0 || size <= budget.MaxBytes) && (budget.MaxKeys == 0 || keys <= budget.MaxKeys) {
+			break
+		}
+	}
+	return split
+}
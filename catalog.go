@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Catalog lists the extracted keys with their proto metadata, independent of
+// any language file, for tools such as dashboards and support consoles.
+type Catalog struct {
+	// Library is the namespace of the keys of a shared library, set with -library.
+	Library string         `json:"library,omitempty"`
+	Entries []CatalogEntry `json:"entries"`
+}
+
+// CatalogEntry describes one key. Enum values carry their enum and number,
+// validation rules and labels their message, field and expression.
+type CatalogEntry struct {
+	Key string `json:"key"`
+	// ID is the stable numeric id of the key, with -key-ids.
+	ID      int    `json:"id,omitempty"`
+	Kind    string `json:"kind"`
+	Source  string `json:"source,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Package string `json:"package,omitempty"`
+	Group   string `json:"group,omitempty"`
+
+	Enum      string             `json:"enum,omitempty"`
+	Number    *int               `json:"number,omitempty"`
+	CodeRange *extract.CodeRange `json:"code_range,omitempty"`
+	GRPCCode  string             `json:"grpc_code,omitempty"`
+
+	Message    string `json:"message,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Expression string `json:"expression,omitempty"`
+
+	File string `json:"file"`
+	Line int    `json:"line,omitempty"`
+}
+
+// writeCatalog writes the catalog of the provided entries, of the given library
+// if any, to a JSON file.
+func writeCatalog(entries []extract.Entry, library, filePath string) error {
+	catalog := Catalog{Library: library, Entries: make([]CatalogEntry, 0, len(entries))}
+	for _, e := range entries {
+		entry := CatalogEntry{
+			Key:     e.Key,
+			ID:      e.ID,
+			Kind:    e.Kind,
+			Source:  textutil.Unescape(e.Message),
+			Comment: e.Comment,
+			Package: e.Package,
+			Group:   e.Group,
+			File:    e.File,
+			Line:    e.Line,
+		}
+		switch e.Kind {
+		case extract.KindEnum:
+			number := e.Number
+			entry.Enum, entry.Number = e.Path, &number
+			entry.CodeRange, entry.GRPCCode = e.CodeRange, e.GRPCCode
+		case extract.KindCEL, extract.KindRule, extract.KindPGV, extract.KindLabel:
+			if i := strings.LastIndex(e.Path, "."); i >= 0 {
+				entry.Message, entry.Field = e.Path[:i], e.Path[i+1:]
+			} else {
+				entry.Message = e.Path
+			}
+			entry.Expression = textutil.Unescape(e.Expression)
+		}
+		catalog.Entries = append(catalog.Entries, entry)
+	}
+
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode catalog: %w", err)
+	}
+	if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write catalog: %w", err)
+	}
+	return nil
+}
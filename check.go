@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// checkCatalog compares the keys a language file would be generated with, its
+// entries and aliases, to those it has, and returns a message for each kind of
+// difference generation would make: keys missing from the file, extra keys no
+// longer extracted unless the stale mode keeps them, and keys without a value
+// generation would fill with a fallback or default translation. The values
+// left empty by -empty-value blank are thus not reported.
+func checkCatalog(entries []extract.Entry, aliasKeys []string, existing map[string]string, lang, langPath, staleMode string) []string {
+	expected := make(map[string]bool, len(entries)+len(aliasKeys))
+	var missing, empty []string
+	for _, e := range entries {
+		expected[e.Key] = true
+		value, ok := existing[e.Key]
+		seeded, _ := optionTranslation(e.Translations, lang)
+		switch {
+		case !ok:
+			missing = append(missing, e.Key)
+		case value == "" && (e.Fallback != "" || seeded != ""):
+			empty = append(empty, e.Key)
+		}
+	}
+	for _, key := range aliasKeys {
+		expected[key] = true
+		if _, ok := existing[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	var extra []string
+	for _, key := range sortedKeys(existing) {
+		if !expected[key] && staleMode != staleKeep {
+			extra = append(extra, key)
+		}
+	}
+
+	var problems []string
+	name := filepath.Base(langPath)
+	for _, group := range []struct {
+		kind string
+		keys []string
+	}{{"missing", missing}, {"extra", extra}, {"empty", empty}} {
+		if len(group.keys) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d %s key(s): %s", name, len(group.keys), group.kind, strings.Join(group.keys, ", ")))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// checkCodeRanges returns a message for every enum value outside the code range
+// of its enum. Zero values are exempt, as proto3 requires every enum to start
+// with one.
+func checkCodeRanges(entries []extract.Entry) []string {
+	var violations []string
+	for _, e := range entries {
+		if e.CodeRange == nil || e.Number == 0 {
+			continue
+		}
+		if e.Number < e.CodeRange.Min || e.Number > e.CodeRange.Max {
+			violations = append(violations, fmt.Sprintf("%s:%d: %s = %d is outside the code range %d-%d of %s",
+				e.File, e.Line, e.Name, e.Number, e.CodeRange.Min, e.CodeRange.Max, e.Path))
+		}
+	}
+	return violations
+}
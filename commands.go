@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// command is a subcommand of the tool. setup defines the command's flags on fs
+// and returns the function running the command once they are parsed, which
+// returns the error the command fails with.
+type command struct {
+	name    string
+	summary string
+	setup   func(fs *flag.FlagSet) func(args []string) error
+	// aliases are former names of the command, still accepted.
+	aliases []string
+	// configIgnore are the flags of the command meaning something else than
+	// the options of the config file named like them, which are not applied.
+	configIgnore []string
+}
+
+// commands lists the subcommands. Without one, the language files are generated.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "gen", summary: "Generate or update the language files from the protos", setup: generateCommand},
+		{name: "check", summary: "Fail when the language files are not up to date with the protos, without writing", setup: presetCommand(generateCommand, "check", "true")},
+		{name: "sync", summary: "Write the source-language translations back into the proto comments", setup: syncCommentsCommand, aliases: []string{"sync-comments"}, configIgnore: []string{"L"}},
+		{name: "import", summary: "Merge translated XLIFF files into the language files", setup: xliffImportCommand, aliases: []string{"xliff-import"}},
+		{name: "export", summary: "Write XLIFF files for translators", setup: xliffExportCommand, aliases: []string{"xliff-export"}},
+		{name: "stats", summary: "Report the translation progress of every language", setup: statsCommand},
+		{name: "prune", summary: "Generate the language files, removing the keys no longer extracted from the protos", setup: presetCommand(generateCommand, "stale", stalePrune)},
+		{name: "migrate", summary: "Convert hand-written language files to the generated keys and format", setup: migrateCommand},
+		{name: "doctor", summary: "Check the environment and options of a generation run", setup: doctorCommand},
+		{name: "completion", summary: "Print a shell completion script", setup: completionCommand},
+		{name: "plugin", summary: "Run as a protoc plugin", setup: pluginCommand},
+		{name: "pack", summary: "Bundle the language files into an archive", setup: packCommand, configIgnore: []string{"archive"}},
+		{name: "lock", summary: "Sign off the values of keys", setup: lockCommand},
+		{name: "review", summary: "Set the review state of translations, or review them interactively", setup: reviewCommand},
+		{name: "qa", summary: "Score the translations of every language against the source language", setup: qaCommand, configIgnore: []string{"report"}},
+		{name: "bench", summary: "Time parsing, merging and writing on a synthetic corpus or the given protos", setup: benchCommand},
+		{name: "golden", summary: "Write a golden-file test of the generated files", setup: goldenCommand, configIgnore: []string{"package"}},
+		{name: "fallbacks", summary: "List the keys looked up without a translation in runtime logs", setup: fallbacksCommand},
+		{name: "merge", summary: "Combine the language files of shared libraries", setup: mergeCommand},
+		{name: "audit", summary: "Report the drift of deployed language files from the protos, without writing", setup: auditCommand},
+		{name: "version", summary: "Print the version", setup: versionCommand},
+		{name: "help", summary: "Print the commands, or the flags of a command", setup: helpCommand},
+	}
+}
+
+// findCommand returns the command of a name or former name.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// setupCommand defines the flags of a command on fs, with -config for the
+// commands that do not define it, and returns the function running the command
+// once they are parsed, which first applies the config file to the flags not
+// given on the command line.
+func setupCommand(cmd command, fs *flag.FlagSet) func(args []string) error {
+	run := cmd.setup(fs)
+	if fs.Lookup("config") == nil {
+		fs.String("config", "", configUsage)
+	}
+	return func(args []string) error {
+		if _, err := applyConfig(fs, fs.Lookup("config").Value.String(), cmd.configIgnore...); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return run(args)
+	}
+}
+
+// presetCommand returns the setup of a command running another one with a flag
+// preset as if given on the command line, so that the config file does not
+// override it.
+func presetCommand(setup func(fs *flag.FlagSet) func(args []string) error, name, value string) func(fs *flag.FlagSet) func(args []string) error {
+	return func(fs *flag.FlagSet) func(args []string) error {
+		run := setup(fs)
+		fs.Lookup(name).DefValue = value
+		fs.Set(name, value)
+		return run
+	}
+}
+
+// Exit statuses of a command that fails, and of one given invalid flags or
+// arguments, as the flag package exits with.
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// usageError is an error in the flags or arguments of a command.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+
+func (e usageError) Unwrap() error { return e.err }
+
+// usageErrorf formats a usageError.
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// reportedError is an error a command already reported, such as on the event
+// stream of gen, which is not logged again.
+type reportedError struct {
+	err error
+}
+
+func (e reportedError) Error() string { return e.err.Error() }
+
+func (e reportedError) Unwrap() error { return e.err }
+
+// errFailed ends a command whose failures, such as the problems found by a
+// check, were all reported as they were found.
+var errFailed = reportedError{errors.New("failed")}
+
+// errUsage ends a command that printed its usage, given the wrong arguments.
+var errUsage = reportedError{usageError{errors.New("usage")}}
+
+// exit logs the error a command failed with, unless it was reported, and exits
+// with its exitStatus. It returns when the command succeeded.
+func exit(err error) {
+	if err == nil {
+		return
+	}
+	if !errors.As(err, new(reportedError)) {
+		log.Printf("%s\n", sentence(err.Error()))
+	}
+	os.Exit(exitStatus(err))
+}
+
+// exitStatus returns the status a command returning err exits with.
+func exitStatus(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, new(usageError)):
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// sentence capitalizes the first word of an error message to log it, unless it
+// is a name such as that of a file.
+func sentence(message string) string {
+	word, _, _ := strings.Cut(message, " ")
+	if word == "" || strings.ToLower(word) != word || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return message
+	}
+	r, size := utf8.DecodeRuneInString(message)
+	return string(unicode.ToUpper(r)) + message[size:]
+}
+
+// printCommands prints the usage of the tool with its commands.
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: i18n-gen <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun i18n-gen help <command> for the flags of a command.\n")
+}
+
+// helpCommand implements the help command, which prints the commands, or the
+// usage and flags of the command given.
+func helpCommand(fs *flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) == 0 {
+			printCommands(os.Stdout)
+			return nil
+		}
+		cmd, ok := findCommand(args[0])
+		if !ok {
+			return usageErrorf("unknown command: %s", args[0])
+		}
+		cmdFlags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmdFlags.SetOutput(os.Stdout)
+		cmdFlags.Usage = func() {
+			fmt.Fprintf(cmdFlags.Output(), "Usage: i18n-gen %s [flags]\n\n%s.\n\n", cmd.name, cmd.summary)
+			cmdFlags.PrintDefaults()
+		}
+		setupCommand(cmd, cmdFlags) // commands taking arguments replace the usage
+		cmdFlags.Usage()
+		return nil
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testProto is a proto file of two keys the command tests extract.
+const testProto = `syntax = "proto3";
+package demo.v1;
+
+enum ErrorReason {
+  ERROR_REASON_UNSPECIFIED = 0;
+  // User not found
+  USER_NOT_FOUND = 1;
+}
+`
+
+// runCommand runs a command line as main does, with standard input read from
+// stdin and standard output discarded, and returns its exit status.
+func runCommand(t *testing.T, stdin string, args ...string) int {
+	t.Helper()
+	dir := t.TempDir()
+	in := filepath.Join(dir, "stdin")
+	if err := os.WriteFile(in, []byte(stdin), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inFile, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inFile.Close()
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+	stdin0, stdout0 := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = inFile, outFile
+	log.SetOutput(io.Discard)
+	defer func() {
+		os.Stdin, os.Stdout = stdin0, stdout0
+		log.SetOutput(os.Stderr)
+	}()
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		t.Fatalf("unknown command %s", args[0])
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	run := setupCommand(cmd, fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage // as the flag package exits
+	}
+	return exitStatus(run(fs.Args()))
+}
+
+// writeTestProto writes testProto to a directory and returns its path.
+func writeTestProto(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "errors.proto")
+	if err := os.WriteFile(path, []byte(testProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	proto := writeTestProto(t, dir)
+	out := filepath.Join(dir, "i18n")
+	missing := filepath.Join(dir, "missing")
+	if status := runCommand(t, "", "gen", "-P", proto, "-O", out, "-L", "en"); status != 0 {
+		t.Fatalf("gen exited with %d", status)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+	}{
+		{[]string{"gen", "-P", proto, "-O", filepath.Join(dir, "gen"), "-L", "en"}, 0},
+		{[]string{"gen", "-P", proto, "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"gen", "-P", missing + ".proto", "-O", out}, exitFailure},
+		{[]string{"gen", "-P", proto, "-O", out, "-L", "en", "-key-max-len", "1"}, exitFailure},
+		{[]string{"gen", "-bogus"}, exitUsage},
+		{[]string{"gen", "-P", proto, "-O", out, "-require-review", "reviewed"}, exitUsage},
+		{[]string{"gen", "-P", proto, "-O", out, "-go-out", filepath.Join(dir, "i18n-go"), "-format", "jsonc"}, exitUsage},
+		{[]string{"gen", "-P", proto, "-O", out, "-go-out", filepath.Join(dir, "i18n-go"), "-encoding", "utf-16le"}, exitUsage},
+		{[]string{"check", "-P", proto, "-O", out, "-sort", "bogus"}, exitUsage},
+		{[]string{"check", "-P", proto, "-O", missing, "-L", "en"}, exitFailure},
+		{[]string{"prune", "-P", proto, "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"sync", "-P", proto, "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"sync", "-P", missing + ".proto", "-O", out}, exitFailure},
+		{[]string{"import", "-O", out}, exitUsage},
+		{[]string{"import", "-P", proto, "-O", out, missing + ".xlf"}, exitFailure},
+		{[]string{"export", "-P", proto, "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"export", "-P", missing + ".proto", "-O", out}, exitFailure},
+		{[]string{"stats", "-P", proto, "-O", out, "-L", "en"}, 0},
+		{[]string{"stats", "-P", proto, "-O", out, "-review", "review.json", "-L", "en"}, 0},
+		{[]string{"stats", "-P", proto, "-O", out, "-L", "en", "-outdated"}, exitUsage},
+		{[]string{"stats", "-P", proto, "-O", out, "-L", "en", "-modified", "modified.json", "-outdated"}, 0},
+		{[]string{"stats", "-P", proto, "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"stats", "-P", missing + ".proto", "-O", out}, exitFailure},
+		{[]string{"migrate", "-O", out}, exitUsage},
+		{[]string{"migrate", "-P", proto, "-O", filepath.Join(dir, "migrate"), missing + ".toml"}, exitFailure},
+		{[]string{"doctor", "-P", missing + ".proto", "-O", out}, exitFailure},
+		{[]string{"completion", "bash"}, 0},
+		{[]string{"completion"}, exitUsage},
+		{[]string{"completion", "tcsh"}, exitUsage},
+		{[]string{"pack", "-O", out}, exitUsage},
+		{[]string{"pack", "-O", out, "-version", "1", "-archive", "bogus"}, exitUsage},
+		{[]string{"pack", "-O", out, "-version", "1", "-format", "bogus"}, exitUsage},
+		{[]string{"pack", "-O", missing, "-version", "1"}, exitFailure},
+		{[]string{"lock", "-O", out}, exitUsage},
+		{[]string{"lock", "-O", out, "-format", "bogus", "USER_NOT_FOUND"}, exitUsage},
+		{[]string{"review", "-O", out, "-state", "bogus"}, exitUsage},
+		{[]string{"review", "-O", out, "-L", "en", "USER_NOT_FOUND"}, exitUsage},
+		{[]string{"review", "-O", out, "-review", "review.json", "-L", "en", "USER_NOT_FOUND"}, 0},
+		{[]string{"qa", "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"bench", "-runs", "0"}, exitUsage},
+		{[]string{"golden", "-dir", filepath.Join(dir, "golden"), "-bogus"}, exitUsage},
+		{[]string{"fallbacks", "-O", out, "-format", "bogus"}, exitUsage},
+		{[]string{"fallbacks", "-O", out, missing + ".log"}, exitFailure},
+		{[]string{"merge", "-O", filepath.Join(dir, "merge")}, exitUsage},
+		{[]string{"merge", "-O", filepath.Join(dir, "merge"), "-format", "bogus", out}, exitUsage},
+		{[]string{"audit"}, exitUsage},
+		{[]string{"audit", "-P", proto, "-L", "en", missing}, exitFailure},
+		{[]string{"version"}, 0},
+		{[]string{"help", "gen"}, 0},
+		{[]string{"help", "bogus"}, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if status := runCommand(t, "", tt.args...); status != tt.status {
+				t.Errorf("exit status %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	// The plugin reports failures in its response, as protoc expects
+	if status := runCommand(t, "\xff", "plugin"); status != 0 {
+		t.Errorf("plugin exited with %d on an invalid request, want 0", status)
+	}
+}
+
+func TestCollidingKeysFail(t *testing.T) {
+	dir := t.TempDir()
+	proto := writeTestProto(t, dir)
+	tests := []struct {
+		format string
+		static string // static keys colliding in the format
+	}{
+		{"i18next", "[Req.email]\nother = \"Invalid email\"\n\n[Req.email.bad]\nother = \"Bad email\"\n"},
+		{"yaml-nested", "[Req.email]\nother = \"Invalid email\"\n\n[Req.email.bad]\nother = \"Bad email\"\n"},
+		{"i18next-ns", "[demo]\nother = \"Demo\"\n"},             // the object of the proto package demo.v1
+		{"android", "[user.not_found]\nother = \"Not found\"\n"}, // named like USER_NOT_FOUND
+	}
+	for _, tt := range tests {
+		static := filepath.Join(dir, tt.format+".toml")
+		if err := os.WriteFile(static, []byte(tt.static), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"-P", proto, "-static-keys", static, "-O", filepath.Join(dir, tt.format), "-L", "en", "-format", tt.format}
+		for _, cmd := range []string{"gen", "check"} {
+			if status := runCommand(t, "", append([]string{cmd}, args...)...); status != exitFailure {
+				t.Errorf("%s -format %s exited with %d, want %d", cmd, tt.format, status, exitFailure)
+			}
+		}
+	}
+}
+
+func TestPackLanguageFiles(t *testing.T) {
+	dir := t.TempDir()
+	proto := writeTestProto(t, dir)
+	out := filepath.Join(dir, "i18n")
+	if status := runCommand(t, "", "gen", "-P", proto, "-O", out, "-manifest", "keys.json", "-review", "review.json"); status != 0 {
+		t.Fatalf("gen exited with %d", status)
+	}
+	archive := filepath.Join(dir, "i18n.zip")
+	if status := runCommand(t, "", "pack", "-O", out, "-manifest", "keys.json", "-version", "1", "-archive", "zip", "-o", archive); status != 0 {
+		t.Fatalf("pack exited with %d", status)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	want := []string{"i18n-1/en.toml", "i18n-1/keys.json", "i18n-1/zh.toml", "i18n-1/VERSION", "i18n-1/checksums.txt"}
+	if !slices.Equal(names, want) {
+		t.Errorf("packed %v, want %v", names, want)
+	}
+}
+
+func TestCheckAfterGen(t *testing.T) {
+	dir := t.TempDir()
+	proto := writeTestProto(t, dir)
+	tests := []struct {
+		flags  []string
+		extra  bool // whether a key no longer extracted is added after gen
+		status int
+	}{
+		{nil, false, 0},
+		{[]string{"-empty-value", "blank"}, false, 0},
+		{[]string{"-empty-value", "key"}, false, 0},
+		{nil, true, 0}, // kept by the default -stale keep
+		{[]string{"-stale", "prune"}, true, exitFailure},
+		{[]string{"-stale", "archive"}, true, exitFailure},
+	}
+	for i, tt := range tests {
+		out := filepath.Join(dir, fmt.Sprint("i18n", i))
+		args := append([]string{"-P", proto, "-O", out}, tt.flags...)
+		if status := runCommand(t, "", append([]string{"gen"}, args...)...); status != 0 {
+			t.Fatalf("gen %v exited with %d", tt.flags, status)
+		}
+		if tt.extra {
+			f, err := os.OpenFile(filepath.Join(out, "en.toml"), os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = f.WriteString("\n[REMOVED_KEY]\nother = \"Removed\"\n")
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if status := runCommand(t, "", append([]string{"check"}, args...)...); status != tt.status {
+			t.Errorf("check %v after gen, extra key %t: exited with %d, want %d", tt.flags, tt.extra, status, tt.status)
+		}
+	}
+}
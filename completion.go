@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// completionCommand implements the completion command, which prints a bash, zsh
+// or fish completion script for the subcommands, their flags and the values of
+// flags with a fixed set of choices.
+func completionCommand(fs *flag.FlagSet) func(args []string) error {
+	languages := fs.String("L", "en,zh", "Comma-separated list of language codes offered for -L")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: i18n-gen completion [flags] bash|zsh|fish\n")
+		fs.PrintDefaults()
+	}
+
+	return func(args []string) error {
+		if len(args) != 1 {
+			fs.Usage()
+			return errUsage
+		}
+
+		spec := newCompletionSpec(strings.Split(*languages, ","))
+		var script string
+		switch args[0] {
+		case "bash":
+			script = bashCompletion(spec)
+		case "zsh":
+			script = zshCompletion(spec)
+		case "fish":
+			script = fishCompletion(spec)
+		default:
+			return usageErrorf("unsupported shell: %s", args[0])
+		}
+		os.Stdout.WriteString(script)
+		return nil
+	}
+}
+
+// completionSpec holds what the completion scripts offer.
+type completionSpec struct {
+	commands []string            // subcommand names
+	flags    map[string][]string // flag names by subcommand, "" for generation
+	values   map[string][]string // choices by flag name
+}
+
+// newCompletionSpec collects the flags of every command by running its setup on
+// a scratch flag set.
+func newCompletionSpec(languages []string) completionSpec {
+	spec := completionSpec{flags: make(map[string][]string), values: make(map[string][]string)}
+	for _, cmd := range commands {
+		spec.commands = append(spec.commands, cmd.name)
+		spec.flags[cmd.name] = flagNames(cmd)
+		if cmd.name == "gen" {
+			spec.flags[""] = spec.flags[cmd.name]
+		}
+	}
+
+	for _, lang := range languages {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			spec.values["L"] = append(spec.values["L"], lang)
+		}
+	}
+	spec.values["format"] = emit.FormatNames()
+	spec.values["extractor"] = extract.Extractors()
+	spec.values["key-separator"] = keySeparators
+	spec.values["sort"] = []string{sortSource, sortAlpha}
+	spec.values["empty-value"] = []string{emptyBlank, emptyKey, emptySource, emptyTodo}
+	spec.values["state"] = reviewStates
+	spec.values["require-review"] = reviewStates
+	spec.values["alias-mode"] = []string{aliasDuplicate, aliasReference}
+	spec.values["version-keys"] = []string{versionsUnify, versionsNamespace}
+	spec.values["stale"] = []string{staleKeep, staleComment, stalePrune, staleArchive}
+	spec.values["events"] = []string{eventsNDJSON}
+	spec.values["encoding"] = []string{"utf-8", "nfc", "ascii", "utf-16le", "utf-16be"}
+	return spec
+}
+
+// flagNames returns the names of the flags a command takes, with their dash.
+func flagNames(cmd command) []string {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	setupCommand(cmd, fs)
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+// valueFlags returns the names of the flags with choices in a stable order.
+func (s completionSpec) valueFlags() []string {
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func bashCompletion(spec completionSpec) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("# bash completion for i18n-gen, generated by `i18n-gen completion bash`.\n")
+	buffer.WriteString("_i18n_gen() {\n")
+	buffer.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd=\"\"\n")
+	buffer.WriteString("    [[ ${COMP_CWORD} -gt 1 ]] && cmd=\"${COMP_WORDS[1]}\"\n")
+	buffer.WriteString("    case \"$prev\" in\n")
+	for _, name := range spec.valueFlags() {
+		buffer.WriteString(fmt.Sprintf("        -%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", name, strings.Join(spec.values[name], " ")))
+	}
+	buffer.WriteString("    esac\n")
+	buffer.WriteString("    case \"$cmd\" in\n")
+	for _, name := range spec.commands {
+		buffer.WriteString(fmt.Sprintf("        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", name, strings.Join(spec.flags[name], " ")))
+	}
+	all := append(append([]string{}, spec.commands...), spec.flags[""]...)
+	buffer.WriteString(fmt.Sprintf("        *) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", strings.Join(all, " ")))
+	buffer.WriteString("    esac\n")
+	buffer.WriteString("}\n")
+	buffer.WriteString("complete -o default -F _i18n_gen i18n-gen\n")
+	return buffer.String()
+}
+
+func zshCompletion(spec completionSpec) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("#compdef i18n-gen\n")
+	buffer.WriteString("# zsh completion for i18n-gen, generated by `i18n-gen completion zsh`.\n")
+	buffer.WriteString("_i18n_gen() {\n")
+	buffer.WriteString("  case ${words[CURRENT-1]} in\n")
+	for _, name := range spec.valueFlags() {
+		buffer.WriteString(fmt.Sprintf("    -%s) compadd -- %s; return ;;\n", name, strings.Join(spec.values[name], " ")))
+	}
+	buffer.WriteString("  esac\n")
+	buffer.WriteString("  case ${words[2]} in\n")
+	for _, name := range spec.commands {
+		buffer.WriteString(fmt.Sprintf("    %s) compadd -- %s ;;\n", name, strings.Join(spec.flags[name], " ")))
+	}
+	buffer.WriteString(fmt.Sprintf("    *) compadd -- %s %s; _files ;;\n", strings.Join(spec.commands, " "), strings.Join(spec.flags[""], " ")))
+	buffer.WriteString("  esac\n")
+	buffer.WriteString("}\n")
+	buffer.WriteString("compdef _i18n_gen i18n-gen\n")
+	return buffer.String()
+}
+
+func fishCompletion(spec completionSpec) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("# fish completion for i18n-gen, generated by `i18n-gen completion fish`.\n")
+	buffer.WriteString(fmt.Sprintf("complete -c i18n-gen -n __fish_use_subcommand -f -a %q\n", strings.Join(spec.commands, " ")))
+
+	writeFlags := func(condition string, flags []string) {
+		for _, flagName := range flags {
+			name := strings.TrimPrefix(flagName, "-")
+			if values, ok := spec.values[name]; ok {
+				buffer.WriteString(fmt.Sprintf("complete -c i18n-gen -n %q -o %s -x -a %q\n", condition, name, strings.Join(values, " ")))
+			} else {
+				buffer.WriteString(fmt.Sprintf("complete -c i18n-gen -n %q -o %s\n", condition, name))
+			}
+		}
+	}
+	writeFlags("__fish_use_subcommand", spec.flags[""])
+	for _, name := range spec.commands {
+		writeFlags("__fish_seen_subcommand_from "+name, spec.flags[name])
+	}
+	return buffer.String()
+}
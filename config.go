@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/toml"
+	"github.com/protoc-gen/i18n-gen/internal/yaml"
+)
+
+// configFiles are the config files looked for in the working directory, in
+// order, when -config is not given.
+var configFiles = []string{"i18n-gen.yaml", "i18n-gen.yml", "i18n-gen.toml"}
+
+// configUsage is the usage of the -config flag every command takes.
+const configUsage = "YAML or TOML file setting the flags not given on the command line (default: i18n-gen.yaml, i18n-gen.yml or i18n-gen.toml, if present)"
+
+// configNames maps the readable names of the config file to the flags they
+// set. Every other option is named like its flag.
+var configNames = map[string]string{"proto": "P", "output": "O", "languages": "L", "include": "I"}
+
+// configOption is an option of a config file, with a value per item for
+// lists.
+type configOption struct {
+	Name   string
+	Values []string
+	Line   int
+}
+
+// applyConfig sets the flags of fs that were not given on the command line to
+// the options of a config file, in YAML or, for .toml files, TOML. Lists set
+// repeatable flags once per item and are joined with commas for the others.
+// Paths are relative to the directory of the config file. Without a file
+// name, the first of configFiles found in the directory of go:generate
+// directive or the working directory is used, if any. The options are those of
+// gen, and the commands taking fewer flags skip the others and those of
+// ignore.
+func applyConfig(fs *flag.FlagSet, filePath string, ignore ...string) (string, error) {
+	if filePath == "" {
+		for _, name := range configFiles {
+			if _, err := os.Stat(resolveGeneratePath(name)); err == nil {
+				filePath = resolveGeneratePath(name)
+				break
+			}
+		}
+		if filePath == "" {
+			return "", nil
+		}
+	}
+	data, err := readFile(filePath, nil)
+	if err != nil {
+		return "", err
+	}
+	var options []configOption
+	if strings.HasSuffix(filePath, ".toml") {
+		options, err = parseConfigTOML(filePath, data)
+	} else {
+		options, err = parseConfigYAML(filePath, data)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	genFlags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	generateCommand(genFlags)
+	for _, option := range options {
+		name := option.Name
+		if flagName, ok := configNames[name]; ok {
+			name = flagName
+		}
+		f := fs.Lookup(name)
+		if f == nil && genFlags.Lookup(name) == nil || name == "config" || name == "print-directive" {
+			return "", fmt.Errorf("%s:%d: unknown option %s", filePath, option.Line, option.Name)
+		}
+		if f == nil || slices.Contains(ignore, name) {
+			continue
+		}
+		if set[name] {
+			continue // the command line overrides the config file
+		}
+		values := option.Values
+		if _, ok := f.Value.(*stringList); !ok {
+			values = []string{strings.Join(values, ",")}
+		}
+		for _, value := range values {
+			if (pathFlags[name] || goldenFileFlags[name]) && value != "" && !filepath.IsAbs(value) {
+				value = filepath.Join(filepath.Dir(filePath), value)
+			}
+			if err := fs.Set(name, value); err != nil {
+				return "", fmt.Errorf("%s:%d: %s: %w", filePath, option.Line, option.Name, err)
+			}
+		}
+	}
+	return filePath, nil
+}
+
+// parseConfigYAML reads a config file in YAML: a mapping of option names to
+// scalars, flow sequences ([en, zh]) or block sequences of scalars.
+func parseConfigYAML(filePath string, data []byte) ([]configOption, error) {
+	var options []configOption
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || line == "---" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if raw[0] == ' ' || raw[0] == '\t' {
+			if !strings.HasPrefix(line, "- ") || len(options) == 0 {
+				return nil, fmt.Errorf("%s:%d: unrecognized line: %s", filePath, i+1, line)
+			}
+			last := &options[len(options)-1]
+			last.Values = append(last.Values, textutil.Unescape(yaml.Scalar(strings.TrimSpace(line[2:]))))
+			continue
+		}
+		key, rest, ok := yaml.SplitLine(line)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unrecognized line: %s", filePath, i+1, line)
+		}
+		option := configOption{Name: key, Line: i + 1}
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.LastIndex(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated list", filePath, i+1)
+			}
+			for _, item := range splitFlowList(rest[1:end]) {
+				option.Values = append(option.Values, textutil.Unescape(yaml.Scalar(item)))
+			}
+		case rest != "":
+			option.Values = []string{textutil.Unescape(yaml.Scalar(rest))}
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// splitFlowList splits the items of a YAML flow sequence at the commas outside
+// of quotes.
+func splitFlowList(s string) []string {
+	var items []string
+	for s = strings.TrimSpace(s); s != ""; {
+		end := strings.Index(s, ",")
+		if s[0] == '"' || s[0] == '\'' {
+			if closing := yaml.ClosingQuote(s); closing > 0 {
+				end = strings.Index(s[closing:], ",")
+				if end >= 0 {
+					end += closing
+				}
+			}
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		if item := strings.TrimSpace(s[:end]); item != "" {
+			items = append(items, item)
+		}
+		s = strings.TrimSpace(s[min(end+1, len(s)):])
+	}
+	return items
+}
+
+// parseConfigTOML reads a config file in TOML: keys set to strings, arrays of
+// strings, booleans or numbers, without tables.
+func parseConfigTOML(filePath string, data []byte) ([]configOption, error) {
+	pairs, err := toml.ParseKeyValues(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	options := make([]configOption, len(pairs))
+	for i, pair := range pairs {
+		options[i] = configOption{Name: pair.Key, Values: pair.Values, Line: pair.Line}
+	}
+	return options, nil
+}
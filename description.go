@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// grpcHTTPStatus maps gRPC codes to the HTTP status gRPC gateways answer with.
+var grpcHTTPStatus = map[string]int{
+	"OK": 200, "CANCELLED": 499, "UNKNOWN": 500, "INVALID_ARGUMENT": 400,
+	"DEADLINE_EXCEEDED": 504, "NOT_FOUND": 404, "ALREADY_EXISTS": 409,
+	"PERMISSION_DENIED": 403, "RESOURCE_EXHAUSTED": 429, "FAILED_PRECONDITION": 400,
+	"ABORTED": 409, "OUT_OF_RANGE": 400, "UNIMPLEMENTED": 501, "INTERNAL": 500,
+	"UNAVAILABLE": 503, "DATA_LOSS": 500, "UNAUTHENTICATED": 401,
+}
+
+// descriptionData is what a description template renders for a key.
+type descriptionData struct {
+	Key        string
+	Name       string
+	Kind       string // "enum" or "cel"
+	Path       string
+	Package    string
+	Comment    string // leading comment of the enum value, the description without a template
+	Message    string
+	Expression string            // CEL expression of a validation rule
+	Number     int               // number of the enum value
+	Options    map[string]string // options of the enum value by name without parentheses
+	GRPCCode   string            // name of the gRPC code, if any
+	HTTPStatus int               // HTTP status of the gRPC code, 0 without one
+	File       string
+	Line       int
+}
+
+// loadDescriptionTemplate parses a text/template file rendering the
+// description of each key.
+func loadDescriptionTemplate(filePath string) (*template.Template, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filePath).Option("missingkey=zero").Parse(string(data))
+}
+
+// applyDescriptionTemplate replaces the description of every key with the
+// template rendered for it, with surrounding whitespace trimmed. Aliases and
+// static keys keep theirs.
+func applyDescriptionTemplate(entries []extract.Entry, tmpl *template.Template) error {
+	for i, e := range entries {
+		if e.Kind != extract.KindEnum && e.Kind != extract.KindCEL && e.Kind != extract.KindRule && e.Kind != extract.KindPGV && e.Kind != extract.KindLabel {
+			continue
+		}
+		data := descriptionData{
+			Key: e.Key, Name: e.Name, Kind: e.Kind, Path: e.Path, Package: e.Package,
+			Comment: e.Comment, Message: e.Message, Expression: e.Expression, Number: e.Number,
+			Options: make(map[string]string), File: e.File, Line: e.Line,
+		}
+		for _, option := range e.Options {
+			data.Options[strings.Trim(option.Name, "()")] = option.Constant.Source
+		}
+		if e.GRPCCode != "" {
+			data.GRPCCode = grpcCodesByNumber[grpcCodeNumber(e.GRPCCode)]
+			data.HTTPStatus = grpcHTTPStatus[data.GRPCCode]
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", e.File, e.Line, e.Key, err)
+		}
+		entries[i].Comment = strings.TrimSpace(b.String())
+	}
+	return nil
+}
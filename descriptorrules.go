@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// extPGVRules is the extension number of (validate.rules), the legacy
+// protoc-gen-validate rules of a field.
+const extPGVRules = 1071
+
+// ruleEncoding is how the value of a standard rule is encoded.
+type ruleEncoding int
+
+const (
+	ruleInt ruleEncoding = iota
+	ruleUint
+	ruleSint
+	ruleFixed32
+	ruleFixed64
+	ruleSfixed32
+	ruleSfixed64
+	ruleFloat
+	ruleDouble
+	ruleBool
+	ruleString
+	ruleBytes
+	ruleDuration
+	ruleKnownRegex
+)
+
+// ruleField is a field of a message of standard rules: a rule with its
+// encoding, or the rules of another type, of the items of a repeated field or
+// of the keys or values of a map.
+type ruleField struct {
+	name     string
+	encoding ruleEncoding
+	rules    ruleSchema
+}
+
+// ruleSchema are the fields of a message of standard rules by number.
+type ruleSchema map[int]ruleField
+
+// knownRegexes are the values of buf.validate.KnownRegex and
+// validate.KnownRegex by number.
+var knownRegexes = []string{"UNKNOWN", "HTTP_HEADER_NAME", "HTTP_HEADER_VALUE"}
+
+// numberRules returns the rules of a numeric type. Rules past the comparisons
+// differ between protovalidate and PGV and are passed as extra.
+func numberRules(encoding ruleEncoding, extra ruleSchema) ruleSchema {
+	schema := ruleSchema{
+		1: {name: "const", encoding: encoding},
+		2: {name: "lt", encoding: encoding},
+		3: {name: "lte", encoding: encoding},
+		4: {name: "gt", encoding: encoding},
+		5: {name: "gte", encoding: encoding},
+		6: {name: "in", encoding: encoding},
+		7: {name: "not_in", encoding: encoding},
+	}
+	for num, f := range extra {
+		schema[num] = f
+	}
+	return schema
+}
+
+// timeRules returns the rules of google.protobuf.Duration or Timestamp fields,
+// which share their numbers in protovalidate and PGV.
+func timeRules(timestamp bool) ruleSchema {
+	schema := ruleSchema{
+		2: {name: "const", encoding: ruleDuration},
+		3: {name: "lt", encoding: ruleDuration},
+		4: {name: "lte", encoding: ruleDuration},
+		5: {name: "gt", encoding: ruleDuration},
+		6: {name: "gte", encoding: ruleDuration},
+	}
+	if timestamp {
+		schema[7] = ruleField{name: "lt_now", encoding: ruleBool}
+		schema[8] = ruleField{name: "gt_now", encoding: ruleBool}
+		schema[9] = ruleField{name: "within", encoding: ruleDuration}
+	} else {
+		schema[7] = ruleField{name: "in", encoding: ruleDuration}
+		schema[8] = ruleField{name: "not_in", encoding: ruleDuration}
+	}
+	return schema
+}
+
+// fieldRules returns the schema of buf.validate.FieldRules, or of
+// validate.FieldRules for PGV, without their cel rules, which are read apart.
+func fieldRules(pgv bool) ruleSchema {
+	numeric := func(encoding ruleEncoding) ruleSchema {
+		return numberRules(encoding, nil)
+	}
+	floating := func(encoding ruleEncoding) ruleSchema {
+		if pgv {
+			return numeric(encoding)
+		}
+		return numberRules(encoding, ruleSchema{8: {name: "finite", encoding: ruleBool}})
+	}
+	stringRules := ruleSchema{
+		1: {name: "const", encoding: ruleString}, 19: {name: "len", encoding: ruleUint},
+		2: {name: "min_len", encoding: ruleUint}, 3: {name: "max_len", encoding: ruleUint},
+		20: {name: "len_bytes", encoding: ruleUint}, 4: {name: "min_bytes", encoding: ruleUint},
+		5: {name: "max_bytes", encoding: ruleUint}, 6: {name: "pattern", encoding: ruleString},
+		7: {name: "prefix", encoding: ruleString}, 8: {name: "suffix", encoding: ruleString},
+		9: {name: "contains", encoding: ruleString}, 23: {name: "not_contains", encoding: ruleString},
+		10: {name: "in", encoding: ruleString}, 11: {name: "not_in", encoding: ruleString},
+		12: {name: "email", encoding: ruleBool}, 13: {name: "hostname", encoding: ruleBool},
+		14: {name: "ip", encoding: ruleBool}, 15: {name: "ipv4", encoding: ruleBool},
+		16: {name: "ipv6", encoding: ruleBool}, 17: {name: "uri", encoding: ruleBool},
+		18: {name: "uri_ref", encoding: ruleBool}, 21: {name: "address", encoding: ruleBool},
+		22: {name: "uuid", encoding: ruleBool}, 24: {name: "well_known_regex", encoding: ruleKnownRegex},
+	}
+	bytesRules := ruleSchema{
+		1: {name: "const", encoding: ruleBytes}, 13: {name: "len", encoding: ruleUint},
+		2: {name: "min_len", encoding: ruleUint}, 3: {name: "max_len", encoding: ruleUint},
+		4: {name: "pattern", encoding: ruleString}, 5: {name: "prefix", encoding: ruleBytes},
+		6: {name: "suffix", encoding: ruleBytes}, 7: {name: "contains", encoding: ruleBytes},
+		8: {name: "in", encoding: ruleBytes}, 9: {name: "not_in", encoding: ruleBytes},
+		10: {name: "ip", encoding: ruleBool}, 11: {name: "ipv4", encoding: ruleBool},
+		12: {name: "ipv6", encoding: ruleBool},
+	}
+	if !pgv {
+		for num, name := range map[int]string{
+			26: "ip_with_prefixlen", 27: "ipv4_with_prefixlen", 28: "ipv6_with_prefixlen",
+			29: "ip_prefix", 30: "ipv4_prefix", 31: "ipv6_prefix", 32: "host_and_port", 33: "tuuid",
+		} {
+			stringRules[num] = ruleField{name: name, encoding: ruleBool}
+		}
+	}
+
+	schema := ruleSchema{
+		1:  {name: "float", rules: floating(ruleFloat)},
+		2:  {name: "double", rules: floating(ruleDouble)},
+		3:  {name: "int32", rules: numeric(ruleInt)},
+		4:  {name: "int64", rules: numeric(ruleInt)},
+		5:  {name: "uint32", rules: numeric(ruleUint)},
+		6:  {name: "uint64", rules: numeric(ruleUint)},
+		7:  {name: "sint32", rules: numeric(ruleSint)},
+		8:  {name: "sint64", rules: numeric(ruleSint)},
+		9:  {name: "fixed32", rules: numeric(ruleFixed32)},
+		10: {name: "fixed64", rules: numeric(ruleFixed64)},
+		11: {name: "sfixed32", rules: numeric(ruleSfixed32)},
+		12: {name: "sfixed64", rules: numeric(ruleSfixed64)},
+		13: {name: "bool", rules: ruleSchema{1: {name: "const", encoding: ruleBool}}},
+		14: {name: "string", rules: stringRules},
+		15: {name: "bytes", rules: bytesRules},
+		16: {name: "enum", rules: ruleSchema{
+			1: {name: "const", encoding: ruleInt}, 2: {name: "defined_only", encoding: ruleBool},
+			3: {name: "in", encoding: ruleInt}, 4: {name: "not_in", encoding: ruleInt},
+		}},
+		20: {name: "any", rules: ruleSchema{2: {name: "in", encoding: ruleString}, 3: {name: "not_in", encoding: ruleString}}},
+		21: {name: "duration", rules: timeRules(false)},
+		22: {name: "timestamp", rules: timeRules(true)},
+	}
+	repeated := ruleSchema{
+		1: {name: "min_items", encoding: ruleUint}, 2: {name: "max_items", encoding: ruleUint},
+		3: {name: "unique", encoding: ruleBool}, 4: {name: "items", rules: schema},
+	}
+	maps := ruleSchema{
+		1: {name: "min_pairs", encoding: ruleUint}, 2: {name: "max_pairs", encoding: ruleUint},
+		4: {name: "keys", rules: schema}, 5: {name: "values", rules: schema},
+	}
+	if pgv {
+		maps[3] = ruleField{name: "no_sparse", encoding: ruleBool}
+		schema[17] = ruleField{name: "message", rules: ruleSchema{2: {name: "required", encoding: ruleBool}}}
+		schema[21].rules[1] = ruleField{name: "required", encoding: ruleBool}
+		schema[22].rules[1] = ruleField{name: "required", encoding: ruleBool}
+		schema[20].rules[1] = ruleField{name: "required", encoding: ruleBool}
+	} else {
+		schema[25] = ruleField{name: "required", encoding: ruleBool}
+	}
+	schema[18] = ruleField{name: "repeated", rules: repeated}
+	schema[19] = ruleField{name: "map", rules: maps}
+	return schema
+}
+
+// Schemas of the standard rules of (buf.validate.field) and (validate.rules).
+var (
+	standardRuleSchema = fieldRules(false)
+	pgvRuleSchema      = fieldRules(true)
+)
+
+// descriptorRules returns the values set in encoded standard rules, at their
+// path below the extension, as extract.StandardRules takes them. Fields the
+// schema does not know, such as cel rules, are skipped.
+func descriptorRules(data []byte, schema ruleSchema, at []string, line int, rules []extract.StandardRule) ([]extract.StandardRule, error) {
+	fields, err := decodeWire(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		field, ok := schema[f.num]
+		if !ok {
+			continue
+		}
+		fieldAt := append(append([]string{}, at...), field.name)
+		if field.rules != nil {
+			if f.typ != wireBytes {
+				continue
+			}
+			if rules, err = descriptorRules(f.bytes, field.rules, fieldAt, line, rules); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		values, err := ruleValues(f, field.encoding)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", strings.Join(fieldAt, "."), err)
+		}
+		for _, v := range values {
+			rules = append(rules, extract.StandardRule{At: fieldAt, Value: v, Line: line})
+		}
+	}
+	return rules, nil
+}
+
+// ruleValues returns the values of a rule as written in messages, several for
+// a packed repeated field such as the in of a numeric type.
+func ruleValues(f wireField, encoding ruleEncoding) ([]string, error) {
+	switch encoding {
+	case ruleString, ruleBytes:
+		return []string{"'" + textutil.Escape(string(f.bytes)) + "'"}, nil
+	case ruleDuration:
+		d, err := durationValue(f.bytes)
+		if err != nil {
+			return nil, err
+		}
+		return []string{d.String()}, nil
+	}
+
+	var raw []uint64
+	switch {
+	case f.typ != wireBytes:
+		raw = []uint64{f.varint}
+	case encoding == ruleFixed32 || encoding == ruleSfixed32 || encoding == ruleFloat:
+		for b := f.bytes; len(b) >= 4; b = b[4:] {
+			raw = append(raw, uint64(binary.LittleEndian.Uint32(b)))
+		}
+	case encoding == ruleFixed64 || encoding == ruleSfixed64 || encoding == ruleDouble:
+		for b := f.bytes; len(b) >= 8; b = b[8:] {
+			raw = append(raw, binary.LittleEndian.Uint64(b))
+		}
+	default:
+		var err error
+		if raw, err = decodePackedVarints(f.bytes); err != nil {
+			return nil, err
+		}
+	}
+	values := make([]string, len(raw))
+	for i, v := range raw {
+		switch encoding {
+		case ruleInt:
+			values[i] = strconv.FormatInt(int64(v), 10)
+		case ruleSint:
+			values[i] = strconv.FormatInt(int64(v>>1)^-int64(v&1), 10)
+		case ruleSfixed32:
+			values[i] = strconv.FormatInt(int64(int32(v)), 10)
+		case ruleSfixed64:
+			values[i] = strconv.FormatInt(int64(v), 10)
+		case ruleFloat:
+			values[i] = strconv.FormatFloat(float64(math.Float32frombits(uint32(v))), 'g', -1, 32)
+		case ruleDouble:
+			values[i] = strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64)
+		case ruleBool:
+			values[i] = strconv.FormatBool(v != 0)
+		case ruleKnownRegex:
+			values[i] = strconv.FormatUint(v, 10)
+			if v < uint64(len(knownRegexes)) {
+				values[i] = knownRegexes[v]
+			}
+		default:
+			values[i] = strconv.FormatUint(v, 10)
+		}
+	}
+	return values, nil
+}
+
+// durationValue decodes a google.protobuf.Duration, or the seconds and nanos
+// of a Timestamp.
+func durationValue(data []byte) (time.Duration, error) {
+	fields, err := decodeWire(data)
+	if err != nil {
+		return 0, err
+	}
+	var d time.Duration
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			d += time.Duration(int64(f.varint)) * time.Second
+		case 2:
+			d += time.Duration(int32(f.varint))
+		}
+	}
+	return d, nil
+}
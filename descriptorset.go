@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// descriptorSetFile is the field number of the files of a
+// google.protobuf.FileDescriptorSet.
+const descriptorSetFile = 1
+
+// descriptorSetFiles extracts the entries of every file of a compiled
+// FileDescriptorSet, such as the output of protoc --descriptor_set_out or buf
+// build, read from standard input for -. The files are read as in plugin
+// mode, with the options and comments protoc resolved, and returned by the
+// name they were compiled under; a set that fails to read is returned as one
+// file with its error.
+func descriptorSetFiles(setPath string, opts extract.Options) []extract.File {
+	var data []byte
+	var err error
+	if setPath == stdioPath {
+		setPath = stdinName
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(setPath)
+	}
+	if err != nil {
+		return []extract.File{{Path: setPath, Err: err}}
+	}
+	return decodeDescriptorSet(setPath, data, opts)
+}
+
+// decodeDescriptorSet extracts the entries of the files of an encoded
+// FileDescriptorSet, or of a buf image, whose files are encoded the same.
+func decodeDescriptorSet(setPath string, data []byte, opts extract.Options) []extract.File {
+	if opts.Extractor != "" && opts.Extractor != extract.DefaultExtractor {
+		return []extract.File{{Path: setPath, Err: fmt.Errorf("the %s extractor cannot read descriptor sets", opts.Extractor)}}
+	}
+	fields, err := decodeWire(data)
+	if err != nil {
+		return []extract.File{{Path: setPath, Err: fmt.Errorf("decode descriptor set: %w", err)}}
+	}
+
+	var files []extract.File
+	for _, f := range fields {
+		if f.num != descriptorSetFile {
+			continue
+		}
+		entries, name, err := descriptorEntries(f.bytes, opts)
+		if err != nil {
+			return append(files, extract.File{Path: setPath, Err: fmt.Errorf("read descriptor: %w", err)})
+		}
+		files = append(files, extract.File{Path: name, Entries: entries})
+	}
+	if len(files) == 0 {
+		return []extract.File{{Path: setPath, Err: fmt.Errorf("no file descriptors in %s", setPath)}}
+	}
+	return files
+}
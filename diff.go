@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the shortest edit script turning the lines a into the
+// lines b, computed with the Myers algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[prevY]})
+			} else {
+				ops = append(ops, diffOp{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedDiff returns the unified diff between two versions of a file, or an
+// empty string if they are equal. A missing version is named /dev/null.
+func unifiedDiff(oldName, newName string, oldData, newData []byte) string {
+	if bytes.Equal(oldData, newData) {
+		return ""
+	}
+	ops := diffLines(splitLines(oldData), splitLines(newData))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of the hunk around it, which runs on
+		// while changes are at most twice the context apart
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops) && i <= last+2*diffContext; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+		from, to := max(first-diffContext, start), min(last+diffContext+1, len(ops))
+
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldLine--
+		}
+		if newCount == 0 {
+			newLine--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+		for _, op := range ops[from:to] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		start = to
+	}
+	return b.String()
+}
+
+// splitLines splits a file into lines without their line breaks.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// copyTree copies the regular files below a directory to another one, which
+// is what a dry run generates into. A missing directory copies nothing.
+func copyTree(from, to string) error {
+	err := filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(to, rel))
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// diffTrees returns the unified diffs of the files that differ between two
+// directories, named by their path below the first one, in path order.
+func diffTrees(oldDir, newDir string) (string, error) {
+	files := make(map[string]bool)
+	for _, dir := range []string{oldDir, newDir} {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			files[rel] = true
+			return err
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	for _, rel := range sortedKeys(files) {
+		oldName, newName := filepath.Join(oldDir, rel), filepath.Join(oldDir, rel)
+		oldData, err := os.ReadFile(filepath.Join(oldDir, rel))
+		if os.IsNotExist(err) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return "", err
+		}
+		newData, err := os.ReadFile(filepath.Join(newDir, rel))
+		if os.IsNotExist(err) {
+			newName = "/dev/null"
+		} else if err != nil {
+			return "", err
+		}
+		b.WriteString(unifiedDiff(oldName, newName, oldData, newData))
+	}
+	return b.String(), nil
+}
+
+// copyFile copies a generated file to another path, creating its directory.
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, 0644)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+)
+
+// languagePaths returns the paths of the language files of the languages.
+func languagePaths(outFormat emit.Format, outputDir string, langs []string) []string {
+	paths := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		paths = append(paths, outFormat.Path(outputDir, lang))
+	}
+	return paths
+}
+
+// languageFileHashes returns the hash of every existing language file, by path
+// relative to the output directory, as recorded in the manifest.
+func languageFileHashes(outputDir string, langPaths []string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for _, langPath := range langPaths {
+		data, err := os.ReadFile(langPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(outputDir, langPath)
+		if err != nil {
+			return nil, err
+		}
+		hashes[filepath.ToSlash(rel)] = textHash(string(data))
+	}
+	return hashes, nil
+}
+
+// loadManifestHashes returns the hashes of the language files recorded in a
+// manifest, or none when it does not exist.
+func loadManifestHashes(filePath string) (map[string]string, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(filePath), err)
+	}
+	return manifest.Files, nil
+}
+
+// generatedName is the file in the output directory recording the hash of every
+// language file as gen last wrote it, which tells its own output from edits
+// made by hand whether or not -manifest is set.
+const generatedName = ".i18n-gen.json"
+
+// generatedState is the content of the generatedName file.
+type generatedState struct {
+	Files map[string]string `json:"files"` // language file hashes by path relative to the output directory
+}
+
+// loadGeneratedHashes returns the hashes of the language files recorded in the
+// output directory, or none when they were never recorded.
+func loadGeneratedHashes(outputDir string) (map[string]string, error) {
+	filePath := filepath.Join(outputDir, generatedName)
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state generatedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", generatedName, err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]string)
+	}
+	return state.Files, nil
+}
+
+// writeGeneratedHashes records the hashes of the language files in the output
+// directory.
+func writeGeneratedHashes(outputDir string, hashes map[string]string) error {
+	data, err := json.MarshalIndent(generatedState{Files: hashes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, generatedName), append(data, '\n'), 0644)
+}
+
+// dirtyLanguageFiles returns the language files edited by hand since they were
+// last generated, which a run would overwrite: those that differ from the hash
+// recorded when they were generated, unless git has them committed, and, in a
+// git work tree, those with uncommitted changes and no recorded hash.
+func dirtyLanguageFiles(outputDir string, langPaths []string, recorded map[string]string) ([]string, error) {
+	current, err := languageFileHashes(outputDir, langPaths)
+	if err != nil {
+		return nil, err
+	}
+	changed, inGit := gitChangedFiles(outputDir)
+
+	var dirty []string
+	for _, langPath := range langPaths {
+		rel, err := filepath.Rel(outputDir, langPath)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		hash, exists := current[rel]
+		generated, known := recorded[rel]
+		switch {
+		case !exists:
+		case inGit && !changed[rel]:
+		case known && hash == generated:
+		case !inGit && !known:
+		default:
+			dirty = append(dirty, langPath)
+		}
+	}
+	return dirty, nil
+}
+
+// gitChangedFiles returns the files below dir with changes not committed, by
+// path relative to dir, and whether dir is in a git work tree with commits.
+func gitChangedFiles(dir string) (map[string]bool, bool) {
+	out, err := exec.Command("git", "-C", dir, "diff", "--name-only", "--relative", "HEAD", "--", ".").Output()
+	if err != nil {
+		return nil, false
+	}
+	changed := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changed[line] = true
+		}
+	}
+	return changed, true
+}
+
+// confirmOverwrite asks whether to overwrite the dirty files when in is a
+// terminal, and reports the answer; it is always no otherwise.
+func confirmOverwrite(dirty []string, in *os.File, out io.Writer) bool {
+	info, err := in.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	fmt.Fprintf(out, "%s edited by hand since last generated. Overwrite? [y/N] ", strings.Join(dirty, ", "))
+	answer, _ := bufio.NewReader(in).ReadBytes('\n')
+	answer = bytes.ToLower(bytes.TrimSpace(answer))
+	return bytes.Equal(answer, []byte("y")) || bytes.Equal(answer, []byte("yes"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs a git command in dir.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestGenerateTwiceWithoutCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	proto := writeTestProto(t, dir)
+	out := filepath.Join(dir, "i18n")
+	gen := []string{"gen", "-P", proto, "-O", out, "-L", "en,zh"}
+	git(t, dir, "init", "-q")
+	if status := runCommand(t, "", gen...); status != 0 {
+		t.Fatalf("gen exited with %d", status)
+	}
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "generate")
+
+	// The output of a run is not mistaken for edits made by hand by the next
+	if err := os.WriteFile(proto, []byte(strings.Replace(testProto, "}", "  EMAIL_TAKEN = 2;\n}", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for run := 1; run <= 2; run++ {
+		if status := runCommand(t, "", gen...); status != 0 {
+			t.Fatalf("run %d: gen exited with %d", run, status)
+		}
+	}
+
+	// Edits made by hand are
+	langPath := filepath.Join(out, "zh.toml")
+	data, err := os.ReadFile(langPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(langPath, append(data, "# edited\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := runCommand(t, "", gen...); status != exitFailure {
+		t.Errorf("gen exited with %d over a file edited by hand, want %d", status, exitFailure)
+	}
+	if status := runCommand(t, "", append(gen, "-force")...); status != 0 {
+		t.Errorf("gen -force exited with %d", status)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// doctorCheck is the outcome of a single diagnostic.
+type doctorCheck struct {
+	ok      bool
+	message string
+	fix     string
+}
+
+// doctorCommand implements the doctor command, which checks the environment and
+// options of a generation run and prints how to fix what is wrong.
+func doctorCommand(fs *flag.FlagSet) func(args []string) error {
+	var protoPatterns stringList
+	fs.Var(&protoPatterns, "P", "Proto files: a glob, ** matching any number of directories, e.g. api/**/*.proto, a directory or a file; may be repeated (default "+defaultProtoPattern+")")
+	outputDir := fs.String("O", "./i18n/", "Path to the output directory")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Output format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	configFile := fs.String("config", "", configUsage)
+	ownersFile := fs.String("owners", "", "CODEOWNERS-style file of the owners of the keys to check (optional)")
+	notify := fs.Bool("notify", false, "Check that the owners to notify have a webhook")
+	ticketsFile := fs.String("tickets", "", "JSON file configuring the tickets created for new keys, whose tracker token is checked (optional)")
+
+	return func(_ []string) error {
+		var checks []doctorCheck
+		checks = append(checks, checkConfig(*configFile)...)
+		checks = append(checks, checkProtos(protoPatterns)...)
+		checks = append(checks, checkOutputDir(*outputDir))
+		checks = append(checks, checkLanguages(*languages)...)
+		if _, ok := emit.Formats[*format]; ok {
+			checks = append(checks, doctorCheck{ok: true, message: fmt.Sprintf("output format %s is supported", *format)})
+		} else {
+			checks = append(checks, doctorCheck{message: fmt.Sprintf("unknown output format %s", *format), fix: "use one of " + strings.Join(emit.FormatNames(), ", ")})
+		}
+		if *ownersFile != "" {
+			checks = append(checks, checkOwners(*ownersFile, *notify)...)
+		}
+		if *ticketsFile != "" {
+			checks = append(checks, checkTickets(*ticketsFile))
+		}
+
+		failed := 0
+		for _, c := range checks {
+			if c.ok {
+				fmt.Printf("[ok]   %s\n", c.message)
+				continue
+			}
+			failed++
+			fmt.Printf("[fail] %s\n", c.message)
+			if c.fix != "" {
+				fmt.Printf("       fix: %s\n", c.fix)
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("%d problem(s) found.\n", failed)
+			return errFailed
+		}
+		fmt.Println("No problems found.")
+		return nil
+	}
+}
+
+// checkProtos checks that the directory of every pattern exists and holds
+// proto files, and that every file parses and imports the definitions of the
+// options it uses.
+func checkProtos(patterns []string) []doctorCheck {
+	if len(patterns) == 0 {
+		patterns = []string{defaultProtoPattern}
+	}
+	var checks []doctorCheck
+	var protoFiles []string
+	found := make(map[string]bool)
+	for _, pattern := range patterns {
+		dir := extract.PatternRoot(pattern)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			checks = append(checks, doctorCheck{
+				message: fmt.Sprintf("proto directory %s does not exist", dir),
+				fix:     "point -P at a file, directory or pattern inside the directory holding your .proto files",
+			})
+			continue
+		}
+		matches, err := findProtoFiles([]string{pattern})
+		if err != nil {
+			checks = append(checks, doctorCheck{message: fmt.Sprintf("cannot walk %s: %v", dir, err), fix: "check the directory permissions"})
+			continue
+		}
+		if len(matches) == 0 {
+			checks = append(checks, doctorCheck{
+				message: fmt.Sprintf("no .proto files match %s", pattern),
+				fix:     "check the path; ** matches any number of directories, e.g. proto/**/*.proto",
+			})
+			continue
+		}
+		checks = append(checks, doctorCheck{ok: true, message: fmt.Sprintf("%d proto file(s) match %s", len(matches), pattern)})
+		for _, file := range matches {
+			if !found[file] {
+				found[file] = true
+				protoFiles = append(protoFiles, file)
+			}
+		}
+	}
+
+	for _, protoFile := range protoFiles {
+		if _, err := extract.FromFile(protoFile, extract.Options{Recover: true}); err != nil {
+			syntaxErrs := extract.AsSyntaxErrors(err)
+			if syntaxErrs == nil {
+				checks = append(checks, doctorCheck{message: fmt.Sprintf("%s: %v", protoFile, err), fix: "fix the syntax error or exclude the file"})
+			}
+			for _, syntaxErr := range syntaxErrs {
+				checks = append(checks, doctorCheck{message: syntaxErr.Error(), fix: "fix the syntax error or exclude the file"})
+			}
+			continue
+		}
+		data, err := readFile(protoFile, nil)
+		if err != nil {
+			continue
+		}
+		source := string(data)
+		if strings.Contains(source, "(buf.validate.") && !strings.Contains(source, "\"buf/validate/validate.proto\"") {
+			checks = append(checks, doctorCheck{
+				message: fmt.Sprintf("%s uses buf.validate options without importing their definition", protoFile),
+				fix:     "add import \"buf/validate/validate.proto\"; to the file",
+			})
+		}
+	}
+	return checks
+}
+
+// checkConfig checks that the config file, if any, only sets flags of gen to
+// values they accept. The commands fail on the options they take themselves
+// before doctor runs.
+func checkConfig(filePath string) []doctorCheck {
+	genFlags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	generateCommand(genFlags)
+	filePath, err := applyConfig(genFlags, filePath)
+	switch {
+	case err != nil:
+		return []doctorCheck{{message: fmt.Sprintf("invalid config file: %v", err), fix: "name the options like the flags of gen, with values they accept"}}
+	case filePath == "":
+		return nil
+	}
+	var set int
+	genFlags.Visit(func(*flag.Flag) { set++ })
+	return []doctorCheck{{ok: true, message: fmt.Sprintf("config file %s sets %d flag(s)", filePath, set)}}
+}
+
+// checkOutputDir checks that the output directory, or the directory it would
+// be created in, exists and that files can be written to it. Nothing is
+// created but a probe file, removed right away.
+func checkOutputDir(outputDir string) doctorCheck {
+	dir := filepath.Clean(outputDir)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			return doctorCheck{message: fmt.Sprintf("%s is not a directory", dir), fix: "choose another -O or move the file away"}
+		}
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return doctorCheck{message: fmt.Sprintf("cannot stat %s: %v", dir, err), fix: "fix the permissions of its parent"}
+		}
+		if parent := filepath.Dir(dir); parent != dir {
+			dir = parent
+			continue
+		}
+		return doctorCheck{message: fmt.Sprintf("output directory %s has no existing parent", outputDir), fix: "choose another -O"}
+	}
+	probe, err := os.CreateTemp(dir, ".i18n-gen-doctor-*")
+	if err != nil {
+		return doctorCheck{message: fmt.Sprintf("%s is not writable: %v", dir, err), fix: "fix the permissions of the directory"}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	if dir != filepath.Clean(outputDir) {
+		return doctorCheck{ok: true, message: fmt.Sprintf("output directory %s does not exist, and will be created in %s, which is writable", outputDir, dir)}
+	}
+	return doctorCheck{ok: true, message: fmt.Sprintf("output directory %s is writable", outputDir)}
+}
+
+// checkOwners checks that the owners file parses and, to notify the owners,
+// that their webhooks are HTTP URLs.
+func checkOwners(filePath string, notify bool) []doctorCheck {
+	rules, err := loadOwners(filePath)
+	if err != nil {
+		return []doctorCheck{{message: fmt.Sprintf("invalid owners file: %v", err), fix: "write a pattern, an owner and an optional webhook per line"}}
+	}
+	checks := []doctorCheck{{ok: true, message: fmt.Sprintf("owners file %s has %d rule(s)", filePath, len(rules))}}
+	if !notify {
+		return checks
+	}
+	webhooks := 0
+	for _, rule := range rules {
+		if rule.webhook == "" {
+			continue
+		}
+		webhooks++
+		if u, err := url.Parse(rule.webhook); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			checks = append(checks, doctorCheck{message: fmt.Sprintf("webhook of %s is not an HTTP URL: %s", rule.owner, rule.webhook), fix: "use the https URL of the incoming webhook"})
+		}
+	}
+	if webhooks == 0 {
+		checks = append(checks, doctorCheck{message: fmt.Sprintf("no rule of %s has a webhook to notify", filePath), fix: "add the webhook of the owners as third field, or drop -notify"})
+	}
+	return checks
+}
+
+// checkTickets checks that the ticket configuration parses and that the
+// environment variable of its token is set.
+func checkTickets(filePath string) doctorCheck {
+	config, err := loadTicketConfig(filePath)
+	switch {
+	case err != nil:
+		return doctorCheck{message: fmt.Sprintf("invalid ticket configuration: %v", err), fix: "see -tickets in the README"}
+	case config.TokenEnv == "":
+		return doctorCheck{message: fmt.Sprintf("%s: no token_env, so tickets are created without a token", filePath), fix: "name the environment variable holding the " + config.Tracker + " token in token_env"}
+	case os.Getenv(config.TokenEnv) == "":
+		return doctorCheck{message: fmt.Sprintf("environment variable %s of the %s token is not set", config.TokenEnv, config.Tracker), fix: "export " + config.TokenEnv + " before the run"}
+	case config.Tracker == trackerJira && strings.Contains(config.URL, ".atlassian.net") && !strings.Contains(os.Getenv(config.TokenEnv), ":"):
+		return doctorCheck{message: fmt.Sprintf("%s holds no email:token for Jira Cloud", config.TokenEnv), fix: "set it to the email of the account and its API token, separated by a colon"}
+	}
+	return doctorCheck{ok: true, message: fmt.Sprintf("%s token is set in %s", config.Tracker, config.TokenEnv)}
+}
+
+// checkLanguages checks that every language is a well-formed BCP 47 tag.
+func checkLanguages(languages string) []doctorCheck {
+	var checks []doctorCheck
+	for _, lang := range strings.Split(languages, ",") {
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		tag, err := language.Parse(lang)
+		switch {
+		case err != nil:
+			checks = append(checks, doctorCheck{message: fmt.Sprintf("language %q is not a valid BCP 47 tag", lang), fix: "use codes such as en, zh-Hans or pt-BR"})
+		case tag.String() != lang:
+			checks = append(checks, doctorCheck{message: fmt.Sprintf("language %q is not in canonical form", lang), fix: fmt.Sprintf("use %s", tag)})
+		default:
+			checks = append(checks, doctorCheck{ok: true, message: fmt.Sprintf("language %s is valid", lang)})
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{message: "no languages configured", fix: "pass a comma-separated list to -L"})
+	}
+	return checks
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventsNDJSON is the format of -events: one JSON object per line.
+const eventsNDJSON = "ndjson"
+
+// Types of the events of a generation run.
+const (
+	eventFileParsed      = "file_parsed"      // a proto file was parsed, with the number of its entries
+	eventKeyAdded        = "key_added"        // a key was added to a language file
+	eventLanguageWritten = "language_written" // a language file was written
+	eventWarning         = "warning"
+	eventError           = "error"
+	eventInfo            = "info" // any other message of the run
+)
+
+// event is a structured event of a generation run, streamed as it happens for
+// orchestration systems and editors.
+type event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	File     string    `json:"file,omitempty"`
+	Language string    `json:"language,omitempty"`
+	Key      string    `json:"key,omitempty"`
+	Entries  *int      `json:"entries,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// eventStream writes events as NDJSON. A nil stream discards them, so runs
+// without -events emit unconditionally, and its warnf, errorf and infof only
+// log.
+type eventStream struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newEventStream(w io.Writer) *eventStream {
+	return &eventStream{encoder: json.NewEncoder(w)}
+}
+
+// emit writes an event, stamped with the current time.
+func (s *eventStream) emit(e event) {
+	if s == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoder.Encode(e)
+}
+
+// warnf logs a warning and emits it as a warning event.
+func (s *eventStream) warnf(format string, args ...any) {
+	message := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	log.Printf("Warning: %s\n", message)
+	s.emit(event{Type: eventWarning, Message: message})
+}
+
+// errorf logs a failure, of the run or of one of its steps, and emits it as an
+// error event.
+func (s *eventStream) errorf(format string, args ...any) {
+	message := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	log.Printf("%s\n", message)
+	s.emit(event{Type: eventError, Message: message})
+}
+
+// infof logs a message and emits it as an info event.
+func (s *eventStream) infof(format string, args ...any) {
+	message := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	log.Printf("%s\n", message)
+	s.emit(event{Type: eventInfo, Message: message})
+}
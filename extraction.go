@@ -0,0 +1,383 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// extractFlags are the flags deciding which keys are extracted from the protos
+// and how they are shaped. gen and the commands comparing the language files
+// with the protos, such as audit, export and import, share them, so that they
+// agree on the keys of a project.
+type extractFlags struct {
+	protoPatterns, descriptorSets, modules, includePaths  stringList
+	optionRules, staticKeys, keyTransforms, keySanitizers stringList
+	packages, excludePackages, excludePaths               stringList
+
+	extractorName, enumPrefix, enumSuffix, versionKeys, library, keySeparator *string
+	aliasesFile, enumAliases                                                  *string
+	enumRegex, enumExclude, keyRegex, keyExclude, unspecifiedRegex            *string
+	prefixNestedEnums, qualifyIDs, followImports, defaultExcludes             *bool
+	skipUnspecified, recoverErrors                                            *bool
+	parallel                                                                  *int
+}
+
+// addExtractFlags defines the flags of extractFlags on fs.
+func addExtractFlags(fs *flag.FlagSet) *extractFlags {
+	f := &extractFlags{}
+	f.extractorName = fs.String("extractor", extract.DefaultExtractor, "Extractor reading the keys of the files matching -P, among those registered with extract.Register")
+	f.enumPrefix = fs.String("prefix", "", "Only process enums with this prefix (optional)")
+	f.enumSuffix = fs.String("suffix", "", "Only process enums with this suffix (optional)")
+	f.prefixNestedEnums = fs.Bool("prefix-nested-enums", false, "Prefix the keys of the values of enums nested in messages with the enclosing messages, e.g. Order.PENDING")
+	f.versionKeys = fs.String("version-keys", versionsUnify, "How keys found in several versions of a package, e.g. user.v1 and user.v1beta1, are written: unify them into the key of the most stable, latest version, or namespace them by version (v1beta1.KEY)")
+	f.qualifyIDs = fs.Bool("qualify-ids", false, "Prefix validation ids that are not globally unique with their message/field path")
+	f.library = fs.String("library", "", "Namespace the keys as those of a shared library, prefixing them with this name and a dot, or with the module path of go.mod for auto (optional)")
+	f.keySeparator = fs.String("key-separator", ".", "Separator joining the namespaces of keys, such as the dot separated segments of ids, versions and -library (., : or /)")
+	fs.Var(&f.protoPatterns, "P", "Proto files to extract: a glob, ** matching any number of directories, e.g. api/**/*.proto, a directory or a file; may be repeated (default "+defaultProtoPattern+")")
+	fs.Var(&f.descriptorSets, "descriptor-set", "Compiled FileDescriptorSet to extract every file of, such as the output of protoc --descriptor_set_out --include_source_info or buf build, with the options and comments protoc resolved, or - for standard input; may be repeated, instead of or with -P")
+	fs.Var(&f.modules, "module", "Module of the Buf Schema Registry to extract every file of, e.g. buf.build/acme/errors:v1.2.0, fetched with the buf CLI (or $BUF) without a local checkout; may be repeated, instead of or with -P")
+	fs.Var(&f.includePaths, "I", "Include path used to find imported proto files; may be repeated")
+	f.followImports = fs.Bool("imports", false, "Also extract the enums of the files the proto files import, found below the -I include paths (the working directory by default), that their fields reference")
+	fs.Var(&f.optionRules, "option-rule", "Map a custom option to keys or messages, e.g. xerr.info.text=message; may be repeated")
+	fs.Var(&f.keySanitizers, "key-sanitize", "Replace the characters of keys a target format does not allow, e.g. '\\s+=_' for spaces in cel ids, after -key-separator; <regexp>=<replacement>, may be repeated, applied in order; keys mapped to the same key fail the run")
+	fs.Var(&f.keyTransforms, "key-transform", "Rewrite the extracted keys, e.g. s/^ERR_//, trim-prefix:ERR_, prefix:errors., lower, upper, camel or snake; may be repeated, applied in order")
+	fs.Var(&f.excludePaths, "exclude", "Skip the proto files and directories matching this glob below the directory of -P: a name, such as testdata, matches at any depth, a path, such as api/legacy/** or **/internal/*.proto, the whole path; may be repeated")
+	f.defaultExcludes = fs.Bool("default-excludes", true, "Skip the vendor, third_party and node_modules directories, which hold vendored protos such as googleapis")
+	fs.Var(&f.packages, "package", "Only extract the files of the proto packages matching this pattern, * matching any run of characters, e.g. myapp.errors.*; may be repeated")
+	fs.Var(&f.excludePackages, "exclude-package", "Skip the files of the proto packages matching this pattern, * matching any run of characters; may be repeated")
+	fs.Var(&f.staticKeys, "static-keys", "TOML file of keys not derived from protos, merged after the proto keys; may be repeated")
+	f.aliasesFile = fs.String("aliases", "", "JSON file mapping deprecated keys to the keys replacing them, such as the rename map of migrate; aliases are written to the language files until their optional until date (optional)")
+	f.enumRegex = fs.String("enum-regex", "", "Only extract the enums whose name, or Message.Enum path when nested, matches this regular expression (optional)")
+	f.enumExclude = fs.String("enum-exclude", "", "Skip the enums whose name, or Message.Enum path when nested, matches this regular expression (optional)")
+	f.keyRegex = fs.String("key-regex", "", "Only extract the keys, of enum values and validation rules, matching this regular expression (optional)")
+	f.keyExclude = fs.String("key-exclude", "", "Skip the keys, of enum values and validation rules, matching this regular expression (optional)")
+	f.skipUnspecified = fs.Bool("skip-unspecified", false, "Skip the enum values numbered 0, sentinels such as ERR_UNSPECIFIED = 0 nobody translates")
+	f.unspecifiedRegex = fs.String("unspecified-regex", "", "Skip the enum values whose name matches this regular expression, whatever their number, e.g. _(UNSPECIFIED|UNKNOWN)$ (optional)")
+	f.enumAliases = fs.String("enum-aliases", enumAliasesCanonical, "Keys of the values of enums with allow_alias sharing a number: only the first name (canonical), or every name, the others written as aliases of the first (all)")
+	f.parallel = fs.Int("parallel", runtime.NumCPU(), "Number of proto files parsed concurrently")
+	f.recoverErrors = fs.Bool("recover", false, "Skip the enum, message or other top-level declaration containing a proto syntax error and extract the rest of the file, reporting every error")
+	return f
+}
+
+// extraction is what the protos yield once shaped: the files parsed and their
+// entries, with the keys gen writes.
+type extraction struct {
+	protoFiles   []string // proto files found with -P
+	parsed       []extract.File
+	entries      []extract.Entry
+	originalKeys map[string]string // keys before -key-separator and -key-sanitize, by key
+	aliases      map[string]alias
+	aliasKeys    []string // keys written as aliases of others
+	events       *eventStream
+}
+
+// extract parses the protos of the flags and shapes the keys of their entries
+// as gen writes them. With suggestIDs, the validation rules without an id are
+// given one. Every file parsed is reported to events, if set, and the errors of
+// those that fail to parse are logged.
+func (f *extractFlags) extract(suggestIDs bool, events *eventStream) (*extraction, error) {
+	opts, err := f.options(suggestIDs)
+	if err != nil {
+		return nil, err
+	}
+	x, err := f.parse(opts, events)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.shape(x); err != nil {
+		return nil, err
+	}
+	if err := f.splitAliases(x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// options returns the extract.Options of the flags.
+func (f *extractFlags) options(suggestIDs bool) (extract.Options, error) {
+	if *f.enumAliases != enumAliasesCanonical && *f.enumAliases != enumAliasesAll {
+		return extract.Options{}, usageErrorf("unknown enum aliases: %s", *f.enumAliases)
+	}
+	filters := make(map[string]*regexp.Regexp)
+	for name, expr := range map[string]string{"unspecified-regex": *f.unspecifiedRegex, "enum-regex": *f.enumRegex, "enum-exclude": *f.enumExclude, "key-regex": *f.keyRegex, "key-exclude": *f.keyExclude} {
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return extract.Options{}, usageErrorf("invalid -%s: %w", name, err)
+		}
+		filters[name] = re
+	}
+	return extract.Options{
+		Extractor:         *f.extractorName,
+		EnumPrefix:        *f.enumPrefix,
+		EnumSuffix:        *f.enumSuffix,
+		SuggestIDs:        suggestIDs,
+		Recover:           *f.recoverErrors,
+		Workers:           *f.parallel,
+		PrefixNestedEnums: *f.prefixNestedEnums,
+		EnumAliases:       *f.enumAliases == enumAliasesAll,
+		SkipUnspecified:   *f.skipUnspecified,
+		Unspecified:       filters["unspecified-regex"],
+		EnumRegex:         filters["enum-regex"],
+		EnumExclude:       filters["enum-exclude"],
+		KeyRegex:          filters["key-regex"],
+		KeyExclude:        filters["key-exclude"],
+		Packages:          f.packages,
+		ExcludePackages:   f.excludePackages,
+	}, nil
+}
+
+// resolvePatterns sets -P to its default when no input is given, and makes
+// its patterns relative to the directory of a go:generate directive.
+func (f *extractFlags) resolvePatterns() {
+	if len(f.protoPatterns) == 0 && len(f.descriptorSets) == 0 && len(f.modules) == 0 {
+		f.protoPatterns = stringList{defaultProtoPattern}
+	}
+	for i, pattern := range f.protoPatterns {
+		if pattern != stdioPath {
+			f.protoPatterns[i] = resolveGeneratePath(pattern)
+		}
+	}
+}
+
+// sourceRoot returns the directory of the first -P pattern, or the working
+// directory without one.
+func (f *extractFlags) sourceRoot() string {
+	if len(f.protoPatterns) == 0 {
+		return "."
+	}
+	return extract.PatternRoot(f.protoPatterns[0])
+}
+
+// parse finds the proto files matching any of the patterns, each once, and
+// parses them with the descriptor sets, modules and imports of the flags.
+func (f *extractFlags) parse(opts extract.Options, events *eventStream) (*extraction, error) {
+	f.resolvePatterns()
+	extractor, err := extract.Lookup(*f.extractorName)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := []string(f.excludePaths)
+	if *f.defaultExcludes {
+		exclude = append(exclude, extract.DefaultExcludes...)
+	}
+	x := &extraction{}
+	var readStdin bool
+	for _, pattern := range f.protoPatterns {
+		readStdin = readStdin || pattern == stdioPath
+	}
+	if x.protoFiles, err = findFiles(f.protoPatterns, extractor, exclude); err != nil {
+		return nil, fmt.Errorf("failed to find proto files: %w", err)
+	}
+	if readStdin && slices.Contains(f.descriptorSets, stdioPath) {
+		return nil, usageErrorf("invalid -P - with -descriptor-set -, which both read standard input")
+	}
+	if len(x.protoFiles) == 0 && !readStdin && len(f.descriptorSets) == 0 && len(f.modules) == 0 {
+		return nil, fmt.Errorf("no proto files match %s", strings.Join(f.protoPatterns, ", "))
+	}
+
+	// Every file is reported as soon as it is parsed
+	parsed := func(p extract.File) {
+		entries := len(p.Entries)
+		events.emit(event{Type: eventFileParsed, File: p.Path, Entries: &entries})
+	}
+	x.events = events
+	parseOpts := opts
+	parseOpts.Parsed = parsed
+	parseOpts.Transcoded = func(filePath, encoding string) {
+		warnTranscoded(events, filePath, encoding)
+	}
+	x.parsed = extract.ParseFiles(x.protoFiles, parseOpts)
+	var others []extract.File
+	if readStdin {
+		others = append(others, readStdinProto(os.Stdin, opts))
+	}
+	for _, setPath := range f.descriptorSets {
+		others = append(others, descriptorSetFiles(setPath, opts)...)
+	}
+	for _, module := range f.modules {
+		others = append(others, moduleFiles(module, opts)...)
+	}
+	if *f.followImports {
+		others = append(others, importedEnums(x.protoFiles, f.includePaths, opts)...)
+	}
+	for _, p := range others {
+		parsed(p)
+	}
+	x.parsed = append(x.parsed, others...)
+	for _, p := range x.parsed {
+		if p.Err == nil {
+			continue
+		}
+		if syntaxErrs := extract.AsSyntaxErrors(p.Err); syntaxErrs != nil {
+			for _, syntaxErr := range syntaxErrs {
+				events.errorf("Failed to parse proto file: %v\n", syntaxErr)
+			}
+		} else {
+			events.errorf("Failed to parse proto file %s: %v\n", p.Path, p.Err)
+		}
+	}
+	x.entries = extract.Merge(x.parsed)
+	return x, nil
+}
+
+// shape turns the entries of the parsed files into those gen writes: option
+// rules are applied, versions resolved, keys qualified, transformed, joined
+// and sanitized, static keys added, namespaced by -library and made unique.
+func (f *extractFlags) shape(x *extraction) error {
+	if len(f.optionRules) > 0 {
+		extensions, err := loadExtensions(x.protoFiles, f.includePaths)
+		if err != nil {
+			return fmt.Errorf("failed to load option definitions: %w", err)
+		}
+		rules, err := parseOptionRules(f.optionRules, extensions)
+		if err != nil {
+			return usageErrorf("invalid option rule: %w", err)
+		}
+		applyOptionRules(x.entries, rules)
+		if len(f.descriptorSets) > 0 || len(f.modules) > 0 {
+			x.events.warnf("Option rules do not apply to the entries of -descriptor-set and -module, whose custom options are not read\n")
+		}
+	}
+
+	entries, warnings, err := resolveVersions(x.entries, *f.versionKeys)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		x.events.warnf("%s\n", warning)
+	}
+
+	if *f.qualifyIDs {
+		qualifyDuplicateIDs(entries)
+	}
+
+	if len(f.keyTransforms) > 0 {
+		transforms, err := parseKeyTransforms(f.keyTransforms)
+		if err != nil {
+			return usageErrorf("invalid key transformation: %w", err)
+		}
+		for _, warning := range applyKeyTransforms(entries, transforms) {
+			x.events.warnf("%s\n", warning)
+		}
+	}
+
+	if !validKeySeparator(*f.keySeparator) {
+		return usageErrorf("unknown key separator %q (%s)", *f.keySeparator, strings.Join(keySeparators, " "))
+	}
+	sanitizers, err := parseKeySanitizers(f.keySanitizers)
+	if err != nil {
+		return usageErrorf("invalid key sanitization rule: %w", err)
+	}
+	originalKeys, collisions := mapKeys(entries, *f.keySeparator, sanitizers)
+	if len(collisions) > 0 {
+		return fmt.Errorf("%s\n%d key(s) collide after -key-separator and -key-sanitize", strings.Join(collisions, "\n"), len(collisions))
+	}
+
+	protoKeys := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		protoKeys[e.Key] = true
+	}
+	for _, staticFile := range f.staticKeys {
+		static, err := loadStaticKeys(resolveGeneratePath(staticFile), x.events)
+		if err != nil {
+			return fmt.Errorf("failed to load static keys: %w", err)
+		}
+		for _, e := range static {
+			if protoKeys[e.Key] {
+				x.events.warnf("%s:%d: static key %s is already extracted from the protos\n", e.File, e.Line, e.Key)
+			}
+		}
+		entries = append(entries, static...)
+	}
+
+	if *f.library == libraryAuto {
+		if *f.library, err = goModuleName(resolveGeneratePath(".")); err != nil {
+			return fmt.Errorf("failed to name the library: %w", err)
+		}
+	}
+	if *f.library != "" {
+		namespaceLibrary(entries, *f.library, *f.keySeparator)
+		namespaced := make(map[string]string, len(originalKeys))
+		for key, original := range originalKeys {
+			namespaced[*f.library+*f.keySeparator+key] = original
+		}
+		originalKeys = namespaced
+	}
+
+	// Keep unique entries while maintaining order
+	x.entries, x.originalKeys = extract.Unique(entries), originalKeys
+	return nil
+}
+
+// splitAliases loads the aliases of -aliases and splits the entries of enum
+// value aliases out with -enum-aliases all, to be written as aliases.
+func (f *extractFlags) splitAliases(x *extraction) error {
+	var err error
+	if *f.aliasesFile != "" {
+		if x.aliases, err = loadAliases(resolveGeneratePath(*f.aliasesFile)); err != nil {
+			return fmt.Errorf("failed to load aliases: %w", err)
+		}
+		var warnings []string
+		x.aliasKeys, warnings = activeAliases(x.aliases, x.entries, time.Now().Format(time.DateOnly))
+		for _, warning := range warnings {
+			x.events.warnf("%s\n", warning)
+		}
+	}
+	if *f.enumAliases == enumAliasesAll {
+		var enumAliasKeys []string
+		x.entries, x.aliases, enumAliasKeys = splitEnumAliases(x.entries, x.aliases)
+		x.aliasKeys = append(x.aliasKeys, enumAliasKeys...)
+	}
+	return nil
+}
+
+// findFiles returns the files an extractor reads among those any of the
+// patterns match, each once, in the order of the patterns. Standard input, -,
+// is left to the caller.
+func findFiles(patterns []string, extractor extract.Extractor, exclude []string) ([]string, error) {
+	var files []string
+	found := make(map[string]bool)
+	for _, pattern := range patterns {
+		if pattern == stdioPath {
+			continue
+		}
+		matches, err := extract.Find(pattern, extractor, exclude...)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range matches {
+			if !found[filepath.Clean(file)] {
+				found[filepath.Clean(file)] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// findProtoFiles returns the proto files matching any of the -P patterns of the
+// commands that read protos without the other extraction flags, skipping the
+// default excludes, or those of the default pattern without one.
+func findProtoFiles(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{defaultProtoPattern}
+	}
+	extractor, err := extract.Lookup(extract.DefaultExtractor)
+	if err != nil {
+		return nil, err
+	}
+	return findFiles(patterns, extractor, extract.DefaultExcludes)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Policies for the value written when a key has no translation yet.
+const (
+	emptyBlank  = "blank"
+	emptyKey    = "key"
+	emptySource = "source"
+	emptyTodo   = "todo-prefix"
+)
+
+// todoPrefix marks placeholder values written by the todo-prefix policy.
+const todoPrefix = "TODO: "
+
+// applyEmptyValuePolicy sets the fallback of every entry: an empty string, the
+// key, the default message from the proto, or that message (or the key) marked
+// with a TODO prefix. With comments, the leading comment of an enum value
+// without a message, such as // User was not found, stands in for it.
+func applyEmptyValuePolicy(entries []extract.Entry, policy string, comments bool) error {
+	for i, e := range entries {
+		if e.Message == "" && comments && e.Kind == extract.KindEnum && e.Comment != "" {
+			e.Message = textutil.Escape(strings.Join(strings.Split(e.Comment, "\n"), " "))
+		}
+		switch policy {
+		case emptyBlank:
+			entries[i].Fallback = ""
+		case emptyKey:
+			entries[i].Fallback = textutil.Escape(e.Key)
+		case emptySource:
+			entries[i].Fallback = e.Message
+		case emptyTodo:
+			text := e.Message
+			if text == "" {
+				text = textutil.Escape(e.Key)
+			}
+			entries[i].Fallback = todoPrefix + text
+		default:
+			return usageErrorf("unknown empty value policy: %s", policy)
+		}
+	}
+	return nil
+}
+
+// seedTranslations returns copies of entries whose fallback in lang is the
+// default translation set with (i18n.msg) on their enum value, so that the
+// translation is written where the language file has none, or replaces it
+// when the file is overwritten. Locked keys keep their fallback.
+func seedTranslations(entries []extract.Entry, lang string, locked map[string]string) []extract.Entry {
+	seeded := make([]extract.Entry, len(entries))
+	for i, e := range entries {
+		if _, isLocked := locked[e.Key]; !isLocked {
+			if text, ok := optionTranslation(e.Translations, lang); ok {
+				e.Fallback = text
+			}
+		}
+		seeded[i] = e
+	}
+	return seeded
+}
+
+// optionTranslation returns the translation into lang among those of an
+// (i18n.msg) option, named by BCP 47 tag or, as the fields of the former
+// i18n.Messages, with _ for -, ignoring case.
+func optionTranslation(translations map[string]string, lang string) (string, bool) {
+	for name, text := range translations {
+		if strings.EqualFold(strings.ReplaceAll(name, "_", "-"), lang) {
+			return text, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+)
+
+// Why a logged lookup fell back, as the language files stand now.
+const (
+	fallbackMissing      = "missing"      // the language file has no such key
+	fallbackUntranslated = "untranslated" // the key has no value in the language file
+	fallbackUnknown      = "unknown"      // no language file has the key; it is not extracted
+)
+
+// fallbackAttrRe matches an attribute of a log line written by slog's text
+// handler, e.g. i18n_key=USER_NOT_FOUND or i18n_key="a key".
+var fallbackAttrRe = regexp.MustCompile(`\b(i18n_key|i18n_lang)=("(?:[^"\\]|\\.)*"|\S+)`)
+
+// fallbackItem is a key looked up without a translation in a language.
+type fallbackItem struct {
+	Key    string `json:"key"`
+	Lang   string `json:"lang"`
+	Count  int    `json:"count"`
+	Status string `json:"status"`
+}
+
+// fallbacksCommand implements the fallbacks command, which reads the runtime
+// logs of lookups of keys without a translation, as written by the LogMissing
+// hook of the -go-out package, and lists the keys and languages to fix,
+// those looked up most often first.
+func fallbacksCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	top := fs.Int("top", 0, "Only list this many keys and languages (0 for all)")
+	jsonName := fs.String("json", "", "Write the list as JSON to this file (optional)")
+
+	return func(args []string) error {
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+
+		counts := make(map[[2]string]int) // key and language -> lookups
+		readLog := func(r io.Reader, name string) error {
+			if err := readFallbackLog(r, counts); err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			return nil
+		}
+		if len(args) == 0 {
+			if err := readLog(os.Stdin, "standard input"); err != nil {
+				return err
+			}
+		}
+		for _, name := range args {
+			file, err := os.Open(name)
+			if err != nil {
+				return fmt.Errorf("failed to open log: %w", err)
+			}
+			err = readLog(file, name)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		values := make(map[string]map[string]string)
+		known := make(map[string]bool)
+		for _, lang := range splitLanguages(*languages) {
+			var err error
+			if values[lang], err = loadLanguage(inFormat, inFormat.Path(*outputDir, lang), nil); err != nil {
+				return fmt.Errorf("failed to load %s: %w", lang, err)
+			}
+			for key := range values[lang] {
+				known[key] = true
+			}
+		}
+
+		var items []fallbackItem
+		lookups, fixed := 0, 0
+		for id, count := range counts {
+			key, lang := id[0], id[1]
+			lookups += count
+			item := fallbackItem{Key: key, Lang: lang, Count: count}
+			value, ok := values[lang][key]
+			switch {
+			case !known[key]:
+				item.Status = fallbackUnknown
+			case values[lang] == nil || !ok:
+				item.Status = fallbackMissing
+			case value == "":
+				item.Status = fallbackUntranslated
+			default:
+				fixed++
+				continue
+			}
+			items = append(items, item)
+		}
+		slices.SortFunc(items, func(a, b fallbackItem) int {
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+			return strings.Compare(a.Key+"\x00"+a.Lang, b.Key+"\x00"+b.Lang)
+		})
+		if *top > 0 && len(items) > *top {
+			items = items[:*top]
+		}
+
+		fmt.Printf("%d fallback lookup(s) of %d key(s) and language(s); %d fixed since\n", lookups, len(counts), fixed)
+		for _, item := range items {
+			fmt.Printf("%8d  %-6s  %-12s  %s\n", item.Count, item.Lang, item.Status, item.Key)
+		}
+
+		if *jsonName != "" {
+			if items == nil {
+				items = []fallbackItem{}
+			}
+			data, err := json.MarshalIndent(items, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to write fallbacks: %w", err)
+			}
+			if err := os.WriteFile(*jsonName, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write fallbacks: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// readFallbackLog counts the lookups of keys without a translation in a log,
+// by key and language. Lines are JSON objects, possibly after a prefix added
+// by the log collector, or lines of slog's text handler, with the i18n_key and
+// i18n_lang attributes; other lines are ignored.
+func readFallbackLog(r io.Reader, counts map[[2]string]int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var key, lang string
+		if i := bytes.IndexByte(line, '{'); i >= 0 {
+			var record struct {
+				Key  string `json:"i18n_key"`
+				Lang string `json:"i18n_lang"`
+			}
+			if json.Unmarshal(line[i:], &record) == nil {
+				key, lang = record.Key, record.Lang
+			}
+		}
+		if key == "" {
+			for _, m := range fallbackAttrRe.FindAllSubmatch(line, -1) {
+				value := string(m[2])
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					value = unquoted
+				}
+				if string(m[1]) == "i18n_key" {
+					key = value
+				} else {
+					lang = value
+				}
+			}
+		}
+		if key != "" {
+			counts[[2]string{key, lang}]++
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// formattingDeviation is a run of lines of a language file formatted
+// differently from what generation writes for the same content, such as odd
+// spacing, keys out of order or stray blank lines.
+type formattingDeviation struct {
+	Line int      // first line of the run in the file
+	Keys []string // keys written on the lines of the run, if any
+}
+
+// String describes the deviation as its line and keys, or as blank lines.
+func (d formattingDeviation) String() string {
+	if len(d.Keys) == 0 {
+		return fmt.Sprintf("line %d (blank or comment lines)", d.Line)
+	}
+	return fmt.Sprintf("line %d (%s)", d.Line, strings.Join(d.Keys, ", "))
+}
+
+// formattingDeviations returns the runs of lines of an existing language file
+// that differ from the file generated from its own content: the entries it
+// already holds, in their order, and the keys the protos no longer extract.
+// These are formatting edits, told apart from the content changes of a merge.
+func formattingDeviations(entries, stale []extract.Entry, existing map[string]string, outFormat emit.Format, lang, langPath string, enc emit.Encoding) ([]formattingDeviation, error) {
+	before, _, err := textutil.ReadFile(langPath) // warned about when loaded
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []extract.Entry
+	for _, e := range entries {
+		if _, ok := existing[e.Key]; ok && !e.Commented {
+			kept = append(kept, e)
+		}
+	}
+	for _, e := range stale {
+		e.Commented = false
+		kept = append(kept, e)
+	}
+
+	// The canonical file is generated over a copy, so that it keeps the
+	// comments and header of the file as a merge does
+	dir, err := os.MkdirTemp("", "i18n-gen-formatting")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	canonicalPath := filepath.Join(dir, filepath.Base(langPath))
+	if err := copyFile(langPath, canonicalPath); err != nil {
+		return nil, err
+	}
+	if err := outFormat.Write(kept, lang, canonicalPath, false, enc, nil); err != nil {
+		return nil, err
+	}
+	canonical, _, err := textutil.ReadFile(canonicalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(kept))
+	for _, e := range kept {
+		keys = append(keys, e.Key)
+	}
+	// Longer keys first, so that a line names the key it writes rather than a
+	// key it starts with
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	beforeLines, canonicalLines := splitLines(trimCR(before)), splitLines(trimCR(canonical))
+	beforeSections, canonicalSections := sectionKeys(beforeLines, keys), sectionKeys(canonicalLines, keys)
+	var deviations []formattingDeviation
+	ops := diffLines(beforeLines, canonicalLines)
+	b, c := 0, 0 // indexes of the lines of the files
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			b, c, i = b+1, c+1, i+1
+			continue
+		}
+		deviation := formattingDeviation{Line: b + 1}
+		section := ""
+		seen := make(map[string]bool)
+		for ; i < len(ops) && ops[i].kind != ' '; i++ {
+			if ops[i].kind == '-' {
+				section = cmp.Or(section, beforeSections[b])
+				b++
+			} else {
+				section = cmp.Or(section, canonicalSections[c])
+				c++
+			}
+			if key := lineKey(ops[i].line, keys); key != "" && !seen[key] {
+				seen[key] = true
+				deviation.Keys = append(deviation.Keys, key)
+			}
+		}
+		// Lines naming no key, such as the fields of a TOML table, belong to
+		// the key named last
+		if len(deviation.Keys) == 0 && section != "" {
+			deviation.Keys = []string{section}
+		}
+		deviations = append(deviations, deviation)
+	}
+	return deviations, nil
+}
+
+// sectionKeys returns, for every line of a language file, the key named last
+// on it or the lines before it.
+func sectionKeys(lines, keys []string) []string {
+	sections := make([]string, len(lines))
+	section := ""
+	for i, line := range lines {
+		if key := lineKey(line, keys); key != "" {
+			section = key
+		}
+		sections[i] = section
+	}
+	return sections
+}
+
+// lineKey returns the first of the keys, longest first, that a line of a
+// language file names.
+func lineKey(line string, keys []string) string {
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+	for _, key := range keys {
+		if strings.Contains(line, key) {
+			return key
+		}
+	}
+	return ""
+}
+
+// trimCR drops the carriage returns of the line breaks of a file, which
+// generation keeps as found.
+func trimCR(data []byte) []byte {
+	return []byte(strings.ReplaceAll(string(data), "\r\n", "\n"))
+}
+
+// contentChanges returns the keys a merge added to a language file, removed
+// from it, and whose value it changed, each in key order.
+func contentChanges(before, after map[string]string) (added, removed, changed []string) {
+	for key, value := range after {
+		previous, ok := before[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case previous != value:
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// reportMerge logs the formatting deviations of a language file before it was
+// merged, separately from the content changes of the merge, so reviewers can
+// tell translation edits from formatting churn in the diff.
+func reportMerge(outFormat emit.Format, langPath string, before map[string]string, deviations []formattingDeviation, events *eventStream) error {
+	after, err := loadLanguage(outFormat, langPath, events)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(langPath)
+	if len(deviations) > 0 {
+		lines := make([]string, len(deviations))
+		for i, d := range deviations {
+			lines[i] = d.String()
+		}
+		events.infof("%s: formatting differed from the generated output, not a content change: %s\n", name, strings.Join(lines, "; "))
+	}
+	added, removed, changed := contentChanges(before, after)
+	for _, change := range []struct {
+		verb string
+		keys []string
+	}{{"added", added}, {"removed", removed}, {"changed the value of", changed}} {
+		if len(change.keys) > 0 {
+			events.infof("%s: content: %s %d key(s): %s\n", name, change.verb, len(change.keys), strings.Join(change.keys, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,809 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// generator runs gen in steps: it extracts the entries of the protos, checks
+// and shapes them, writes the language files and then the sidecar files next to
+// them. It holds the flags of gen and the state the steps pass on.
+type generator struct {
+	ef                  *extractFlags
+	outputDir           *string
+	languages           *string
+	manifestName        *string
+	writeHeaders        *bool
+	headerTime          *bool
+	force               *bool
+	catalogName         *string
+	examplesName        *string
+	reviewName          *string
+	modifiedName        *string
+	locksName           *string
+	assignKeyIDs        *bool
+	namespaceLangsFile  *string
+	ownersFile          *string
+	notify              *bool
+	ticketsFile         *string
+	requireReview       *string
+	suggestionsName     *string
+	sortOrder           *string
+	collateKeys         *bool
+	sourceLang          *string
+	sourceMode          *string
+	derivedMode         *string
+	emptyValue          *string
+	commentMessages     *bool
+	format              *string
+	tsKeysName          *string
+	keyMaxLen           *int
+	keyPattern          *string
+	keyMinDepth         *int
+	maxBundleBytes      *int64
+	maxBundleKeys       *int
+	writeBackProtos     *bool
+	goOut               *string
+	goPackage           *string
+	descriptionTemplate *string
+	aliasMode           *string
+	staleMode           *string
+	archiveDir          *string
+	encodingSpec        *string
+	quarantine          *bool
+	reportFormatting    *bool
+	dryRun              *bool
+	checkOnly           *bool
+	eventsFormat        *string
+	reportName          *string
+	printDirective      *string
+
+	events       *eventStream
+	langs        []string
+	toStdout     bool // the language file is written to standard output
+	outFormat    emit.Format
+	encoding     emit.Encoding
+	keyRules     keyRules
+	namespaces   namespaceLanguages
+	x            *extraction
+	langDir      string            // the output directory, or its copy for a dry run
+	generated    map[string]string // hashes of the language files as generated
+	locks        lockStore
+	notices      []newKeyNotice
+	tickets      *ticketConfig
+	untranslated map[string][]extract.Entry
+	tempDirs     []string // removed once the run ends
+	failed       bool     // a step failed but the run went on
+}
+
+// generateCommand generates or updates the language files.
+func generateCommand(fs *flag.FlagSet) func(args []string) error {
+	// Define flags
+	g := &generator{ef: addExtractFlags(fs)}
+	g.outputDir = fs.String("O", "./i18n/", "Path to the output directory")
+	g.languages = fs.String("L", "en,zh", "Comma-separated list of languages")
+	g.manifestName = fs.String("manifest", "", "Write a manifest of the keys and of the hashes of the language files, as generated, to this file in the output directory, e.g. manifest.json (optional)")
+	g.writeHeaders = fs.Bool("header", false, "Write a metadata block at the top of every language file with its language tag, plural categories and translation coverage, for the formats with comments")
+	g.headerTime = fs.Bool("header-time", false, "Also record the generation time in the -header block, so regenerated files differ between runs")
+	g.force = fs.Bool("force", false, "Overwrite language files edited by hand since they were last generated, which otherwise needs a confirmation on a terminal")
+	g.catalogName = fs.String("catalog", "", "Write a JSON catalog of the extracted keys and their proto metadata to this file in the output directory (optional)")
+	g.examplesName = fs.String("openapi-examples", "", "Write an OpenAPI examples object with a localized error response per enum value and language to this file in the output directory (optional)")
+	g.reviewName = fs.String("review", "", "Name of the file in the output directory keeping the review state of every translation (optional)")
+	g.modifiedName = fs.String("modified", "", "Name of the file in the output directory tracking when each source message and translation last changed (optional)")
+	g.locksName = fs.String("locks", "locks.json", "Name of the file in the output directory holding the signed-off values of locked keys (empty to disable)")
+	g.assignKeyIDs = fs.Bool("key-ids", false, "Assign every key a stable numeric id, kept in the locks file and across renames by -aliases, and write it as metadata of the keys")
+	g.namespaceLangsFile = fs.String("namespace-langs", "", "CODEOWNERS-style file restricting the keys of proto package patterns to a subset of the languages; other keys go into every language (optional)")
+	g.ownersFile = fs.String("owners", "", "CODEOWNERS-style file assigning key patterns to owning teams; new keys are reported by owner (optional)")
+	g.notify = fs.Bool("notify", false, "Post the new keys of each owner to the webhook of its owners rule")
+	g.ticketsFile = fs.String("tickets", "", "JSON file configuring the GitHub or Jira tickets created for the keys a run adds without a translation, one per language or per proto package (optional)")
+	g.requireReview = fs.String("require-review", "", "Fail when a translation has not reached this review state (new, machine, reviewed, final; optional)")
+	g.suggestionsName = fs.String("suggestions", "", "Derive ids for validation rules without one and write them to this file in the output directory (optional)")
+	g.sortOrder = fs.String("sort", sortSource, "Order of keys in the language files (source, alpha)")
+	g.collateKeys = fs.Bool("collate", false, "Sort alphabetically using the collation rules of each language instead of byte order")
+	g.sourceLang = fs.String("source-lang", "", "Language whose values come from the protos; updated according to -source-mode (optional)")
+	g.sourceMode = fs.String("source-mode", modeOverwrite, "How the source language file is updated: overwrite with the proto messages, or preserve existing values")
+	g.derivedMode = fs.String("derived-mode", modePreserve, "How the other language files are updated: preserve existing translations, or overwrite them with the -empty-value fallbacks")
+	g.emptyValue = fs.String("empty-value", emptySource, "Value written for keys without a translation (blank, key, source, todo-prefix)")
+	g.commentMessages = fs.Bool("comment-messages", true, "Use the leading comment of an enum value without a message as its source text for -empty-value source and todo-prefix")
+	g.format = fs.String("format", "toml", "Output format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	g.tsKeysName = fs.String("ts-keys", "", "Write a TypeScript union type of all keys to this file in the output directory (optional)")
+	g.keyMaxLen = fs.Int("key-max-len", 0, "Fail when a key is longer than this many characters (0 to disable)")
+	g.keyPattern = fs.String("key-pattern", "", "Fail when a key does not match this regular expression (optional)")
+	g.keyMinDepth = fs.Int("key-min-depth", 0, "Fail when a key has fewer namespace segments, separated by -key-separator (0 to disable)")
+	g.maxBundleBytes = fs.Int64("max-bundle-bytes", 0, "Fail when a language file is larger than this many bytes, suggesting namespaces to split out (0 to disable)")
+	g.maxBundleKeys = fs.Int("max-bundle-keys", 0, "Fail when a language file has more keys than this, suggesting namespaces to split out (0 to disable)")
+	g.writeBackProtos = fs.Bool("write-back", false, "Insert derived ids and missing messages into the cel blocks of the proto files, and missing translations into the (i18n.msg) options of enum values")
+	g.goOut = fs.String("go-out", "", "Generate a Go package with typed keys and a hot-reloading loader in this directory (optional)")
+	g.goPackage = fs.String("go-package", "", "Name of the generated Go package (default: base name of -go-out)")
+	g.descriptionTemplate = fs.String("description-template", "", "Go text/template file rendering the description of each key for translators from its comment, constraint, options and gRPC/HTTP status, instead of the comment alone (optional)")
+	g.aliasMode = fs.String("alias-mode", aliasDuplicate, "How aliases are written: duplicate the value of the aliased key, or reference it (fluent, i18next and i18next-ns only)")
+	g.staleMode = fs.String("stale", staleKeep, "What happens to keys of the language files no longer extracted from the protos: keep them, comment them out with the date (toml only), prune them, or archive them to the -archive directory")
+	g.archiveDir = fs.String("archive", "archive", "Directory, relative to the output directory, of the language files keys are moved to with -stale archive")
+	g.encodingSpec = fs.String("encoding", "utf-8", "Encoding of the language files: utf-8, or a comma-separated list of nfc (normalize to NFC), ascii (escape non-ASCII characters in the syntax of the format; not po or fluent) and utf-16le or utf-16be (with a byte order mark)")
+	g.quarantine = fs.Bool("quarantine", false, "Move existing language files that fail to parse aside to <file>.invalid and regenerate them, instead of failing")
+	g.reportFormatting = fs.Bool("report-formatting", false, "Report the lines of merged language files formatted differently from the generated output, such as odd spacing, keys out of order or stray blank lines, separately from the keys whose content the merge changes")
+	g.dryRun = fs.Bool("dry-run", false, "Print a unified diff of every language file that would change instead of writing anything")
+	g.checkOnly = fs.Bool("check", false, "Compare the extracted keys with the existing language files without writing anything, and fail listing the missing, extra and empty keys")
+	g.eventsFormat = fs.String("events", "", "Stream the events of the run, such as files parsed, keys added, languages written, warnings and errors, to standard output in this format as they happen: ndjson (optional)")
+	g.reportName = fs.String("report", "", "Write a JSON report of the run, with every proto file that failed to parse and where, to this file in the output directory (optional)")
+	g.printDirective = fs.String("print-directive", "", "Print the go:generate directive for the other flags, with paths relative to this package directory, and exit")
+	fs.String("config", "", configUsage)
+
+	return func(_ []string) error {
+		if *g.printDirective != "" {
+			directive, err := generateDirective(fs, *g.printDirective)
+			if err != nil {
+				return fmt.Errorf("failed to build directive: %w", err)
+			}
+			fmt.Println(directive)
+			return nil
+		}
+		return g.run()
+	}
+}
+
+// run generates the language files. The error stopping the run is reported
+// like the failures of the steps it goes on after, which fail it once it ends.
+func (g *generator) run() error {
+	defer func() {
+		for _, dir := range g.tempDirs {
+			os.RemoveAll(dir)
+		}
+	}()
+	if err := g.generate(); err != nil {
+		if !errors.As(err, new(reportedError)) {
+			g.events.errorf("%s\n", sentence(err.Error()))
+			err = reportedError{err}
+		}
+		return err
+	}
+	if g.failed {
+		return errFailed
+	}
+	return nil
+}
+
+// generate runs the steps of the run in order.
+func (g *generator) generate() error {
+	if err := g.configure(); err != nil {
+		return err
+	}
+	if err := g.extract(); err != nil {
+		return err
+	}
+	if err := g.check(); err != nil {
+		return err
+	}
+	if len(g.x.entries) == 0 {
+		g.events.infof("No entries found in any proto files\n")
+		return nil
+	}
+	if err := g.shape(); err != nil {
+		return err
+	}
+	if *g.checkOnly {
+		g.compare()
+		return nil
+	}
+	if err := g.write(); err != nil {
+		return err
+	}
+	if *g.dryRun {
+		diff, err := diffTrees(*g.outputDir, g.langDir)
+		if err != nil {
+			return fmt.Errorf("failed to compare language files: %w", err)
+		}
+		fmt.Print(diff)
+		return nil
+	}
+	return g.sidecars()
+}
+
+// fail reports the failure of a step the run goes on after, which fails the
+// run once it ends.
+func (g *generator) fail(format string, args ...any) {
+	g.events.errorf(format, args...)
+	g.failed = true
+}
+
+// tempDir creates a temporary directory, removed once the run ends.
+func (g *generator) tempDir(pattern string) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err == nil {
+		g.tempDirs = append(g.tempDirs, dir)
+	}
+	return dir, err
+}
+
+// configure checks the flags and sets up the event stream, the output
+// directory, the format and encoding of the language files, the key rules and
+// the languages of the namespaces.
+func (g *generator) configure() error {
+	switch *g.eventsFormat {
+	case "":
+	case eventsNDJSON:
+		if *g.dryRun {
+			return usageErrorf("invalid -events with -dry-run, which prints the diff to standard output")
+		}
+		g.events = newEventStream(os.Stdout)
+	default:
+		return usageErrorf("unknown events format: %s", *g.eventsFormat)
+	}
+
+	g.langs = splitLanguages(*g.languages)
+
+	// The language file written to standard output is generated in a
+	// temporary output directory
+	if *g.outputDir == stdioPath {
+		switch {
+		case len(g.langs) != 1:
+			return usageErrorf("invalid -O -: standard output takes the file of a single language, set one with -L")
+		case *g.dryRun || g.events != nil:
+			return usageErrorf("invalid -O - with -dry-run or -events, which also write to standard output")
+		}
+		dir, err := g.tempDir("i18n-gen-stdout")
+		if err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		g.toStdout = true
+		*g.outputDir = dir
+	} else {
+		*g.outputDir = resolveGeneratePath(*g.outputDir)
+	}
+
+	var ok bool
+	if g.outFormat, ok = emit.Formats[*g.format]; !ok {
+		return usageErrorf("unknown output format: %s", *g.format)
+	}
+	var err error
+	if g.encoding, err = emit.ParseEncoding(*g.encodingSpec, g.outFormat); err != nil {
+		return usageErrorf("unsupported encoding for format %s: %w", *g.format, err)
+	}
+
+	if *g.ef.aliasesFile != "" || *g.ef.enumAliases == enumAliasesAll {
+		if *g.aliasMode != aliasDuplicate && (*g.aliasMode != aliasReference || !referenceFormats[*g.format]) {
+			return usageErrorf("unsupported alias mode for format %s: %s", *g.format, *g.aliasMode)
+		}
+	}
+	if *g.requireReview != "" && reviewRank(*g.requireReview) < 0 {
+		return usageErrorf("invalid required review state: %s", *g.requireReview)
+	}
+	if *g.requireReview != "" && *g.reviewName == "" {
+		return usageErrorf("invalid -require-review: the review states are kept in the review file, set -review")
+	}
+	if *g.sortOrder != sortSource && *g.sortOrder != sortAlpha {
+		return usageErrorf("unknown sort order: %s", *g.sortOrder)
+	}
+	if _, ok := staleVerbs[*g.staleMode]; !ok || (*g.staleMode == staleComment && !commentFormats[*g.format]) {
+		return usageErrorf("unsupported stale mode for format %s: %s", *g.format, *g.staleMode)
+	}
+	if *g.goOut != "" && (*g.format != "toml" || !g.encoding.UTF8()) {
+		return usageErrorf("invalid -go-out with -format %s and -encoding %s: the generated package reads UTF-8 TOML language files", *g.format, *g.encodingSpec)
+	}
+	if *g.assignKeyIDs && *g.locksName == "" {
+		return usageErrorf("invalid -key-ids: the ids are kept in the locks file, set -locks")
+	}
+	if *g.writeHeaders && g.outFormat.Comment == nil {
+		g.events.warnf("-header is not written in the %s format, which has no comments\n", *g.format)
+	}
+
+	g.keyRules = keyRules{MaxLen: *g.keyMaxLen, MinDepth: *g.keyMinDepth, Separator: *g.ef.keySeparator}
+	if *g.keyPattern != "" {
+		if g.keyRules.Pattern, err = regexp.Compile(*g.keyPattern); err != nil {
+			return usageErrorf("invalid key pattern: %w", err)
+		}
+	}
+
+	if *g.namespaceLangsFile != "" {
+		if g.namespaces, err = loadNamespaceLanguages(resolveGeneratePath(*g.namespaceLangsFile)); err != nil {
+			return fmt.Errorf("failed to load namespace languages: %w", err)
+		}
+	}
+	return nil
+}
+
+// extract parses the protos and shapes the keys of their entries as gen writes
+// them. The proto files that fail to parse are logged and fail the run.
+func (g *generator) extract() error {
+	opts, err := g.ef.options(*g.suggestionsName != "" || *g.writeBackProtos)
+	if err != nil {
+		return err
+	}
+	if g.x, err = g.ef.parse(opts, g.events); err != nil {
+		return err
+	}
+	for _, p := range g.x.parsed {
+		if p.Err != nil {
+			g.failed = true
+		}
+	}
+	if err := g.ef.shape(g.x); err != nil {
+		return err
+	}
+	if *g.reportName != "" {
+		if err := writeRunReport(g.x.parsed, extract.Merge(g.x.parsed), filepath.Join(*g.outputDir, *g.reportName)); err != nil {
+			g.fail("Failed to write run report: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// check warns of the keys only differing by case or separators, and fails the
+// run on the enum values outside their code range and the keys breaking the key
+// rules, listing them.
+func (g *generator) check() error {
+	for _, warning := range checkKeyCollisions(g.x.entries) {
+		g.events.warnf("%s\n", warning)
+	}
+	violations := append(checkCodeRanges(g.x.entries), checkKeyRules(g.x.entries, g.keyRules)...)
+	for _, v := range violations {
+		g.events.errorf("%v\n", v)
+	}
+	if len(violations) > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+// shape gives the keys without a message their value and the keys their
+// description for translators, and splits off the aliases.
+func (g *generator) shape() error {
+	// Before the description template replaces the comments
+	if err := applyEmptyValuePolicy(g.x.entries, *g.emptyValue, *g.commentMessages); err != nil {
+		return err
+	}
+	if *g.descriptionTemplate != "" {
+		tmpl, err := loadDescriptionTemplate(resolveGeneratePath(*g.descriptionTemplate))
+		if err != nil {
+			return fmt.Errorf("failed to load description template: %w", err)
+		}
+		if err := applyDescriptionTemplate(g.x.entries, tmpl); err != nil {
+			return fmt.Errorf("failed to render description: %w", err)
+		}
+	}
+	return g.ef.splitAliases(g.x)
+}
+
+// compare compares the extracted keys with the existing language files for
+// -check, failing the run on the keys generation would add, drop or fill in
+// with the stale and empty value policies of the flags, and on the keys
+// colliding in the format.
+func (g *generator) compare() {
+	for _, lang := range g.langs {
+		langEntries := g.namespaces.entries(g.x.entries, lang)
+		langPath := g.outFormat.Path(*g.outputDir, lang)
+		if g.outFormat.Collisions != nil {
+			if err := g.outFormat.Collisions(langEntries, lang); err != nil {
+				g.fail("%s: %v\n", filepath.Base(langPath), err)
+			}
+		}
+		existing, err := loadLanguage(g.outFormat, langPath, g.events)
+		if err != nil {
+			g.fail("Invalid %s: %v\n", filepath.Base(langPath), err)
+			continue
+		}
+		for _, problem := range checkCatalog(langEntries, g.x.aliasKeys, existing, lang, langPath, *g.staleMode) {
+			g.fail("%v\n", problem)
+		}
+	}
+	if !g.failed {
+		g.events.infof("Language files are up to date with the protos.\n")
+	}
+}
+
+// write generates or updates the language files, once the existing ones are
+// known safe to rewrite.
+func (g *generator) write() error {
+	if err := g.prepare(); err != nil {
+		return err
+	}
+	if err := g.protect(); err != nil {
+		return err
+	}
+	if err := g.newKeys(); err != nil {
+		return err
+	}
+	for _, lang := range g.langs {
+		if err := g.writeLanguage(lang); err != nil {
+			return err
+		}
+	}
+	if !*g.dryRun {
+		if err := writeGeneratedHashes(*g.outputDir, g.generated); err != nil {
+			g.fail("Failed to record the hashes of the generated files: %v\n", err)
+		}
+	}
+	if g.toStdout {
+		if err := writeStdout(g.outFormat.Path(g.langDir, g.langs[0])); err != nil {
+			return fmt.Errorf("failed to write to standard output: %w", err)
+		}
+	}
+	return nil
+}
+
+// prepare creates the output directory, or the copy of it a dry run writes to,
+// and loads the locks and assigns the key ids.
+func (g *generator) prepare() error {
+	// Everything the run writes goes to a copy of the output directory for a
+	// dry run, which the diff is taken against
+	g.langDir = *g.outputDir
+	if *g.dryRun {
+		dir, err := g.tempDir("i18n-gen-dry-run")
+		if err != nil {
+			return fmt.Errorf("failed to create dry run directory: %w", err)
+		}
+		g.langDir = dir
+		if err := copyTree(*g.outputDir, g.langDir); err != nil {
+			return fmt.Errorf("failed to copy output directory: %w", err)
+		}
+	} else if err := os.MkdirAll(*g.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// Language files written in UTF-16 on purpose are not warned about
+	if !g.encoding.UTF8() {
+		for _, lang := range g.langs {
+			expectTranscoding(g.outFormat.Path(*g.outputDir, lang))
+			expectTranscoding(g.outFormat.Path(g.langDir, lang))
+		}
+	}
+
+	var err error
+	if g.generated, err = loadGeneratedHashes(*g.outputDir); err != nil {
+		return fmt.Errorf("failed to load the hashes of the generated files: %w", err)
+	}
+
+	g.locks = make(lockStore)
+	if *g.locksName != "" {
+		if g.locks, err = loadLocks(filepath.Join(*g.outputDir, *g.locksName)); err != nil {
+			return fmt.Errorf("failed to load locks: %w", err)
+		}
+	}
+
+	if *g.assignKeyIDs {
+		idsPath := filepath.Join(g.langDir, *g.locksName)
+		ids, err := loadKeyIDs(idsPath)
+		if err != nil {
+			return fmt.Errorf("failed to load key ids: %w", err)
+		}
+		renames := make(map[string]string, len(g.x.aliases))
+		for from, a := range g.x.aliases {
+			renames[from] = a.Key
+		}
+		if added := ids.assign(g.x.entries, renames); added > 0 {
+			if err := writeKeyIDs(ids, idsPath); err != nil {
+				return fmt.Errorf("failed to write key ids: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// protect refuses to rewrite language files that do not fully parse, since
+// their unreadable translations would be lost, unless quarantined, or whose
+// locked values changed. It also refuses to overwrite translator edits made
+// directly on disk, not yet committed, unless forced or confirmed.
+func (g *generator) protect() error {
+	invalid := false
+	for _, lang := range g.langs {
+		langPath := g.outFormat.Path(g.langDir, lang)
+		existing, err := loadLanguage(g.outFormat, langPath, g.events)
+		if err == nil {
+			for _, conflict := range checkLocks(g.locks, lang, existing, g.x.entries) {
+				g.events.errorf("%v\n", conflict)
+				invalid = true
+			}
+			continue
+		}
+		if !*g.quarantine {
+			g.events.errorf("Invalid %s: %v\n", filepath.Base(langPath), err)
+			invalid = true
+			continue
+		}
+		if err := os.Rename(langPath, langPath+".invalid"); err != nil {
+			g.events.errorf("Failed to quarantine %s: %v\n", filepath.Base(langPath), err)
+			invalid = true
+			continue
+		}
+		if *g.dryRun {
+			g.events.warnf("%s is invalid and would be moved to %s: %v\n", filepath.Base(langPath), filepath.Base(langPath)+".invalid", err)
+		} else {
+			g.events.warnf("%s is invalid and was moved to %s: %v\n", filepath.Base(langPath), filepath.Base(langPath)+".invalid", err)
+		}
+	}
+	if invalid {
+		return errFailed
+	}
+
+	if *g.dryRun || *g.force {
+		return nil
+	}
+	// The hashes of a manifest written before they were recorded on every run
+	// also count
+	recorded := make(map[string]string)
+	if *g.manifestName != "" {
+		manifest, err := loadManifestHashes(filepath.Join(*g.outputDir, *g.manifestName))
+		if err != nil {
+			return fmt.Errorf("failed to load manifest: %w", err)
+		}
+		maps.Copy(recorded, manifest)
+	}
+	maps.Copy(recorded, g.generated)
+	dirty, err := dirtyLanguageFiles(*g.outputDir, languagePaths(g.outFormat, *g.outputDir, g.langs), recorded)
+	if err != nil {
+		return fmt.Errorf("failed to check language files for manual edits: %w", err)
+	}
+	if len(dirty) > 0 && !confirmOverwrite(dirty, os.Stdin, os.Stderr) {
+		return fmt.Errorf("refusing to overwrite language files edited by hand since last generated: %s; commit the edits or use -force", strings.Join(dirty, ", "))
+	}
+	return nil
+}
+
+// newKeys finds the keys missing from the first language's file, which are new
+// and reported to their owners, and loads the configuration of the tickets for
+// the keys the run adds without a translation.
+func (g *generator) newKeys() error {
+	if *g.ownersFile != "" {
+		ownerRules, err := loadOwners(*g.ownersFile)
+		if err != nil {
+			return fmt.Errorf("failed to load owners: %w", err)
+		}
+		if len(g.langs) > 0 {
+			existing, err := loadLanguage(g.outFormat, g.outFormat.Path(*g.outputDir, g.langs[0]), g.events)
+			if err != nil {
+				return fmt.Errorf("failed to load existing translations: %w", err)
+			}
+			g.notices = routeNewKeys(g.namespaces.entries(g.x.entries, g.langs[0]), existing, ownerRules)
+		}
+	}
+
+	g.untranslated = make(map[string][]extract.Entry)
+	if *g.ticketsFile != "" {
+		var err error
+		if g.tickets, err = loadTicketConfig(resolveGeneratePath(*g.ticketsFile)); err != nil {
+			return fmt.Errorf("failed to load ticket configuration: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeLanguage generates or updates the language file of lang. Its failures
+// only fail the run, which goes on with the next language.
+func (g *generator) writeLanguage(lang string) error {
+	langPath := g.outFormat.Path(g.langDir, lang)
+	langEntries := sortEntries(g.namespaces.entries(g.x.entries, lang), *g.sortOrder, lang, *g.collateKeys)
+	mode, err := languageMode(lang, *g.sourceLang, *g.sourceMode, *g.derivedMode)
+	if err != nil {
+		return err
+	}
+	if mode == modeOverwrite {
+		if langEntries, err = overwriteLanguage(langEntries, g.outFormat, langPath, g.locks[lang]); err != nil {
+			g.fail("Failed to overwrite %s: %v\n", filepath.Base(langPath), err)
+			return nil
+		}
+	}
+	langEntries = seedTranslations(langEntries, lang, g.locks[lang])
+	if len(g.x.aliasKeys) > 0 {
+		// Aliases follow the translation the aliased key keeps
+		var existing map[string]string
+		if mode == modePreserve {
+			if existing, err = loadLanguage(g.outFormat, langPath, g.events); err != nil {
+				g.fail("Failed to load %s: %v\n", filepath.Base(langPath), err)
+				return nil
+			}
+		}
+		langEntries = appendAliases(langEntries, g.x.aliases, g.x.aliasKeys, existing, *g.aliasMode)
+	}
+	existing, err := loadLanguage(g.outFormat, langPath, g.events)
+	if err != nil {
+		g.fail("Failed to load %s: %v\n", filepath.Base(langPath), err)
+		return nil
+	}
+	var header *emit.Header
+	if *g.writeHeaders {
+		var generated time.Time
+		if *g.headerTime {
+			generated = time.Now()
+		}
+		translated, keys := languageCoverage(langEntries, existing, lang, mode == modeOverwrite)
+		header = emit.NewHeader(lang, translated, keys, generated)
+	}
+	stale := staleEntries(langEntries, existing, langPath, *g.staleMode, time.Now().Format(time.DateOnly))
+	if len(stale) > 0 {
+		g.events.infof("%s: %s %d key(s) no longer extracted from the protos: %s\n", filepath.Base(langPath), staleVerbs[*g.staleMode], len(stale), strings.Join(entryKeys(stale), ", "))
+	}
+	var deviations []formattingDeviation
+	if *g.reportFormatting && mode != modeOverwrite {
+		if deviations, err = formattingDeviations(langEntries, stale, existing, g.outFormat, lang, langPath, g.encoding); err != nil {
+			g.fail("Failed to check the formatting of %s: %v\n", filepath.Base(langPath), err)
+		}
+	}
+	switch *g.staleMode {
+	case staleKeep, staleComment:
+		langEntries = append(langEntries, stale...)
+	case staleArchive:
+		archivePath := g.outFormat.Path(filepath.Join(g.langDir, *g.archiveDir), lang)
+		if err := archiveStale(stale, langEntries, g.outFormat, lang, archivePath, g.encoding); err != nil {
+			g.fail("Failed to archive %s: %v\n", filepath.Base(langPath), err)
+			return nil
+		}
+	}
+	if err := g.outFormat.Write(langEntries, lang, langPath, mode == modeOverwrite, g.encoding, header); err != nil {
+		g.fail("Failed to generate %s: %v\n", filepath.Base(langPath), err)
+		return nil
+	}
+	if !*g.dryRun {
+		hashes, err := languageFileHashes(*g.outputDir, []string{langPath})
+		if err != nil {
+			g.fail("Failed to hash %s: %v\n", filepath.Base(langPath), err)
+		}
+		maps.Copy(g.generated, hashes)
+	}
+	if g.events != nil {
+		for _, e := range langEntries {
+			if _, ok := existing[e.Key]; !ok && !e.Commented {
+				g.events.emit(event{Type: eventKeyAdded, File: langPath, Language: lang, Key: e.Key})
+			}
+		}
+		entries := len(langEntries)
+		g.events.emit(event{Type: eventLanguageWritten, File: langPath, Language: lang, Entries: &entries})
+	}
+	if *g.reportFormatting && mode != modeOverwrite {
+		if err := reportMerge(g.outFormat, langPath, existing, deviations, g.events); err != nil {
+			g.fail("Failed to load %s: %v\n", filepath.Base(langPath), err)
+		}
+	}
+	if !*g.dryRun {
+		g.events.infof("%s generated/updated successfully.", filepath.Base(langPath))
+	}
+	if g.tickets != nil && !*g.dryRun {
+		written, err := loadLanguage(g.outFormat, langPath, g.events)
+		if err != nil {
+			g.fail("Failed to load %s: %v\n", filepath.Base(langPath), err)
+		}
+		g.untranslated[lang] = newUntranslatedKeys(langEntries, existing, written, lang == *g.sourceLang)
+	}
+
+	// The default language also provides the default resources
+	if g.outFormat.DefaultPath != nil && lang == defaultLanguage(*g.sourceLang, *g.languages) {
+		if err := copyFile(langPath, g.outFormat.DefaultPath(g.langDir)); err != nil {
+			g.fail("Failed to write default resources: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// sidecars writes the files next to the language files derived from the keys,
+// such as the manifest and catalog, files the keys elsewhere, such as tickets
+// and write-back, and fails the run on the review states and bundle sizes
+// short of their requirements.
+func (g *generator) sidecars() error {
+	if *g.manifestName != "" {
+		files, err := languageFileHashes(*g.outputDir, languagePaths(g.outFormat, *g.outputDir, g.langs))
+		if err != nil {
+			g.fail("Failed to hash language files: %v\n", err)
+		}
+		if err := writeManifest(g.x.entries, g.x.originalKeys, files, filepath.Join(*g.outputDir, *g.manifestName)); err != nil {
+			g.fail("Failed to write manifest: %v\n", err)
+		}
+	}
+
+	if *g.modifiedName != "" {
+		if err := recordModified(g.x.entries, g.outFormat, *g.outputDir, g.langs, g.namespaces, filepath.Join(*g.outputDir, *g.modifiedName), g.ef.sourceRoot()); err != nil {
+			g.fail("Failed to record modification times: %v\n", err)
+		}
+	}
+
+	if *g.catalogName != "" {
+		if err := writeCatalog(g.x.entries, *g.ef.library, filepath.Join(*g.outputDir, *g.catalogName)); err != nil {
+			g.fail("Failed to write catalog: %v\n", err)
+		}
+	}
+
+	if *g.examplesName != "" {
+		if err := writeOpenAPIExamples(g.x.entries, g.outFormat, *g.outputDir, g.langs, g.namespaces, filepath.Join(*g.outputDir, *g.examplesName)); err != nil {
+			g.fail("Failed to write OpenAPI examples: %v\n", err)
+		}
+	}
+
+	if *g.reviewName != "" {
+		reviewPath := filepath.Join(*g.outputDir, *g.reviewName)
+		store, err := loadReviewStore(reviewPath)
+		if err != nil {
+			return fmt.Errorf("failed to load review states: %w", err)
+		}
+		syncReviewStore(store, g.x.entries, g.langs, g.namespaces)
+		if err := writeReviewStore(store, reviewPath); err != nil {
+			g.fail("Failed to write review states: %v\n", err)
+		}
+		if *g.requireReview != "" {
+			violations := checkReviewStates(store, g.x.entries, g.langs, g.namespaces, *g.requireReview)
+			for _, v := range violations {
+				g.events.errorf("%v\n", v)
+			}
+			if len(violations) > 0 {
+				return errFailed
+			}
+		}
+	}
+
+	if g.tickets != nil {
+		for _, ticket := range g.tickets.ticketsFor(g.langs, g.untranslated) {
+			url, err := g.tickets.createTicket(ticket)
+			if err != nil {
+				g.fail("Failed to create ticket for %s%s: %v\n", ticket.Language, ticket.Namespace, err)
+				continue
+			}
+			g.events.infof("Created ticket for %d key(s) of %s%s: %s\n", len(ticket.Keys), ticket.Language, ticket.Namespace, url)
+		}
+	}
+
+	for _, notice := range g.notices {
+		g.events.infof("New keys for %s: %s\n", notice.Owner, strings.Join(notice.Keys, ", "))
+		if *g.notify && notice.webhook != "" {
+			if err := notifyOwner(notice); err != nil {
+				g.fail("Failed to notify %s: %v\n", notice.Owner, err)
+			}
+		}
+	}
+
+	if *g.suggestionsName != "" {
+		if err := writeSuggestions(g.x.entries, filepath.Join(*g.outputDir, *g.suggestionsName)); err != nil {
+			g.fail("Failed to write suggestions: %v\n", err)
+		}
+	}
+
+	if *g.tsKeysName != "" {
+		if err := emit.TypeScriptKeys(g.x.entries, filepath.Join(*g.outputDir, *g.tsKeysName)); err != nil {
+			g.fail("Failed to write TypeScript keys: %v\n", err)
+		}
+	}
+
+	if *g.goOut != "" {
+		if err := writeGoPackage(g.x.entries, *g.goOut, *g.goPackage, *g.ef.library); err != nil {
+			g.fail("Failed to generate Go package: %v\n", err)
+		} else if err := writeGRPCCodes(g.x.entries, *g.goOut, *g.goPackage, *g.ef.library, g.events); err != nil {
+			g.fail("Failed to generate gRPC codes: %v\n", err)
+		}
+	}
+
+	if *g.writeBackProtos {
+		// Messages missing from the proto are taken from the first language's file
+		var messages map[string]string
+		var firstLang string
+		if len(g.langs) > 0 {
+			firstLang = g.langs[0]
+			var err error
+			if messages, err = loadLanguage(g.outFormat, g.outFormat.Path(*g.outputDir, firstLang), g.events); err != nil {
+				return fmt.Errorf("failed to load messages for write-back: %w", err)
+			}
+		}
+		if err := writeBack(g.x.entries, messages, firstLang, g.events); err != nil {
+			g.fail("Failed to write back proto files: %v\n", err)
+		}
+	}
+
+	if *g.maxBundleBytes > 0 || *g.maxBundleKeys > 0 {
+		budget := bundleBudget{MaxBytes: *g.maxBundleBytes, MaxKeys: *g.maxBundleKeys}
+		violations, err := checkBundleBudget(g.x.entries, g.outFormat, *g.outputDir, g.langs, budget)
+		if err != nil {
+			return fmt.Errorf("failed to check bundle sizes: %w", err)
+		}
+		for _, v := range violations {
+			g.events.errorf("%v\n", v)
+		}
+		if len(violations) > 0 {
+			return errFailed
+		}
+	}
+	return nil
+}
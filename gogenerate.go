@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pathFlags are the flags holding paths that are relative to the working directory.
+var pathFlags = map[string]bool{"P": true, "O": true, "I": true}
+
+// resolveGeneratePath resolves a relative path against the directory of the file
+// holding the //go:generate directive when running under go generate, so that
+// paths in directives are relative to their package. Paths stay relative to keep
+// the generated files free of machine specific locations.
+func resolveGeneratePath(path string) string {
+	goFile := os.Getenv("GOFILE")
+	if goFile == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(goFile), path)
+}
+
+// generateDirective returns the //go:generate directive reproducing the flags set
+// on fs, with path flags rewritten relative to the package directory pkgDir.
+func generateDirective(fs *flag.FlagSet, pkgDir string) (string, error) {
+	absPkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve package directory: %w", err)
+	}
+
+	v, _, _ := buildInfo()
+	if versionParts(v)[0] < 0 || strings.Contains(v, "-") {
+		v = "latest"
+	}
+	args := []string{"//go:generate", "go", "run", "github.com/protoc-gen/i18n-gen@" + v}
+
+	var visitErr error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "print-directive" {
+			return
+		}
+		values := []string{f.Value.String()}
+		if list, ok := f.Value.(*stringList); ok {
+			values = *list
+		}
+		for _, value := range values {
+			if pathFlags[f.Name] {
+				abs, err := filepath.Abs(value)
+				if err == nil {
+					value, err = filepath.Rel(absPkgDir, abs)
+				}
+				if err != nil {
+					visitErr = fmt.Errorf("relativize -%s: %w", f.Name, err)
+					return
+				}
+				value = filepath.ToSlash(value)
+			}
+			if strings.ContainsAny(value, " \t\"") {
+				value = strconv.Quote(value)
+			}
+			args = append(args, "-"+f.Name, value)
+		}
+	})
+	if visitErr != nil {
+		return "", visitErr
+	}
+	return strings.Join(args, " "), nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// goldenFileFlags are the gen flags naming input files, copied into the
+// harness so that it does not depend on the project tree.
+var goldenFileFlags = map[string]bool{"static-keys": true, "aliases": true, "description-template": true, "owners": true}
+
+// goldenDroppedFlags are the gen flags left out of the harness: the output
+// directory, which the test chooses, the config file, whose options are passed
+// as flags, and flags writing outside of it or not writing at all.
+var goldenDroppedFlags = map[string]bool{
+	"O": true, "go-out": true, "go-package": true, "write-back": true, "notify": true, "tickets": true,
+	"print-directive": true, "dry-run": true, "check": true, "parallel": true, "modified": true, "config": true,
+}
+
+// goldenCommand implements the golden command, which writes a golden-file test
+// harness for the given gen flags: a copy of the proto files and other inputs,
+// the current language files as the starting point, the files gen writes from
+// them as the expected output, and a Go test running the installed i18n-gen
+// and comparing its output with them. Run after upgrading i18n-gen, the test
+// shows whether the upgrade changes the generated files.
+func goldenCommand(fs *flag.FlagSet) func(args []string) error {
+	dir := fs.String("dir", "i18ntest", "Directory of the harness, a Go package holding the test and its testdata")
+	goPackage := fs.String("package", "", "Name of the Go package of the test (default: base name of -dir)")
+
+	return func(args []string) error {
+		genFlags := flag.NewFlagSet("gen", flag.ContinueOnError)
+		generateCommand(genFlags)
+		if err := genFlags.Parse(args); err != nil {
+			return usageErrorf("invalid gen flags: %w", err)
+		}
+		if _, err := applyConfig(genFlags, genFlags.Lookup("config").Value.String()); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if *goPackage == "" {
+			*goPackage = goIdentifier(filepath.Base(*dir), false)
+		}
+		testdata := filepath.Join(*dir, "testdata")
+		if err := os.RemoveAll(testdata); err != nil {
+			return fmt.Errorf("failed to clear testdata: %w", err)
+		}
+
+		harnessArgs, err := goldenArgs(genFlags, *dir)
+		if err != nil {
+			return fmt.Errorf("failed to copy inputs: %w", err)
+		}
+		if err := copyTree(genFlags.Lookup("O").Value.String(), filepath.Join(testdata, "existing")); err != nil {
+			return fmt.Errorf("failed to copy language files: %w", err)
+		}
+		data, err := json.MarshalIndent(harnessArgs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to write gen flags: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(testdata, "args.json"), append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write gen flags: %w", err)
+		}
+		if err := writeGoldenTest(*dir, *goPackage); err != nil {
+			return fmt.Errorf("failed to write test: %w", err)
+		}
+
+		// Record the expected output by running the test in update mode
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate i18n-gen: %w", err)
+		}
+		cmd := exec.Command("go", "test", "-run", "TestGolden", "-update", ".")
+		cmd.Dir = *dir
+		cmd.Env = append(os.Environ(), "I18N_GEN="+self)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to record golden files: %w\n%s", err, bytes.TrimSpace(output))
+		}
+		log.Printf("Golden-file test written to %s; run go test there after upgrading i18n-gen.\n", *dir)
+		return nil
+	}
+}
+
+// goldenArgs returns the gen flags of the harness, relative to its directory:
+// the proto files and input files are copied to its testdata, include paths
+// point back to the project, and the dropped flags are left out.
+func goldenArgs(genFlags *flag.FlagSet, dir string) ([]string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		args     []string
+		visitErr error
+	)
+	patterns := []string(*genFlags.Lookup("P").Value.(*stringList))
+	if len(patterns) == 0 {
+		patterns = []string{defaultProtoPattern}
+	}
+	for _, pattern := range patterns {
+		// The files matching a pattern are copied below testdata/proto as they
+		// are below its root, which the pattern is rewritten relative to
+		root := extract.PatternRoot(pattern)
+		protoFiles, err := extract.FindFiles(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range protoFiles {
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return nil, err
+			}
+			if err := copyFile(file, filepath.Join(dir, "testdata", "proto", rel)); err != nil {
+				return nil, err
+			}
+		}
+		rel, err := filepath.Rel(root, pattern)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-P", filepath.ToSlash(filepath.Join("testdata", "proto", rel)))
+	}
+	args = append(args, "-modified", "")
+
+	genFlags.Visit(func(f *flag.Flag) {
+		if goldenDroppedFlags[f.Name] || f.Name == "P" || visitErr != nil {
+			return
+		}
+		values := []string{f.Value.String()}
+		if list, ok := f.Value.(*stringList); ok {
+			values = *list
+		}
+		for i, value := range values {
+			switch {
+			case goldenFileFlags[f.Name]:
+				target := filepath.Join("testdata", "files", fmt.Sprintf("%s-%d-%s", f.Name, i, filepath.Base(value)))
+				if visitErr = copyFile(value, filepath.Join(dir, target)); visitErr != nil {
+					return
+				}
+				value = filepath.ToSlash(target)
+			case pathFlags[f.Name]:
+				abs, err := filepath.Abs(value)
+				if err == nil {
+					value, err = filepath.Rel(absDir, abs)
+				}
+				if err != nil {
+					visitErr = err
+					return
+				}
+				value = filepath.ToSlash(value)
+			}
+			args = append(args, "-"+f.Name, value)
+		}
+	})
+	return args, visitErr
+}
+
+// writeGoldenTest writes the Go test of the harness.
+func writeGoldenTest(dir, pkg string) error {
+	var buffer bytes.Buffer
+	if err := goldenTestTemplate.Execute(&buffer, pkg); err != nil {
+		return fmt.Errorf("render test: %w", err)
+	}
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("format test: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, "golden_test.go"), source, 0644)
+}
+
+var goldenTestTemplate = template.Must(template.New("test").Parse(`// Code generated by i18n-gen golden. DO NOT EDIT.
+
+package {{.}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/golden with the output of i18n-gen")
+
+// TestGolden runs i18n-gen, or $I18N_GEN, with the flags of testdata/args.json
+// on the language files of testdata/existing and compares its output with
+// testdata/golden.
+func TestGolden(t *testing.T) {
+	bin := os.Getenv("I18N_GEN")
+	if bin == "" {
+		bin = "i18n-gen"
+	}
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		t.Skipf("%s not found: %v", bin, err)
+	}
+	data, err := os.ReadFile("testdata/args.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args []string
+	if err := json.Unmarshal(data, &args); err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	if err := copyDir("testdata/existing", out); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command(path, append([]string{"gen", "-O", out}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("i18n-gen failed: %v\n%s", err, output)
+	}
+
+	if *update {
+		if err := os.RemoveAll("testdata/golden"); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyDir(out, "testdata/golden"); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	got, err := readDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := readDir("testdata/golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range want {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s is no longer generated", name)
+		} else if !bytes.Equal(got[name], data) {
+			t.Errorf("%s differs from testdata/golden/%s:\n%s", name, name, got[name])
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("%s is generated but not in testdata/golden", name)
+		}
+	}
+}
+
+// readDir returns the contents of the files below a directory by their slash
+// separated path below it.
+func readDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)], err = os.ReadFile(path)
+		return err
+	})
+	return files, err
+}
+
+// copyDir copies the files below a directory to another one.
+func copyDir(from, to string) error {
+	files, err := readDir(from)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for name, data := range files {
+		target := filepath.Join(to, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+`))
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// goKey is a key exposed as a constant of the generated Go package.
+type goKey struct {
+	Name string
+	Key  string
+	File string
+	Line int
+}
+
+// writeGoPackage generates a Go package with typed keys and loaders for the TOML
+// language files: a static Catalog and a Reloader that swaps in updated
+// translations from a directory or URL without restart. The files are decoded
+// with github.com/BurntSushi/toml, so values written by hand or by other tools
+// in any TOML string form are read.
+func writeGoPackage(entries []extract.Entry, dir, pkg, library string) error {
+	if pkg == "" {
+		pkg = goIdentifier(filepath.Base(dir), false)
+	}
+
+	used := make(map[string]bool)
+	keys := make([]goKey, 0, len(entries))
+	for _, e := range entries {
+		name := goKeyName(e.Key, library)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s%d", goKeyName(e.Key, library), i)
+		}
+		used[name] = true
+		keys = append(keys, goKey{Name: name, Key: e.Key, File: filepath.ToSlash(e.File), Line: e.Line})
+	}
+
+	var buffer bytes.Buffer
+	if err := goPackageTemplate.Execute(&buffer, struct {
+		Package string
+		Keys    []goKey
+	}{pkg, keys}); err != nil {
+		return fmt.Errorf("render Go package: %w", err)
+	}
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("format Go package: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create Go package directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "i18n.go"), source, 0644); err != nil {
+		return fmt.Errorf("write Go package: %w", err)
+	}
+	return nil
+}
+
+// goIdentifier converts a key into a Go identifier, CamelCased when exported,
+// lower cased otherwise.
+func goIdentifier(key string, exported bool) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if !exported {
+		return strings.ToLower(strings.Join(parts, ""))
+	}
+	for i, part := range parts {
+		if strings.ToUpper(part) == part {
+			parts[i] = textutil.SnakeToCamel(part)
+			continue
+		}
+		// Keep the casing of mixed case words such as CreateUserRequest
+		words := strings.Split(part, "_")
+		for j, word := range words {
+			if word != "" {
+				words[j] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		parts[i] = strings.Join(words, "")
+	}
+	name := strings.Join(parts, "")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "Key" + name
+	}
+	return name
+}
+
+var goPackageTemplate = template.Must(template.New("package").Parse(`// Code generated by i18n-gen. DO NOT EDIT.
+
+// Package {{.Package}} provides typed access to the translations generated by i18n-gen.
+package {{.Package}}
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Key identifies a translatable message.
+type Key string
+
+// Keys extracted from the proto files.
+const (
+{{- range .Keys}}
+	// {{.Name}} is declared at {{.File}}:{{.Line}}.
+	{{.Name}} Key = {{printf "%q" .Key}}
+{{- end}}
+)
+
+// Translator returns the translation of a key in a language.
+type Translator interface {
+	Translate(lang string, key Key) string
+}
+
+// Catalog holds the translations of every language.
+type Catalog struct {
+	messages map[string]map[Key]string
+}
+
+// Translate returns the translation of key in lang, or the key itself when there is none.
+func (c *Catalog) Translate(lang string, key Key) string {
+	if c != nil {
+		if message := c.messages[lang][key]; message != "" {
+			return message
+		}
+	}
+	if OnMissing != nil {
+		OnMissing(lang, key)
+	}
+	return string(key)
+}
+
+// OnMissing, if set, is called whenever a key without a translation is looked up.
+var OnMissing func(lang string, key Key)
+
+// LogMissing returns an OnMissing logging each lookup as a warning with the
+// i18n_key and i18n_lang attributes, which i18n-gen fallbacks reads back from
+// the logs to report the translations to fix first.
+func LogMissing(logger *slog.Logger) func(lang string, key Key) {
+	return func(lang string, key Key) {
+		logger.Warn("i18n missing key", "i18n_key", string(key), "i18n_lang", lang)
+	}
+}
+
+// Load reads the <lang>.toml files of a directory.
+func Load(dir string) (*Catalog, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	catalog := &Catalog{messages: make(map[string]map[Key]string)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		catalog.messages[strings.TrimSuffix(filepath.Base(file), ".toml")] = messages
+	}
+	return catalog, nil
+}
+
+// messageFields are the fields of a go-i18n message, which tell its table apart
+// from a table nesting the messages of a dotted id.
+var messageFields = []string{"id", "description", "hash", "leftdelim", "rightdelim", "zero", "one", "two", "few", "many", "other"}
+
+// parse decodes a language file: tables of go-i18n messages, whose other form
+// is the translation, or id = "translation" pairs. The tables of dotted ids
+// such as [errors.not_found] nest, and their ids are joined back with dots.
+func parse(data []byte) (map[Key]string, error) {
+	var tables map[string]any
+	if err := toml.Unmarshal(data, &tables); err != nil {
+		return nil, err
+	}
+	messages := make(map[Key]string)
+	collect(messages, "", tables)
+	return messages, nil
+}
+
+// collect adds the messages of decoded tables below the id prefix.
+func collect(messages map[Key]string, prefix string, tables map[string]any) {
+	for name, value := range tables {
+		id := name
+		if prefix != "" {
+			id = prefix + "." + name
+		}
+		switch value := value.(type) {
+		case string:
+			messages[Key(id)] = value
+		case map[string]any:
+			if !slices.ContainsFunc(messageFields, func(field string) bool { _, ok := value[field]; return ok }) {
+				collect(messages, id, value)
+			} else if other, ok := value["other"].(string); ok {
+				messages[Key(id)] = other
+			}
+		}
+	}
+}
+
+// Reloader serves translations and atomically swaps in updated ones without restart.
+type Reloader struct {
+	current atomic.Pointer[Catalog]
+}
+
+// Translate returns the translation of key in lang from the latest catalog.
+func (r *Reloader) Translate(lang string, key Key) string {
+	return r.current.Load().Translate(lang, key)
+}
+
+// WatchDir loads the language files of dir and reloads them every interval when
+// one of them changed, until ctx is done. Reload errors are passed to onError, if
+// set, and keep the previous translations.
+func WatchDir(ctx context.Context, dir string, interval time.Duration, onError func(error)) (*Reloader, error) {
+	catalog, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reloader{}
+	r.current.Store(catalog)
+
+	last, _ := dirSignature(dir)
+	go r.poll(ctx, interval, onError, func() error {
+		signature, err := dirSignature(dir)
+		if err != nil || signature == last {
+			return err
+		}
+		catalog, err := Load(dir)
+		if err != nil {
+			return err
+		}
+		r.current.Store(catalog)
+		last = signature
+		return nil
+	})
+	return r, nil
+}
+
+// WatchURL loads <baseURL>/<lang>.toml for every language and reloads them every
+// interval when one of them changed, until ctx is done. Reload errors are passed
+// to onError, if set, and keep the previous translations.
+func WatchURL(ctx context.Context, baseURL string, langs []string, interval time.Duration, onError func(error)) (*Reloader, error) {
+	var last [sha256.Size]byte
+	load := func() (*Catalog, bool, error) {
+		catalog := &Catalog{messages: make(map[string]map[Key]string)}
+		hash := sha256.New()
+		for _, lang := range langs {
+			data, err := fetch(ctx, strings.TrimSuffix(baseURL, "/")+"/"+lang+".toml")
+			if err != nil {
+				return nil, false, err
+			}
+			hash.Write(data)
+			if catalog.messages[lang], err = parse(data); err != nil {
+				return nil, false, fmt.Errorf("%s: %w", lang, err)
+			}
+		}
+		var sum [sha256.Size]byte
+		copy(sum[:], hash.Sum(nil))
+		changed := sum != last
+		last = sum
+		return catalog, changed, nil
+	}
+
+	catalog, _, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r := &Reloader{}
+	r.current.Store(catalog)
+
+	go r.poll(ctx, interval, onError, func() error {
+		catalog, changed, err := load()
+		if err == nil && changed {
+			r.current.Store(catalog)
+		}
+		return err
+	})
+	return r, nil
+}
+
+func (r *Reloader) poll(ctx context.Context, interval time.Duration, onError func(error), reload func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// dirSignature summarizes the names, sizes and modification times of the
+// language files of dir.
+func dirSignature(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+var (
+	_ Translator = (*Catalog)(nil)
+	_ Translator = (*Reloader)(nil)
+)
+`))
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// grpcCodeNames maps the google.rpc.Code names to the constants of
+// google.golang.org/grpc/codes.
+var grpcCodeNames = map[string]string{
+	"OK":                  "OK",
+	"CANCELLED":           "Canceled",
+	"UNKNOWN":             "Unknown",
+	"INVALID_ARGUMENT":    "InvalidArgument",
+	"DEADLINE_EXCEEDED":   "DeadlineExceeded",
+	"NOT_FOUND":           "NotFound",
+	"ALREADY_EXISTS":      "AlreadyExists",
+	"PERMISSION_DENIED":   "PermissionDenied",
+	"RESOURCE_EXHAUSTED":  "ResourceExhausted",
+	"FAILED_PRECONDITION": "FailedPrecondition",
+	"ABORTED":             "Aborted",
+	"OUT_OF_RANGE":        "OutOfRange",
+	"UNIMPLEMENTED":       "Unimplemented",
+	"INTERNAL":            "Internal",
+	"UNAVAILABLE":         "Unavailable",
+	"DATA_LOSS":           "DataLoss",
+	"UNAUTHENTICATED":     "Unauthenticated",
+}
+
+// grpcCodesByNumber lists the google.rpc.Code names by number.
+var grpcCodesByNumber = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND",
+	"ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION",
+	"ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// goGRPCCode returns the Go expression of a gRPC code given by name or number.
+func goGRPCCode(code string) (string, bool) {
+	if name, ok := grpcCodeNames[code]; ok {
+		return "codes." + name, true
+	}
+	if n, err := strconv.Atoi(code); err == nil && n >= 0 {
+		return fmt.Sprintf("codes.Code(%d)", n), true
+	}
+	return "", false
+}
+
+// writeGRPCCodes adds grpc.go to the generated Go package, mapping the keys of
+// enum values with a grpc_code option to their code. The file is only written
+// when at least one value declares a code, so the package only depends on gRPC
+// when needed.
+func writeGRPCCodes(entries []extract.Entry, dir, pkg, library string, events *eventStream) error {
+	type mapping struct {
+		Name string
+		Code string
+	}
+	if pkg == "" {
+		pkg = goIdentifier(filepath.Base(dir), false)
+	}
+
+	var mappings []mapping
+	used := make(map[string]bool)
+	for _, e := range entries {
+		name := goKeyName(e.Key, library)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s%d", goKeyName(e.Key, library), i)
+		}
+		used[name] = true
+		if e.GRPCCode == "" {
+			continue
+		}
+		code, ok := goGRPCCode(e.GRPCCode)
+		if !ok {
+			events.warnf("%s:%d: unknown gRPC code %s\n", e.File, e.Line, e.GRPCCode)
+			continue
+		}
+		mappings = append(mappings, mapping{Name: name, Code: code})
+	}
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	if err := grpcTemplate.Execute(&buffer, struct {
+		Package  string
+		Mappings []mapping
+	}{pkg, mappings}); err != nil {
+		return fmt.Errorf("render gRPC codes: %w", err)
+	}
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("format gRPC codes: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "grpc.go"), source, 0644); err != nil {
+		return fmt.Errorf("write gRPC codes: %w", err)
+	}
+	return nil
+}
+
+var grpcTemplate = template.Must(template.New("grpc").Parse(`// Code generated by i18n-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCCodes maps error keys to the gRPC status code declared with grpc_code.
+var GRPCCodes = map[Key]codes.Code{
+{{- range .Mappings}}
+	{{.Name}}: {{.Code}},
+{{- end}}
+}
+
+// Status returns a status with the code of key and its translation in lang as
+// message. Keys without a declared code map to codes.Unknown.
+func Status(t Translator, lang string, key Key) *status.Status {
+	code, ok := GRPCCodes[key]
+	if !ok {
+		code = codes.Unknown
+	}
+	return status.New(code, t.Translate(lang, key))
+}
+
+// StatusFromEnum returns the status of a generated error enum value, whose
+// String method returns the name of the value and thus its key.
+func StatusFromEnum(t Translator, lang string, value fmt.Stringer) *status.Status {
+	return Status(t, lang, Key(value.String()))
+}
+`))
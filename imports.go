@@ -0,0 +1,131 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/emicklei/proto"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// importedEnums extracts the values of the enums declared in the files the
+// proto files import, transitively, looked up below the include paths as
+// protoc does, that a field of the proto files references, such as a shared
+// error reason of a well-known proto. Files that fail to parse are returned
+// with their error, like those of extract.ParseFiles.
+func importedEnums(protoFiles, includePaths []string, opts extract.Options) []extract.File {
+	if len(includePaths) == 0 {
+		includePaths = []string{"."}
+	}
+	own := make(map[string]bool, len(protoFiles))
+	for _, file := range protoFiles {
+		own[filepath.Clean(file)] = true
+	}
+
+	var imported []string
+	var references []typeReference
+	seen := make(map[string]bool)
+	queue := append([]string{}, protoFiles...)
+	for len(queue) > 0 {
+		file := filepath.Clean(queue[0])
+		queue = queue[1:]
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		if !own[file] {
+			imported = append(imported, file)
+		}
+
+		definition, err := parseProtoDefinition(file)
+		if err != nil {
+			continue // reported when the file is extracted
+		}
+		pkg := extract.PackageName(definition)
+		for _, elem := range definition.Elements {
+			switch elem := elem.(type) {
+			case *proto.Import:
+				if resolved := resolveImport(elem.Filename, includePaths); resolved != "" {
+					queue = append(queue, resolved)
+				}
+			case *proto.Message:
+				if own[file] {
+					references = fieldReferences(references, pkg, elem.Name, elem.Elements)
+				}
+			}
+		}
+	}
+
+	var parsed []extract.File
+	for _, p := range extract.ParseFiles(imported, opts) {
+		if p.Err != nil {
+			parsed = append(parsed, p)
+			continue
+		}
+		var entries []extract.Entry
+		for _, e := range p.Entries {
+			if e.Kind == extract.KindEnum && referenced(references, qualify(e.Package, e.Path)) {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) > 0 {
+			parsed = append(parsed, extract.File{Path: p.Path, Entries: entries})
+		}
+	}
+	return parsed
+}
+
+// typeReference is the type of a field, as written, with the scope it is
+// resolved in: the qualified name of the message declaring the field.
+type typeReference struct {
+	name, scope string
+}
+
+// fieldReferences appends the types of the fields of a message, and of its
+// nested messages, to references.
+func fieldReferences(references []typeReference, pkg, message string, elements []proto.Visitee) []typeReference {
+	scope := qualify(pkg, message)
+	for _, elem := range elements {
+		switch elem := elem.(type) {
+		case *proto.NormalField:
+			references = append(references, typeReference{elem.Type, scope})
+		case *proto.MapField:
+			references = append(references, typeReference{elem.Type, scope})
+		case *proto.Oneof:
+			for _, oneofElem := range elem.Elements {
+				if field, ok := oneofElem.(*proto.OneOfField); ok {
+					references = append(references, typeReference{field.Type, scope})
+				}
+			}
+		case *proto.Message:
+			references = fieldReferences(references, pkg, message+"."+elem.Name, elem.Elements)
+		}
+	}
+	return references
+}
+
+// referenced reports whether one of the references resolves to the enum of
+// the given fully qualified name: a name starting with a dot is fully
+// qualified, and the others are looked up in the scope of the field and then
+// its enclosing scopes, as protoc does.
+func referenced(references []typeReference, enum string) bool {
+	for _, r := range references {
+		if name, ok := strings.CutPrefix(r.name, "."); ok {
+			if name == enum {
+				return true
+			}
+			continue
+		}
+		for scope := r.scope; ; {
+			if qualify(scope, r.name) == enum {
+				return true
+			}
+			if scope == "" {
+				break
+			}
+			scope = scope[:max(strings.LastIndex(scope, "."), 0)]
+		}
+	}
+	return false
+}
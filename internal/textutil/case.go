@@ -0,0 +1,34 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// SnakeToCamel converts snake_case to CamelCase.
+func SnakeToCamel(input string) string {
+	words := strings.Split(input, "_")
+	caser := cases.Title(language.English) // Create a caser for proper title casing
+	for i := range words {
+		words[i] = caser.String(strings.ToLower(words[i]))
+	}
+	return strings.Join(words, "")
+}
+
+// CamelToSnake converts CamelCase to snake_case.
+func CamelToSnake(input string) string {
+	var b strings.Builder
+	for i, r := range input {
+		if unicode.IsUpper(r) {
+			if i > 0 && !unicode.IsUpper(rune(input[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,280 @@
+package textutil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Values are kept internally as they appear between the quotes of a TOML basic
+// string (and of a proto string literal), i.e. with backslash escapes. Writers
+// never print a value, key or comment as is: they pass it through the escaper
+// of their format below, so that no text can end a string, table or element
+// early, and escapes only one of the formats knows do not leak into another.
+
+// simpleEscapes maps the character after a backslash to the byte it stands for.
+var simpleEscapes = map[byte]byte{
+	'\\': '\\', '"': '"', '\'': '\'', '/': '/', '?': '?',
+	'n': '\n', 't': '\t', 'r': '\r', 'b': '\b', 'f': '\f', 'a': '\a', 'v': '\v',
+}
+
+// Unescape turns an internally stored value into plain text. It understands
+// the escapes of TOML, JSON, YAML, PO and proto string literals, including
+// \uXXXX (and JSON surrogate pairs), \UXXXXXXXX, \xHH and octal escapes; a
+// backslash that starts none of them is kept.
+func Unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			i++
+			continue
+		}
+		s := value[i+1:]
+		if c, ok := simpleEscapes[s[0]]; ok {
+			b.WriteByte(c)
+			i += 2
+			continue
+		}
+		switch {
+		case s[0] == 'u' || s[0] == 'U':
+			size := 4
+			if s[0] == 'U' {
+				size = 8
+			}
+			r, n := hexDigits(s[1:], size)
+			if n != size {
+				break
+			}
+			i += 2 + size
+			if utf16.IsSurrogate(rune(r)) && strings.HasPrefix(value[i:], `\u`) {
+				if low, n := hexDigits(value[i+2:], 4); n == 4 {
+					if pair := utf16.DecodeRune(rune(r), rune(low)); pair != utf8.RuneError {
+						b.WriteRune(pair)
+						i += 6
+						continue
+					}
+				}
+			}
+			b.WriteRune(rune(r))
+			continue
+		case s[0] == 'x' || s[0] == 'X':
+			if v, n := hexDigits(s[1:], 2); n > 0 {
+				b.WriteByte(byte(v))
+				i += 2 + n
+				continue
+			}
+		case s[0] >= '0' && s[0] <= '7':
+			v, n := 0, 0
+			for n < 3 && n < len(s) && s[n] >= '0' && s[n] <= '7' && v*8+int(s[n]-'0') <= 0xFF {
+				v = v*8 + int(s[n]-'0')
+				n++
+			}
+			b.WriteByte(byte(v))
+			i += 1 + n
+			continue
+		}
+		b.WriteByte('\\')
+		i++
+	}
+	return b.String()
+}
+
+// hexDigits parses up to max leading hex digits of s, returning their value and
+// how many there were.
+func hexDigits(s string, max int) (uint32, int) {
+	var v uint32
+	n := 0
+	for ; n < max && n < len(s); n++ {
+		c := s[n]
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c -= 'a' - 10
+		case c >= 'A' && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return v, n
+		}
+		v = v<<4 | uint32(c)
+	}
+	return v, n
+}
+
+// Escape turns plain text into the internally stored form of a value,
+// which is also valid between the double quotes of TOML, JSON, YAML and proto
+// strings. Control characters and the characters YAML and JavaScript take as
+// line breaks are written as \u escapes.
+func Escape(text string) string {
+	return EscapeText(text, func(r rune) string {
+		if r < 0x20 || r == 0x7F || r == 0x85 || r == 0x2028 || r == 0x2029 {
+			return fmt.Sprintf(`\u%04X`, r)
+		}
+		return ""
+	})
+}
+
+// Normalize rewrites an internally stored value, which may come from a
+// proto literal or any of the language files, with the escapes of Escape
+// only, for writing between the double quotes of TOML, JSON, YAML or proto.
+func Normalize(value string) string {
+	return Escape(Unescape(value))
+}
+
+// EscapePO turns plain text into the contents of a PO string. Gettext only
+// knows C escapes, so control characters are written in octal.
+func EscapePO(text string) string {
+	return EscapeText(text, func(r rune) string {
+		if r < 0x20 || r == 0x7F {
+			return fmt.Sprintf(`\%03o`, r)
+		}
+		return ""
+	})
+}
+
+// EscapeText backslash escapes quotes, backslashes and the common control
+// characters of text, and the runes numeric returns a replacement for. Bytes
+// that are not valid UTF-8 are kept.
+func EscapeText(text string, numeric func(r rune) string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteByte(text[i])
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		default:
+			if escaped := numeric(r); escaped != "" {
+				b.WriteString(escaped)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// EscapeAndroid turns plain text into the contents of a <string> of an Android
+// resource file: backslashes, quotes, apostrophes, line breaks and tabs are
+// backslash escaped, as are @ and ? at the start, which would make the text a
+// resource reference; spaces Android would collapse or trim are written as
+// \u0020, and the XML special characters as entities.
+func EscapeAndroid(text string) string {
+	var b strings.Builder
+	for i, r := range text {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\'' || r == '"':
+			b.WriteString(`\` + string(r))
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case (r == '@' || r == '?') && i == 0:
+			b.WriteString(`\` + string(r))
+		case r == ' ' && (i == 0 || i == len(text)-1 || text[i-1] == ' '):
+			b.WriteString(`\u0020`)
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// UnescapeAndroid turns the text of a <string> of an Android resource file into
+// plain text. As Android does, whitespace is collapsed unless the text is
+// enclosed in double quotes, and unescaped double quotes are dropped.
+func UnescapeAndroid(text string) string {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	} else {
+		text = strings.Join(strings.FieldsFunc(text, isXMLSpace), " ")
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '"' {
+			continue
+		}
+		if text[i] == '\\' && i+1 < len(text) {
+			if text[i+1] != '@' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(text[i+1])
+			i++
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return Unescape(b.String())
+}
+
+// isXMLSpace reports whether r is whitespace Android collapses: only the
+// whitespace of XML, not the Unicode line and paragraph separators.
+func isXMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// EscapeStrings turns plain text into the contents of a string of an Apple
+// .strings file, which knows C escapes and \UXXXX for other characters.
+func EscapeStrings(text string) string {
+	return EscapeText(text, func(r rune) string {
+		if r < 0x20 || r == 0x7F {
+			return fmt.Sprintf(`\U%04X`, r)
+		}
+		return ""
+	})
+}
+
+// UnescapeStrings turns the contents of a string of an Apple .strings file into
+// plain text. Unlike elsewhere, \U is followed by four hex digits.
+func UnescapeStrings(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			if _, n := hexDigits(text[i+2:], 4); text[i+1] == 'U' && n == 4 {
+				b.WriteString(`\u`)
+			} else {
+				b.WriteString(text[i : i+2])
+			}
+			i++
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return Unescape(b.String())
+}
+
+// EscapeXML escapes text for use in XML character data and attribute values.
+func EscapeXML(text string) string {
+	var buffer bytes.Buffer
+	xml.EscapeText(&buffer, []byte(text))
+	return buffer.String()
+}
@@ -0,0 +1,94 @@
+package textutil
+
+import (
+	"encoding/xml"
+	"testing"
+	"unicode/utf8"
+)
+
+// escapers are the escapers of the formats with the unescapers reading their
+// output back. The XML formats are read back through an XML decoder first, as
+// the loaders of their files do.
+var escapers = []struct {
+	name     string
+	escape   func(string) string
+	unescape func(string) string
+	valid    func(string) bool // texts the format can hold
+}{
+	{"toml", Escape, Unescape, nil},
+	{"po", EscapePO, Unescape, nil},
+	{"strings", EscapeStrings, UnescapeStrings, nil},
+	{"android", EscapeAndroid, func(s string) string { return UnescapeAndroid(xmlText(s)) }, xmlChars},
+	{"xml", EscapeXML, xmlText, xmlChars},
+}
+
+// xmlText returns the character data of escaped XML text.
+func xmlText(s string) string {
+	var v struct {
+		Text string `xml:",chardata"`
+	}
+	if err := xml.Unmarshal([]byte("<s>"+s+"</s>"), &v); err != nil {
+		return "error: " + err.Error()
+	}
+	return v.Text
+}
+
+// xmlChars reports whether text is valid UTF-8 made of characters XML allows,
+// without carriage returns, which XML parsers turn into line feeds.
+func xmlChars(text string) bool {
+	if !utf8.ValidString(text) {
+		return false
+	}
+	for _, r := range text {
+		if r == '\r' || r < 0x20 && r != '\n' && r != '\t' || r >= 0xFFFE && r <= 0xFFFF || r >= 0xD800 && r <= 0xDFFF {
+			return false
+		}
+	}
+	return true
+}
+
+var escapeSeeds = []string{
+	"", "plain text", `quote " and backslash \`, "line\nbreak\ttab\rreturn",
+	"{{.Name}} %s %d", "\x00\x1f\x7f", "\u0085  ", "emoji 😀 中文",
+	"  leading and trailing  ", "@string/ref", "?attr", "<tag> & 'apos'",
+	`A \x41 \101 \n`, "\xff\xfe invalid", "\\\\\\", `"quoted"`,
+}
+
+// FuzzEscape checks that the text a format escapes reads back unchanged.
+func FuzzEscape(f *testing.F) {
+	for _, seed := range escapeSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, e := range escapers {
+			if e.valid != nil && !e.valid(text) {
+				continue
+			}
+			if got := e.unescape(e.escape(text)); got != text {
+				t.Errorf("%s: %q escaped as %q reads back as %q", e.name, text, e.escape(text), got)
+			}
+		}
+	})
+}
+
+// FuzzUnescape checks that any stored value, once unescaped, is escaped and
+// read back to the same text, so values survive being rewritten by a writer.
+func FuzzUnescape(f *testing.F) {
+	for _, seed := range escapeSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, e := range escapers {
+			text := e.unescape(value)
+			if e.valid != nil && !e.valid(text) {
+				continue
+			}
+			if got := e.unescape(e.escape(text)); got != text {
+				t.Errorf("%s: %q unescaped as %q reads back as %q", e.name, value, text, got)
+			}
+		}
+		if text := Unescape(value); Unescape(Normalize(value)) != text {
+			t.Errorf("normalized %q reads back as %q, want %q", value, Unescape(Normalize(value)), text)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package textutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// ReadFile reads a proto or language file as UTF-8, returning the name of the
+// encoding it was transcoded from as Decode does. Files some Windows editors
+// save as UTF-16 or GBK are transcoded instead of being read as mojibake, for
+// the caller to warn about; a UTF-8 byte order mark is dropped. Errors of
+// os.ReadFile are returned unwrapped.
+func ReadFile(filePath string) ([]byte, string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return Decode(data)
+}
+
+// OpenFile opens a language file for reading as UTF-8 like ReadFile, but as a
+// stream for files in UTF-8 or UTF-16, so that large files are not held in
+// memory. Other encodings can only be told from the whole file, which is then
+// read like ReadFile does.
+func OpenFile(filePath string) (io.ReadCloser, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	reader := bufio.NewReaderSize(file, 64*1024)
+	head, err := reader.Peek(1024)
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, "", err
+	}
+	stream := func(r io.Reader, name string) (io.ReadCloser, string, error) {
+		return struct {
+			io.Reader
+			io.Closer
+		}{r, file}, name, nil
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		reader.Discard(3)
+		return stream(reader, "")
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return stream(transform.NewReader(reader, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()), "UTF-16")
+	}
+	if endian, ok := utf16WithoutBOM(head); ok {
+		return stream(transform.NewReader(reader, unicode.UTF16(endian, unicode.IgnoreBOM).NewDecoder()), "UTF-16")
+	}
+
+	valid, err := validUTF8(reader)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	if valid {
+		return stream(bufio.NewReaderSize(file, 64*1024), "")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+	text, name, err := Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return io.NopCloser(bytes.NewReader(text)), name, nil
+}
+
+// validUTF8 reports whether everything read from r is valid UTF-8, reading it
+// in chunks.
+func validUTF8(r io.Reader) (bool, error) {
+	buf := make([]byte, 64*1024)
+	carry := 0 // bytes of a character cut at the end of the previous chunk
+	for {
+		n, err := r.Read(buf[carry:])
+		chunk := buf[:carry+n]
+		end := len(chunk)
+		for i := 1; i < utf8.UTFMax && i <= end; i++ {
+			if utf8.RuneStart(chunk[end-i]) {
+				if !utf8.FullRune(chunk[end-i:]) {
+					end -= i
+				}
+				break
+			}
+		}
+		if !utf8.Valid(chunk[:end]) {
+			return false, nil
+		}
+		carry = copy(buf, chunk[end:])
+		if err == io.EOF {
+			return carry == 0, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// Decode converts data to UTF-8, returning the name of the encoding it was
+// converted from, or an empty name when it already was UTF-8. UTF-16 is
+// recognized by its byte order mark or, without one, by the NUL bytes of
+// ASCII characters; data that is not valid UTF-8 but valid GBK is taken as GBK.
+func Decode(data []byte) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], "", nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		text, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		return text, "UTF-16", err
+	}
+	if endian, ok := utf16WithoutBOM(data); ok {
+		text, err := unicode.UTF16(endian, unicode.IgnoreBOM).NewDecoder().Bytes(data)
+		return text, "UTF-16", err
+	}
+	if utf8.Valid(data) {
+		return data, "", nil
+	}
+	if text, ok := decodeStrict(simplifiedchinese.GBK, data); ok {
+		return text, "GBK", nil
+	}
+	return data, "", nil
+}
+
+// utf16WithoutBOM guesses whether data is UTF-16 without a byte order mark
+// from the share of NUL bytes at even or odd offsets, which ASCII text in
+// UTF-16 has for every other byte.
+func utf16WithoutBOM(data []byte) (unicode.Endianness, bool) {
+	n := min(len(data), 1024) &^ 1
+	if n < 4 {
+		return unicode.LittleEndian, false
+	}
+	var even, odd int
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	switch pairs := n / 2; {
+	case odd*10 >= pairs*4 && even == 0:
+		return unicode.LittleEndian, true
+	case even*10 >= pairs*4 && odd == 0:
+		return unicode.BigEndian, true
+	}
+	return unicode.LittleEndian, false
+}
+
+// decodeStrict decodes data, reporting whether every byte sequence was valid
+// in the encoding.
+func decodeStrict(enc encoding.Encoding, data []byte) ([]byte, bool) {
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil || !utf8.Valid(text) || bytes.ContainsRune(text, utf8.RuneError) {
+		return nil, false
+	}
+	return text, true
+}
+
+// NewXMLDecoder returns a decoder of XML read with ReadFile, which is UTF-8
+// whatever encoding its declaration names.
+func NewXMLDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+// UnmarshalXML decodes XML read with ReadFile.
+func UnmarshalXML(data []byte, v any) error {
+	return NewXMLDecoder(data).Decode(v)
+}
@@ -0,0 +1,543 @@
+// Package toml reads and writes the TOML of language and config files rather
+// than with a TOML library, as the files only hold tables of strings. The
+// reader implements that part of TOML completely: bare, quoted and dotted
+// keys, and basic, literal and multi-line strings with every escape, so files
+// edited by hand or by other tools are read as any TOML parser would read them.
+package toml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+)
+
+// Item is a table header or a key/value pair of a TOML file.
+type Item struct {
+	Header   bool     // whether this is a [table] header
+	Key      string   // the table name, or the key of the pair; dotted keys are joined with dots
+	Value    string   // plain text of the string value of a pair
+	Comments []string // text of the comment lines directly above
+	Leading  []string // text of all comment lines since the previous item
+	Line     int      // line of the header or key
+	Column   int      // column of the header or key, in characters
+}
+
+// bareKeyRe matches the table names written without quotes. Dotted names
+// stay bare, as go-i18n reads them as nested message ids.
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// bareKeyPartRe matches a bare key or the bare part of a dotted key.
+var bareKeyPartRe = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// escapeRe matches the escapes allowed in basic strings.
+var escapeRe = regexp.MustCompile(`^\\([btnfr"\\]|x[0-9A-Fa-f]{2}|u[0-9A-Fa-f]{4}|U[0-9A-Fa-f]{8})`)
+
+// Key returns the name of the table of a key, quoted unless it only contains
+// characters allowed in bare keys.
+func Key(key string) string {
+	if bareKeyRe.MatchString(key) {
+		return key
+	}
+	return String(key)
+}
+
+// String returns plain text as a TOML basic string.
+func String(text string) string {
+	return "\"" + textutil.Escape(text) + "\""
+}
+
+// Parse reads the table headers and key/value pairs of a TOML file whose
+// values are all strings, and the comment lines after the last of them, as a
+// Scanner does.
+func Parse(filePath string, data []byte) (items []Item, trailing []string, err error) {
+	scanner := NewScanner(filePath, bytes.NewReader(data))
+	for scanner.Scan() {
+		items = append(items, scanner.Item())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return items, scanner.Trailing(), nil
+}
+
+// Scanner reads the table headers and key/value pairs of a TOML file whose
+// values are all strings one at a time, holding no more than the lines of the
+// current item in memory, so that large language files can be validated and
+// loaded as they are read. Anything else, such as arrays of tables, numbers or
+// text after a value, and tables or keys defined twice, is reported as an
+// error at its line and column.
+type Scanner struct {
+	p        parser
+	item     Item
+	err      error
+	comments []string       // comment lines directly above the next item
+	leading  []string       // comment lines since the previous item
+	tables   map[string]int // line of every table header
+	keys     map[string]int // line of every key of the current table
+}
+
+// NewScanner returns a Scanner reading a TOML file from r, named filePath in
+// errors.
+func NewScanner(filePath string, r io.Reader) *Scanner {
+	return &Scanner{
+		p:      parser{filePath: filePath, r: bufio.NewReader(r), line: 1},
+		tables: make(map[string]int),
+		keys:   make(map[string]int),
+	}
+}
+
+// Scan advances to the next header or pair, returning false at the end of the
+// file or on the first error.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	p := &s.p
+	for {
+		p.compact()
+		if p.i == len(p.s) && !p.fill() {
+			s.err = p.readErr
+			return false
+		}
+		p.skipSpace()
+		switch {
+		case p.i == len(p.s):
+		case p.s[p.i] == '\n':
+			s.comments = nil
+			p.newline()
+		case p.s[p.i] == '#':
+			end := strings.IndexByte(p.s[p.i:], '\n')
+			if end < 0 {
+				end = len(p.s) - p.i
+			}
+			s.comments = append(s.comments, strings.TrimSpace(p.s[p.i+1:p.i+end]))
+			s.leading = append(s.leading, strings.TrimSpace(p.s[p.i+1:p.i+end]))
+			if p.i += end; p.i < len(p.s) {
+				p.newline()
+			}
+		case strings.HasPrefix(p.s[p.i:], "[["):
+			s.err = p.errorf("arrays of tables are not supported")
+			return false
+		case p.s[p.i] == '[':
+			s.err = s.header()
+			return s.err == nil
+		default:
+			s.err = s.pair()
+			return s.err == nil
+		}
+	}
+}
+
+// header reads a table header.
+func (s *Scanner) header() error {
+	p := &s.p
+	item := Item{Header: true, Comments: s.comments, Leading: s.leading, Line: p.line, Column: p.column()}
+	p.i++
+	key, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.skipSpace(); p.i == len(p.s) || p.s[p.i] != ']' {
+		return p.errorf("expected ] after the table name")
+	}
+	p.i++
+	if err := p.endOfLine(); err != nil {
+		return err
+	}
+	if line, ok := s.tables[key]; ok {
+		return fmt.Errorf("%s:%d:%d: table [%s] already defined at line %d", p.filePath, item.Line, item.Column, key, line)
+	}
+	s.tables[key] = item.Line
+	clear(s.keys)
+	item.Key = key
+	s.item, s.comments, s.leading = item, nil, nil
+	return nil
+}
+
+// pair reads a key/value pair.
+func (s *Scanner) pair() error {
+	p := &s.p
+	item := Item{Comments: s.comments, Leading: s.leading, Line: p.line, Column: p.column()}
+	key, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.skipSpace(); p.i == len(p.s) || p.s[p.i] != '=' {
+		return p.errorf("expected = after %s", key)
+	}
+	p.i++
+	p.skipSpace()
+	value, err := p.str()
+	if err != nil {
+		return err
+	}
+	if err := p.endOfLine(); err != nil {
+		return err
+	}
+	if line, ok := s.keys[key]; ok {
+		return fmt.Errorf("%s:%d:%d: key %s already defined at line %d", p.filePath, item.Line, item.Column, key, line)
+	}
+	s.keys[key] = item.Line
+	item.Key, item.Value = key, value
+	s.item, s.comments, s.leading = item, nil, nil
+	return nil
+}
+
+// Item returns the header or pair read by the last call to Scan.
+func (s *Scanner) Item() Item {
+	return s.item
+}
+
+// Err returns the first error met by Scan.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Trailing returns the comment lines after the last item, once Scan returned
+// false.
+func (s *Scanner) Trailing() []string {
+	return s.leading
+}
+
+// KeyValue is a key of a TOML file set to a string, an array of strings, or a
+// bare boolean or number.
+type KeyValue struct {
+	Key    string
+	Values []string // the strings of an array, or the single value
+	Line   int
+}
+
+// ParseKeyValues reads a TOML file of keys set to strings, arrays of strings,
+// booleans or numbers, without tables, as config files are.
+func ParseKeyValues(filePath string, data []byte) ([]KeyValue, error) {
+	p := parser{filePath: filePath, s: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+	var pairs []KeyValue
+	for p.i < len(p.s) {
+		p.skipSpace()
+		switch {
+		case p.i == len(p.s):
+		case p.s[p.i] == '\n' || p.s[p.i] == '#':
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+		case p.s[p.i] == '[':
+			return nil, p.errorf("tables are not supported in the config file")
+		default:
+			pair := KeyValue{Line: p.line}
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if p.skipSpace(); p.i == len(p.s) || p.s[p.i] != '=' {
+				return nil, p.errorf("expected = after %s", key)
+			}
+			p.i++
+			p.skipSpace()
+			pair.Key = key
+			if pair.Values, err = p.value(); err != nil {
+				return nil, err
+			}
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, nil
+}
+
+// parser is the position of a Scanner or ParseKeyValues in a file. A Scanner
+// reads the file a line at a time from r into s, which ParseKeyValues holds
+// whole.
+type parser struct {
+	filePath  string
+	r         *bufio.Reader
+	readErr   error
+	s         string
+	i         int
+	line      int
+	lineStart int // offset in s of the current line
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s:%d:%d: %s", p.filePath, p.line, p.column(), fmt.Sprintf(format, args...))
+}
+
+// errorAt returns an error at an offset of s after the current position,
+// counting the lines in between.
+func (p *parser) errorAt(offset int, format string, args ...any) error {
+	line, lineStart := p.line, p.lineStart
+	if between := p.s[p.i:offset]; strings.Contains(between, "\n") {
+		line += strings.Count(between, "\n")
+		lineStart = p.i + strings.LastIndexByte(between, '\n') + 1
+	}
+	return fmt.Errorf("%s:%d:%d: %s", p.filePath, line, utf8.RuneCountInString(p.s[lineStart:offset])+1, fmt.Sprintf(format, args...))
+}
+
+// column returns the column of the current position, counted in characters.
+func (p *parser) column() int {
+	return utf8.RuneCountInString(p.s[p.lineStart:p.i]) + 1
+}
+
+// fill reads the next line into s, reporting whether there was one.
+func (p *parser) fill() bool {
+	if p.r == nil || p.readErr != nil {
+		return false
+	}
+	line, err := p.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		p.readErr = err
+		return false
+	}
+	if line == "" {
+		return false
+	}
+	if strings.HasSuffix(line, "\r\n") {
+		line = line[:len(line)-2] + "\n"
+	}
+	p.s += line
+	return true
+}
+
+// compact drops the text read before the current line from s.
+func (p *parser) compact() {
+	if p.r == nil || p.lineStart == 0 {
+		return
+	}
+	p.s, p.i, p.lineStart = p.s[p.lineStart:], p.i-p.lineStart, 0
+}
+
+// skipSpace skips spaces and tabs.
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// newline skips a line break.
+func (p *parser) newline() {
+	p.i++
+	p.line++
+	p.lineStart = p.i
+}
+
+// endOfLine skips the rest of a line after a header or value, which may only
+// hold a comment.
+func (p *parser) endOfLine() error {
+	p.skipSpace()
+	switch {
+	case p.i == len(p.s):
+	case p.s[p.i] == '#':
+		if end := strings.IndexByte(p.s[p.i:], '\n'); end >= 0 {
+			p.i += end
+		} else {
+			p.i = len(p.s)
+		}
+	case p.s[p.i] != '\n':
+		return p.errorf("unexpected text after the value: %s", strings.SplitN(p.s[p.i:], "\n", 2)[0])
+	}
+	if p.i < len(p.s) {
+		p.newline()
+	}
+	return nil
+}
+
+// key reads a bare, quoted or dotted key.
+func (p *parser) key() (string, error) {
+	var parts []string
+	for {
+		p.skipSpace()
+		if p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\'') {
+			if strings.HasPrefix(p.s[p.i:], `"""`) || strings.HasPrefix(p.s[p.i:], "'''") {
+				return "", p.errorf("keys cannot be multi-line strings")
+			}
+			part, err := p.str()
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		} else if part := bareKeyPartRe.FindString(p.s[p.i:]); part != "" {
+			parts = append(parts, part)
+			p.i += len(part)
+		} else {
+			return "", p.errorf("expected a key")
+		}
+		if p.skipSpace(); p.i == len(p.s) || p.s[p.i] != '.' {
+			return strings.Join(parts, "."), nil
+		}
+		p.i++
+	}
+}
+
+// str reads a basic, literal or multi-line string and returns its plain text.
+func (p *parser) str() (string, error) {
+	rest := p.s[p.i:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multiline(`"""`)
+	case strings.HasPrefix(rest, "'''"):
+		return p.multiline("'''")
+	case strings.HasPrefix(rest, `"`):
+		end := 1
+		for end < len(rest) && rest[end] != '"' && rest[end] != '\n' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) || rest[end] != '"' {
+			return "", p.errorf("unterminated string")
+		}
+		if err := p.checkEscapes(p.i+1, rest[1:end], false); err != nil {
+			return "", err
+		}
+		p.i += end + 1
+		return textutil.Unescape(rest[1:end]), nil
+	case strings.HasPrefix(rest, "'"):
+		end := strings.IndexAny(rest[1:], "'\n")
+		if end < 0 || rest[1+end] != '\'' {
+			return "", p.errorf("unterminated string")
+		}
+		p.i += end + 2
+		return rest[1 : 1+end], nil
+	case rest == "" || rest[0] == '\n' || rest[0] == '#':
+		return "", p.errorf("missing value")
+	}
+	return "", p.errorf("unsupported value, only strings are allowed: %s", strings.SplitN(rest, "\n", 2)[0])
+}
+
+// multiline reads a multi-line string with the given delimiter. A line break
+// right after the opening delimiter is dropped, and in basic strings a
+// backslash at the end of a line drops the line break and the whitespace
+// after it.
+func (p *parser) multiline(delim string) (string, error) {
+	start := p.i + 3
+	end := start
+	for {
+		if end+5 >= len(p.s) {
+			p.fill() // the delimiter and up to two quotes may be on the next line
+		}
+		if end >= len(p.s) {
+			return "", p.errorf("unterminated multi-line string")
+		}
+		if delim[0] == '"' && p.s[end] == '\\' {
+			end += 2
+			continue
+		}
+		if strings.HasPrefix(p.s[end:], delim) {
+			// Up to two quotes right before the closing delimiter are content
+			for extra := 0; extra < 2 && end+3 < len(p.s) && p.s[end+3] == delim[0]; extra++ {
+				end++
+			}
+			break
+		}
+		end++
+	}
+	content := p.s[start:end]
+	if delim[0] == '"' {
+		if err := p.checkEscapes(start, content, true); err != nil {
+			return "", err
+		}
+	}
+	p.i = end + 3
+	p.line += strings.Count(content, "\n")
+	if last := strings.LastIndexByte(content, '\n'); last >= 0 {
+		p.lineStart = start + last + 1
+	}
+
+	content = strings.TrimPrefix(content, "\n")
+	if delim[0] == '\'' {
+		return content, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(content); i++ {
+		if content[i] != '\\' || i+1 == len(content) {
+			b.WriteByte(content[i])
+			continue
+		}
+		if trimmed := strings.TrimLeft(content[i+1:], " \t"); strings.HasPrefix(trimmed, "\n") {
+			i = len(content) - len(strings.TrimLeft(trimmed, " \t\n")) - 1
+			continue
+		}
+		b.WriteString(content[i : i+2])
+		i++
+	}
+	return textutil.Unescape(b.String()), nil
+}
+
+// checkEscapes rejects the escapes TOML does not know in the contents of a
+// basic string at an offset of s after the current position. In multi-line
+// strings a backslash may also end a line.
+func (p *parser) checkEscapes(at int, content string, multiline bool) error {
+	for i := strings.IndexByte(content, '\\'); i >= 0; {
+		m := escapeRe.FindString(content[i:])
+		if m == "" && multiline && strings.HasPrefix(strings.TrimLeft(content[i+1:], " \t"), "\n") {
+			m = "\\"
+		}
+		if m == "" {
+			return p.errorAt(at+i, "invalid escape %q", content[i:min(i+2, len(content))])
+		}
+		next := strings.IndexByte(content[i+len(m):], '\\')
+		if next < 0 {
+			break
+		}
+		i += len(m) + next
+	}
+	return nil
+}
+
+// value reads a string, an array of strings, or a bare boolean or number.
+func (p *parser) value() ([]string, error) {
+	if p.i < len(p.s) && p.s[p.i] == '[' {
+		p.i++
+		var values []string
+		for {
+			// Arrays may span lines and hold comments
+			for p.i < len(p.s) && strings.ContainsRune(" \t\n#", rune(p.s[p.i])) {
+				if p.s[p.i] == '#' {
+					for p.i < len(p.s) && p.s[p.i] != '\n' {
+						p.i++
+					}
+					continue
+				}
+				if p.s[p.i] == '\n' {
+					p.newline()
+					continue
+				}
+				p.i++
+			}
+			if p.i < len(p.s) && p.s[p.i] == ']' {
+				p.i++
+				return values, nil
+			}
+			value, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if p.skipSpace(); p.i < len(p.s) && p.s[p.i] == ',' {
+				p.i++
+			}
+		}
+	}
+	if p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\'') {
+		value, err := p.str()
+		return []string{value}, err
+	}
+	end := strings.IndexAny(p.s[p.i:], " \t\n#")
+	if end < 0 {
+		end = len(p.s) - p.i
+	}
+	if end == 0 {
+		return nil, p.errorf("missing value")
+	}
+	value := p.s[p.i : p.i+end]
+	p.i += end
+	return []string{value}, nil
+}
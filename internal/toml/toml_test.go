@@ -0,0 +1,180 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The cases follow the examples of the strings and keys sections of the TOML
+// 1.0 specification, which every TOML parser reads the same way.
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Item
+	}{
+		{
+			name: "bare, quoted and dotted keys",
+			data: "[ERR_A]\nother = \"a\"\n[\"key with space\"]\nother = \"b\"\n[errors.not_found]\n\"quoted.key\" = \"c\"\nsite . \"google.com\" = \"d\"\n['literal key']\nother = 'e'\n",
+			want: []Item{
+				{Header: true, Key: "ERR_A", Line: 1, Column: 1},
+				{Key: "other", Value: "a", Line: 2, Column: 1},
+				{Header: true, Key: "key with space", Line: 3, Column: 1},
+				{Key: "other", Value: "b", Line: 4, Column: 1},
+				{Header: true, Key: "errors.not_found", Line: 5, Column: 1},
+				{Key: "quoted.key", Value: "c", Line: 6, Column: 1},
+				{Key: "site.google.com", Value: "d", Line: 7, Column: 1},
+				{Header: true, Key: "literal key", Line: 8, Column: 1},
+				{Key: "other", Value: "e", Line: 9, Column: 1},
+			},
+		},
+		{
+			name: "basic string escapes",
+			data: `a = "I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF."` + "\n" + `b = "\U0001F600 \\ \b\f\r"` + "\n",
+			want: []Item{
+				{Key: "a", Value: "I'm a string. \"You can quote me\". Name\tJosé\nLocation\tSF.", Line: 1, Column: 1},
+				{Key: "b", Value: "😀 \\ \b\f\r", Line: 2, Column: 1},
+			},
+		},
+		{
+			name: "literal strings",
+			data: `winpath = 'C:\Users\nodejs\templates'` + "\n" + `regex = '<\i\c*\s*>'` + "\n",
+			want: []Item{
+				{Key: "winpath", Value: `C:\Users\nodejs\templates`, Line: 1, Column: 1},
+				{Key: "regex", Value: `<\i\c*\s*>`, Line: 2, Column: 1},
+			},
+		},
+		{
+			name: "multi-line basic strings",
+			data: "str1 = \"\"\"\nRoses are red\nViolets are blue\"\"\"\nstr2 = \"\"\"\nThe quick brown \\\n\n\n  fox jumps over \\\n    the lazy dog.\"\"\"\nstr3 = \"\"\"Here are two quotation marks: \"\". Simple enough.\"\"\"\n",
+			want: []Item{
+				{Key: "str1", Value: "Roses are red\nViolets are blue", Line: 1, Column: 1},
+				{Key: "str2", Value: "The quick brown fox jumps over the lazy dog.", Line: 4, Column: 1},
+				{Key: "str3", Value: `Here are two quotation marks: "". Simple enough.`, Line: 10, Column: 1},
+			},
+		},
+		{
+			name: "multi-line literal strings",
+			data: "re = '''I [dw]on't need \\d{2} apples'''\nlines = '''\nThe first newline is\ntrimmed in raw strings.\n'''\n",
+			want: []Item{
+				{Key: "re", Value: `I [dw]on't need \d{2} apples`, Line: 1, Column: 1},
+				{Key: "lines", Value: "The first newline is\ntrimmed in raw strings.\n", Line: 2, Column: 1},
+			},
+		},
+		{
+			name: "comments",
+			data: "# file comment\n\n# about A\n# more\n[A] # trailing\nother = \"a\" # trailing\n# left over\n",
+			want: []Item{
+				{Header: true, Key: "A", Comments: []string{"about A", "more"}, Leading: []string{"file comment", "about A", "more"}, Line: 5, Column: 1},
+				{Key: "other", Value: "a", Line: 6, Column: 1},
+			},
+		},
+		{
+			name: "CRLF line endings",
+			data: "[A]\r\nother = \"a\"\r\n",
+			want: []Item{
+				{Header: true, Key: "A", Line: 1, Column: 1},
+				{Key: "other", Value: "a", Line: 2, Column: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, _, err := Parse("test.toml", []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, tt.want) {
+				t.Errorf("Parse =\n%#v\nwant\n%#v", items, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrailing(t *testing.T) {
+	_, trailing, err := Parse("test.toml", []byte("[A]\nother = \"a\"\n\n# stale: B\n# other = \"b\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"stale: B", `other = "b"`}; !reflect.DeepEqual(trailing, want) {
+		t.Errorf("trailing = %q, want %q", trailing, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"duplicate key", "[A]\nother = \"a\"\nother = \"b\"\n", "test.toml:3:1: key other already defined at line 2"},
+		{"duplicate table", "[A]\n[B]\n[A]\n", "test.toml:3:1: table [A] already defined at line 1"},
+		{"array of tables", "[[A]]\n", "arrays of tables are not supported"},
+		{"invalid escape", `a = "\q"` + "\n", "test.toml:1:"},
+		{"unterminated string", "a = \"abc\n", "test.toml:1:"},
+		{"text after value", "a = \"a\" b\n", "test.toml:1:"},
+		{"missing equals", "a \"a\"\n", "expected = after a"},
+		{"number value", "a = 1\n", "test.toml:1:"},
+		{"unterminated multi-line string", "a = \"\"\"abc\n", "test.toml:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse("test.toml", []byte(tt.data))
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded", tt.data)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) = %v, want an error containing %q", tt.data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	data := "# config\nformat = \"po\"\nlang = [\"en\", 'zh',\n  \"fr\", ]\nforce = true\nparallel = 4\n"
+	pairs, err := ParseKeyValues("i18n-gen.toml", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyValue{
+		{Key: "format", Values: []string{"po"}, Line: 2},
+		{Key: "lang", Values: []string{"en", "zh", "fr"}, Line: 3},
+		{Key: "force", Values: []string{"true"}, Line: 5},
+		{Key: "parallel", Values: []string{"4"}, Line: 6},
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("ParseKeyValues =\n%#v\nwant\n%#v", pairs, want)
+	}
+	if _, err := ParseKeyValues("i18n-gen.toml", []byte("[table]\n")); err == nil {
+		t.Error("ParseKeyValues accepted a table")
+	}
+}
+
+// FuzzString checks that keys and strings written with Key and String are read
+// back as they were.
+func FuzzString(f *testing.F) {
+	for _, seed := range []string{"", "plain", "with space", "dotted.key", `quote " backslash \`, "line\nbreak\ttab\r", "\x00\x7f\u0085\u2028", "中文 😀", "'''", `"""`} {
+		f.Add(seed, seed)
+	}
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if key == "" || !validText(key) || !validText(value) {
+			return
+		}
+		data := "[" + Key(key) + "]\nother = " + String(value) + "\n"
+		items, _, err := Parse("fuzz.toml", []byte(data))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", data, err)
+		}
+		if len(items) != 2 || items[0].Key != key || items[1].Value != value {
+			t.Fatalf("Parse(%q) = %#v, want key %q and value %q", data, items, key, value)
+		}
+	})
+}
+
+// validText reports whether text is valid UTF-8 a TOML string can hold: the
+// escapes of String only cover Unicode scalar values.
+func validText(text string) bool {
+	return strings.ToValidUTF8(text, "") == text
+}
@@ -0,0 +1,59 @@
+// Package yaml reads the lines of the YAML subset of language and config
+// files: "key: value" lines of plain, single- or double-quoted scalars.
+package yaml
+
+import (
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+)
+
+// SplitLine splits a "key: value" line into its unquoted key and raw value.
+func SplitLine(line string) (key, rest string, ok bool) {
+	if strings.HasPrefix(line, "\"") || strings.HasPrefix(line, "'") {
+		end := ClosingQuote(line)
+		if end < 0 || !strings.HasPrefix(line[end+1:], ":") {
+			return "", "", false
+		}
+		return textutil.Unescape(Scalar(line[:end+1])), strings.TrimSpace(line[end+2:]), true
+	}
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+// Scalar converts a raw YAML scalar into the escaped form of values.
+func Scalar(raw string) string {
+	switch {
+	case strings.HasPrefix(raw, "\""):
+		if end := ClosingQuote(raw); end > 0 {
+			return raw[1:end]
+		}
+	case strings.HasPrefix(raw, "'"):
+		if end := ClosingQuote(raw); end > 0 {
+			return textutil.Escape(strings.ReplaceAll(raw[1:end], "''", "'"))
+		}
+	}
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = raw[:i]
+	}
+	return textutil.Escape(strings.TrimSpace(raw))
+}
+
+// ClosingQuote returns the index of the quote closing the string that starts s.
+func ClosingQuote(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
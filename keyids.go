@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// keyIDsMember is the member of the locks file holding the stable numeric ids
+// of the keys, next to the signed-off values of every language.
+const keyIDsMember = "@ids"
+
+// keyIDs holds the stable numeric id of every key ever generated, for
+// analytics and event pipelines referencing keys compactly.
+type keyIDs map[string]int
+
+// loadKeyIDs reads the ids of a locks file, returning none when it does not
+// exist.
+func loadKeyIDs(filePath string) (keyIDs, error) {
+	ids := make(keyIDs)
+	members, err := loadLockMembers(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := members[keyIDsMember]; ok {
+		if err := json.Unmarshal(raw, &ids); err != nil {
+			return nil, fmt.Errorf("parse %s: %s: %w", filepath.Base(filePath), keyIDsMember, err)
+		}
+	}
+	return ids, nil
+}
+
+// writeKeyIDs writes the ids to a locks file, keeping its locks.
+func writeKeyIDs(ids keyIDs, filePath string) error {
+	locks, err := loadLocks(filePath)
+	if err != nil {
+		return err
+	}
+	return writeLockFile(locks, ids, filePath)
+}
+
+// assign sets the id of every entry, giving the keys without one the numbers
+// following the highest id, in order. A key renamed from another, as given by
+// renames from the old key to the new one, takes over the id of the old key.
+// The ids of keys no longer extracted are kept, so that numbers are never
+// reused. It returns the number of keys given a new id.
+func (ids keyIDs) assign(entries []extract.Entry, renames map[string]string) int {
+	next := 0
+	for _, id := range ids {
+		next = max(next, id)
+	}
+	for from, to := range renames {
+		if id, ok := ids[from]; ok {
+			if _, taken := ids[to]; !taken {
+				ids[to] = id
+			}
+		}
+	}
+
+	added := 0
+	for i, e := range entries {
+		id, ok := ids[e.Key]
+		if !ok {
+			next++
+			id = next
+			ids[e.Key] = id
+			added++
+		}
+		entries[i].ID = id
+	}
+	return added
+}
+
+// loadLockMembers reads the members of a locks file, returning none when it
+// does not exist.
+func loadLockMembers(filePath string) (map[string]json.RawMessage, error) {
+	members := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return members, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(filePath), err)
+	}
+	return members, nil
+}
+
+// writeLockFile writes the locks and ids as JSON, with members sorted.
+func writeLockFile(locks lockStore, ids keyIDs, filePath string) error {
+	members := make(map[string]any, len(locks)+1)
+	for lang, values := range locks {
+		members[lang] = values
+	}
+	if len(ids) > 0 {
+		members[keyIDsMember] = ids
+	}
+	data, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, append(data, '\n'), 0644)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// keyRules are the naming conventions generated keys must follow. Zero values
+// disable a rule.
+type keyRules struct {
+	MaxLen   int            // maximum key length in characters
+	Pattern  *regexp.Regexp // pattern every key must match
+	MinDepth int            // minimum number of namespace segments
+	// Separator separates the namespace segments, a dot if empty.
+	Separator string
+}
+
+// checkKeyRules returns a message for every entry whose key breaks a rule,
+// pointing at the proto declaration it was extracted from.
+func checkKeyRules(entries []extract.Entry, rules keyRules) []string {
+	var violations []string
+	for _, e := range entries {
+		location := fmt.Sprintf("%s:%d", e.File, e.Line)
+		if rules.MaxLen > 0 && utf8.RuneCountInString(e.Key) > rules.MaxLen {
+			violations = append(violations, fmt.Sprintf("%s: key %q is longer than %d characters", location, e.Key, rules.MaxLen))
+		}
+		if rules.Pattern != nil && !rules.Pattern.MatchString(e.Key) {
+			violations = append(violations, fmt.Sprintf("%s: key %q does not match %s", location, e.Key, rules.Pattern))
+		}
+		separator := cmp.Or(rules.Separator, ".")
+		if depth := len(strings.Split(e.Key, separator)); rules.MinDepth > 0 && depth < rules.MinDepth {
+			violations = append(violations, fmt.Sprintf("%s: key %q has %d namespace segment(s), at least %d required", location, e.Key, depth, rules.MinDepth))
+		}
+	}
+	return violations
+}
+
+// checkKeyCollisions returns a warning for every group of keys that only differ
+// by case or by separators (USER_NOT_FOUND, UserNotFound, user.not-found), which
+// collapse into one key in case-insensitive formats and stores.
+func checkKeyCollisions(entries []extract.Entry) []string {
+	var order []string
+	groups := make(map[string][]extract.Entry)
+	for _, e := range entries {
+		normalized := normalizeKey(e.Key)
+		if _, ok := groups[normalized]; !ok {
+			order = append(order, normalized)
+		}
+		groups[normalized] = append(groups[normalized], e)
+	}
+
+	var warnings []string
+	for _, normalized := range order {
+		group := groups[normalized]
+		if len(group) < 2 {
+			continue
+		}
+		keys := make([]string, len(group))
+		for i, e := range group {
+			keys[i] = fmt.Sprintf("%q (%s:%d)", e.Key, e.File, e.Line)
+		}
+		warnings = append(warnings, fmt.Sprintf("keys only differ by case or separators: %s", strings.Join(keys, ", ")))
+	}
+	return warnings
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// keySeparators are the separators the namespaces of keys may be joined with.
+var keySeparators = []string{".", ":", "/"}
+
+// keySanitizer replaces the characters matching a pattern, such as spaces in
+// cel ids, which a target format does not allow in keys.
+type keySanitizer struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// parseKeySanitizers parses sanitization rules of the form
+// <regexp>=<replacement>, split at the last =, e.g. \s+=_ or [^\w.]=-.
+func parseKeySanitizers(rules []string) ([]keySanitizer, error) {
+	sanitizers := make([]keySanitizer, 0, len(rules))
+	for _, rule := range rules {
+		i := strings.LastIndex(rule, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%q: expected <regexp>=<replacement>", rule)
+		}
+		pattern, err := regexp.Compile(rule[:i])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", rule, err)
+		}
+		sanitizers = append(sanitizers, keySanitizer{pattern: pattern, replacement: rule[i+1:]})
+	}
+	return sanitizers, nil
+}
+
+// mapKeys joins the namespaces of the keys of the entries with the separator
+// instead of dots and applies the sanitizers, in order. It returns the keys the
+// mapping changed, by new key, and a message for every pair of different keys
+// mapped to the same one, which would otherwise share a translation.
+func mapKeys(entries []extract.Entry, separator string, sanitizers []keySanitizer) (map[string]string, []string) {
+	mapKey := func(key string) string {
+		key = strings.ReplaceAll(key, ".", separator)
+		for _, s := range sanitizers {
+			key = s.pattern.ReplaceAllString(key, s.replacement)
+		}
+		return key
+	}
+
+	original := make(map[string]string)
+	first := make(map[string]extract.Entry) // new key -> first entry mapped to it
+	var collisions []string
+	for i, e := range entries {
+		key := mapKey(e.Key)
+		if prev, ok := first[key]; ok && prev.Key != e.Key {
+			collisions = append(collisions, fmt.Sprintf("%s:%d: %s and %s (%s:%d) both map to %s", e.File, e.Line, e.Key, prev.Key, prev.File, prev.Line, key))
+			continue
+		} else if !ok {
+			first[key] = e
+		}
+		if key != e.Key {
+			original[key] = e.Key
+		}
+		entries[i].Key = key
+		if e.Alias != "" {
+			entries[i].Alias = mapKey(e.Alias)
+		}
+	}
+	return original, collisions
+}
+
+// validKeySeparator reports whether keys may be written with the separator.
+func validKeySeparator(separator string) bool {
+	return slices.Contains(keySeparators, separator)
+}
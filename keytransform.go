@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// keyTransform is one step of rewriting the extracted keys.
+type keyTransform struct {
+	apply func(key string) string
+}
+
+// parseKeyTransforms parses key transformation expressions:
+//
+//	s/<regexp>/<replacement>/   replace every match, $1 etc. naming groups;
+//	                            | # , ; ! @ % + = ~ may delimit instead of /
+//	trim-prefix:<text>          remove a prefix
+//	trim-suffix:<text>          remove a suffix
+//	prefix:<text>               prepend text
+//	suffix:<text>               append text
+//	lower, upper                change the case
+//	camel                       snake_case to CamelCase
+//	snake                       CamelCase to snake_case
+func parseKeyTransforms(exprs []string) ([]keyTransform, error) {
+	var transforms []keyTransform
+	for _, expr := range exprs {
+		var t keyTransform
+		name, arg, hasArg := strings.Cut(expr, ":")
+		switch {
+		case len(expr) > 1 && expr[0] == 's' && strings.ContainsRune("/|#,;!@%+=~", rune(expr[1])):
+			parts := strings.Split(expr[2:], expr[1:2])
+			if len(parts) != 3 || parts[2] != "" {
+				return nil, fmt.Errorf("%q: expected s/<regexp>/<replacement>/", expr)
+			}
+			re, err := regexp.Compile(parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", expr, err)
+			}
+			replacement := parts[1]
+			t.apply = func(key string) string { return re.ReplaceAllString(key, replacement) }
+		case hasArg && name == "trim-prefix":
+			t.apply = func(key string) string { return strings.TrimPrefix(key, arg) }
+		case hasArg && name == "trim-suffix":
+			t.apply = func(key string) string { return strings.TrimSuffix(key, arg) }
+		case hasArg && name == "prefix":
+			t.apply = func(key string) string { return arg + key }
+		case hasArg && name == "suffix":
+			t.apply = func(key string) string { return key + arg }
+		case expr == "lower":
+			t.apply = strings.ToLower
+		case expr == "upper":
+			t.apply = strings.ToUpper
+		case expr == "camel":
+			t.apply = textutil.SnakeToCamel
+		case expr == "snake":
+			t.apply = textutil.CamelToSnake
+		default:
+			return nil, fmt.Errorf("%q: unknown key transformation", expr)
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+// applyKeyTransforms rewrites the key of every entry with the transformations,
+// in order. Keys that end up empty keep their original value, with a warning.
+func applyKeyTransforms(entries []extract.Entry, transforms []keyTransform) []string {
+	var warnings []string
+	for i, e := range entries {
+		key := e.Key
+		for _, t := range transforms {
+			key = t.apply(key)
+		}
+		if key == "" {
+			warnings = append(warnings, fmt.Sprintf("%s:%d: key transformations leave %s empty; keeping it", e.File, e.Line, e.Key))
+			continue
+		}
+		entries[i].Key = key
+	}
+	return warnings
+}
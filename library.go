@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// libraryAuto names the library after the module path of the nearest go.mod.
+const libraryAuto = "auto"
+
+// goModuleName returns the module path declared by the go.mod of dir or of the
+// closest directory above it.
+func goModuleName(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "module" {
+					return strings.Trim(fields[1], `"`), nil
+				}
+			}
+			return "", fmt.Errorf("%s: no module directive", filepath.Join(dir, "go.mod"))
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no go.mod found")
+		}
+		dir = parent
+	}
+}
+
+// namespaceLibrary prefixes the keys of the entries, and the keys they are
+// aliases of, with the name of the library and the key separator, so that the
+// keys of shared libraries do not collide in the applications aggregating them.
+func namespaceLibrary(entries []extract.Entry, library, separator string) {
+	for i := range entries {
+		entries[i].Key = library + separator + entries[i].Key
+		if entries[i].Alias != "" {
+			entries[i].Alias = library + separator + entries[i].Alias
+		}
+	}
+}
+
+// goKeyName returns the name of the Go constant of a key, leaving out the
+// namespace of the library and its separator, which the package of the
+// constants already names.
+func goKeyName(key, library string) string {
+	if library != "" && len(key) > len(library) && strings.HasPrefix(key, library) {
+		key = key[len(library)+1:]
+	}
+	return goIdentifier(key, true)
+}
+
+// mergeSource is a library whose language files are merged.
+type mergeSource struct {
+	name    string
+	keys    []string                     // keys in catalog order, then sorted
+	values  map[string]map[string]string // language -> key -> value
+	catalog map[string]CatalogEntry
+	entries []CatalogEntry
+}
+
+// mergeCommand implements the merge command, which combines the language files
+// of shared libraries, generated with -library, into one set of language files
+// for the application, failing on keys defined by more than one of them or by
+// the application itself.
+func mergeCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/libraries/", "Path to the directory the merged language files are written to")
+	appDir := fs.String("app", "", "Directory of the application's own language files, whose keys libraries must not redefine (optional)")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	catalogName := fs.String("catalog", "catalog.json", "Name of the catalog written by gen -catalog in each library directory, for the library name and key descriptions; written merged to the output directory (empty to disable)")
+	encodingSpec := fs.String("encoding", "utf-8", "Encoding of the merged language files (see gen -encoding)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: i18n-gen merge [flags] <library language directory> ...\n")
+		fs.PrintDefaults()
+	}
+
+	return func(args []string) error {
+		if len(args) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		outFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		encoding, err := emit.ParseEncoding(*encodingSpec, outFormat)
+		if err != nil {
+			return usageErrorf("unsupported encoding for format %s: %w", *format, err)
+		}
+		langs := splitLanguages(*languages)
+
+		sources := make([]*mergeSource, 0, len(args))
+		for _, dir := range args {
+			source, err := loadMergeSource(dir, outFormat, langs, *catalogName)
+			if err != nil {
+				return fmt.Errorf("failed to load library %s: %w", dir, err)
+			}
+			sources = append(sources, source)
+		}
+
+		// Every key must come from a single library, and not from the application
+		owners := make(map[string]string)
+		if *appDir != "" {
+			for _, lang := range langs {
+				values, err := loadLanguage(outFormat, outFormat.Path(*appDir, lang), nil)
+				if err != nil {
+					return fmt.Errorf("failed to load application %s: %w", lang, err)
+				}
+				for key := range values {
+					owners[key] = "the application"
+				}
+			}
+		}
+		var collisions []string
+		for _, source := range sources {
+			for _, key := range source.keys {
+				if owner, ok := owners[key]; ok {
+					collisions = append(collisions, fmt.Sprintf("%s: defined by %s and %s", key, owner, source.name))
+					continue
+				}
+				owners[key] = source.name
+			}
+		}
+		if len(collisions) > 0 {
+			for _, collision := range collisions {
+				log.Println(collision)
+			}
+			log.Printf("%d key(s) collide; namespace the libraries with gen -library\n", len(collisions))
+			return errFailed
+		}
+
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		for _, lang := range langs {
+			var entries []extract.Entry
+			for _, source := range sources {
+				for _, key := range source.keys {
+					value, ok := source.values[lang][key]
+					if !ok {
+						continue // left to the fallback language at runtime
+					}
+					e := source.catalog[key]
+					entries = append(entries, extract.Entry{Key: key, Name: key, Kind: e.Kind, Comment: e.Comment, Package: e.Package, Fallback: value})
+				}
+			}
+			langPath := outFormat.Path(*outputDir, lang)
+			if err := outFormat.Write(entries, lang, langPath, true, encoding, nil); err != nil {
+				return fmt.Errorf("failed to write %s: %w", filepath.Base(langPath), err)
+			}
+			log.Printf("%s merged from %d librar(ies).", filepath.Base(langPath), len(sources))
+		}
+
+		if *catalogName != "" {
+			merged := Catalog{Entries: []CatalogEntry{}}
+			for _, source := range sources {
+				merged.Entries = append(merged.Entries, source.entries...)
+			}
+			data, err := json.MarshalIndent(merged, "", "  ")
+			if err == nil {
+				err = os.WriteFile(filepath.Join(*outputDir, *catalogName), append(data, '\n'), 0644)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write catalog: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// loadMergeSource reads the language files of a library and, if present, its
+// catalog, which names the library and orders and describes its keys.
+func loadMergeSource(dir string, outFormat emit.Format, langs []string, catalogName string) (*mergeSource, error) {
+	source := &mergeSource{name: dir, values: make(map[string]map[string]string), catalog: make(map[string]CatalogEntry)}
+	if catalogName != "" {
+		data, err := os.ReadFile(filepath.Join(dir, catalogName))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var catalog Catalog
+			if err := json.Unmarshal(data, &catalog); err != nil {
+				return nil, fmt.Errorf("%s: %w", catalogName, err)
+			}
+			if catalog.Library != "" {
+				source.name = fmt.Sprintf("%s (%s)", catalog.Library, dir)
+			}
+			source.entries = catalog.Entries
+			for _, e := range catalog.Entries {
+				source.catalog[e.Key] = e
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range source.entries {
+		if !seen[e.Key] {
+			seen[e.Key] = true
+			source.keys = append(source.keys, e.Key)
+		}
+	}
+	var extra []string
+	for _, lang := range langs {
+		values, err := loadLanguage(outFormat, outFormat.Path(dir, lang), nil)
+		if err != nil {
+			return nil, err
+		}
+		source.values[lang] = values
+		for key := range values {
+			if !seen[key] {
+				seen[key] = true
+				extra = append(extra, key)
+			}
+		}
+	}
+	sort.Strings(extra)
+	source.keys = append(source.keys, extra...)
+	return source, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// lockStore holds the signed-off value of every locked key by language and
+// key. Regeneration and imports must leave these values untouched.
+type lockStore map[string]map[string]string
+
+// loadLocks reads a locks file, returning an empty store when it does not
+// exist. The ids of the keys it also holds are read by loadKeyIDs.
+func loadLocks(filePath string) (lockStore, error) {
+	locks := make(lockStore)
+	members, err := loadLockMembers(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for lang, raw := range members {
+		if lang == keyIDsMember {
+			continue
+		}
+		var values map[string]string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("parse %s: %s: %w", filepath.Base(filePath), lang, err)
+		}
+		locks[lang] = values
+	}
+	return locks, nil
+}
+
+// writeLocks writes the store as JSON, with languages and keys sorted, keeping
+// the ids of the keys in the file.
+func writeLocks(locks lockStore, filePath string) error {
+	ids, err := loadKeyIDs(filePath)
+	if err != nil {
+		return err
+	}
+	return writeLockFile(locks, ids, filePath)
+}
+
+// checkLocks reports the locked keys of a language whose value in the existing
+// file differs from the signed-off one, or that would be pruned because they
+// are no longer extracted.
+func checkLocks(locks lockStore, lang string, existing map[string]string, entries []extract.Entry) []string {
+	extracted := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		extracted[e.Key] = true
+	}
+	keys := make([]string, 0, len(locks[lang]))
+	for key := range locks[lang] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conflicts []string
+	for _, key := range keys {
+		switch value, ok := existing[key]; {
+		case !extracted[key]:
+			conflicts = append(conflicts, fmt.Sprintf("locked key %s is no longer extracted and would be removed from %s", key, lang))
+		case !ok || value != locks[lang][key]:
+			conflicts = append(conflicts, fmt.Sprintf("%s translation of locked key %s differs from its signed-off value %q", lang, key, locks[lang][key]))
+		}
+	}
+	return conflicts
+}
+
+// lockCommand implements the lock command, which records the current values of
+// the given keys as signed off, or releases them with -unlock.
+func lockCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	locksName := fs.String("locks", "locks.json", "Name of the locks file in the output directory")
+	unlock := fs.Bool("unlock", false, "Release the locks of the given keys instead")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: i18n-gen lock [flags] <key> ...\n")
+		fs.PrintDefaults()
+	}
+
+	return func(keys []string) error {
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		if len(keys) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+
+		locksPath := filepath.Join(*outputDir, *locksName)
+		locks, err := loadLocks(locksPath)
+		if err != nil {
+			return fmt.Errorf("failed to load locks: %w", err)
+		}
+
+		for _, lang := range splitLanguages(*languages) {
+			if *unlock {
+				for _, key := range keys {
+					delete(locks[lang], key)
+				}
+				if len(locks[lang]) == 0 {
+					delete(locks, lang)
+				}
+				continue
+			}
+
+			langPath := inFormat.Path(*outputDir, lang)
+			translations, err := loadLanguage(inFormat, langPath, nil)
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", filepath.Base(langPath), err)
+			}
+			for _, key := range keys {
+				value, ok := translations[key]
+				if !ok {
+					log.Printf("Warning: %s has no key %s\n", filepath.Base(langPath), key)
+					continue
+				}
+				if locks[lang] == nil {
+					locks[lang] = make(map[string]string)
+				}
+				locks[lang][key] = value
+			}
+		}
+
+		if err := writeLocks(locks, locksPath); err != nil {
+			return fmt.Errorf("failed to write locks: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Manifest records how every generated key maps back to its proto source, and
+// the hash of every language file as generated, by path relative to the
+// output directory, to tell manual edits apart.
+type Manifest struct {
+	Entries []ManifestEntry   `json:"entries"`
+	Files   map[string]string `json:"files,omitempty"`
+}
+
+// ManifestEntry describes the origin of a single generated key.
+type ManifestEntry struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Path string `json:"path,omitempty"`
+	// Group is the group set with an i18n-group comment tag.
+	Group string `json:"group,omitempty"`
+	// Code is the number of an enum value.
+	Code *int `json:"code,omitempty"`
+	// GRPCCode is the gRPC status code of an enum value.
+	GRPCCode string `json:"grpc_code,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	// OriginalKey is the key as extracted, when -key-separator or
+	// -key-sanitize changed it.
+	OriginalKey string `json:"original_key,omitempty"`
+}
+
+// writeManifest writes the key mapping of the provided entries to a JSON file,
+// with the original keys of the keys that were mapped, by key, and the hashes
+// of the language files.
+func writeManifest(entries []extract.Entry, originalKeys, files map[string]string, filePath string) error {
+	manifest := Manifest{Entries: make([]ManifestEntry, 0, len(entries)), Files: files}
+	for _, e := range entries {
+		entry := ManifestEntry{
+			Key:      e.Key,
+			Name:     e.Name,
+			Kind:     e.Kind,
+			Path:     e.Path,
+			Group:    e.Group,
+			GRPCCode: e.GRPCCode,
+			File:     e.File,
+			Line:     e.Line,
+
+			OriginalKey: originalKeys[e.Key],
+		}
+		if e.Kind == extract.KindEnum {
+			code := e.Number
+			entry.Code = &code
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
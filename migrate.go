@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/toml"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// migrateCommand implements the migrate command, which converts hand-written flat
+// TOML or JSON language files into the generator's format and key naming. Legacy
+// keys are mapped with the lookup table and rules given, or else matched to the
+// keys extracted from the protos ignoring case and separators; every key that
+// had to change is recorded in a rename map.
+func migrateCommand(fs *flag.FlagSet) func(args []string) error {
+	var mapRules, protoPatterns stringList
+	fs.Var(&protoPatterns, "P", "Proto files: a glob, ** matching any number of directories, e.g. api/**/*.proto, a directory or a file; may be repeated (default "+defaultProtoPattern+")")
+	outputDir := fs.String("O", "./i18n/", "Path to the output directory")
+	enumPrefix := fs.String("prefix", "", "Only process enums with this prefix (optional)")
+	enumSuffix := fs.String("suffix", "", "Only process enums with this suffix (optional)")
+	format := fs.String("format", "toml", "Output format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	renameMapName := fs.String("rename-map", "rename-map.json", "Name of the rename map written to the output directory")
+	locksName := fs.String("locks", "locks.json", "Name of the locks file in the output directory; locked values are kept and differing legacy values reported")
+	mapName := fs.String("map", "", "JSON file mapping legacy keys to the generated keys, such as a rename map of an earlier migration (optional)")
+	fs.Var(&mapRules, "map-rule", "Rewrite the legacy keys missing from -map into generated keys, e.g. s/^err\\.(.*)$/ERR_$1/, trim-prefix:errors., upper or snake; may be repeated, applied in order")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: i18n-gen migrate [flags] <lang>.toml|<lang>.json ...\n")
+		fs.PrintDefaults()
+	}
+
+	return func(args []string) error {
+		outFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown output format: %s", *format)
+		}
+		if len(args) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+
+		mapping, err := loadKeyMapping(*mapName, mapRules)
+		if err != nil {
+			return fmt.Errorf("failed to load key mapping: %w", err)
+		}
+
+		protoFiles, err := findProtoFiles(protoPatterns)
+		if err != nil {
+			return fmt.Errorf("failed to find proto files: %w", err)
+		}
+		var extracted []extract.Entry
+		failed := false
+		for _, protoFile := range protoFiles {
+			entries, err := extract.FromFile(protoFile, extract.Options{EnumPrefix: *enumPrefix, EnumSuffix: *enumSuffix})
+			if err != nil {
+				log.Printf("Failed to parse proto file %s: %v\n", protoFile, err)
+				failed = true
+				continue
+			}
+			extracted = append(extracted, entries...)
+		}
+		extracted = extract.Unique(extracted)
+
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		locks := make(lockStore)
+		if *locksName != "" {
+			if locks, err = loadLocks(filepath.Join(*outputDir, *locksName)); err != nil {
+				return fmt.Errorf("failed to load locks: %w", err)
+			}
+		}
+
+		renames := make(map[string]string)
+		for _, legacyPath := range args {
+			lang := strings.TrimSuffix(filepath.Base(legacyPath), filepath.Ext(legacyPath))
+			legacy, err := loadLegacyBundle(legacyPath)
+			if err != nil {
+				log.Printf("Failed to load %s: %v\n", legacyPath, err)
+				failed = true
+				continue
+			}
+
+			entries, fileRenames := migrateEntries(extracted, legacy, mapping)
+			for i, e := range entries {
+				if locked, ok := locks[lang][e.Key]; ok && e.Fallback != locked {
+					log.Printf("Conflict: %s value of locked key %s differs from its signed-off value %q; keeping the locked value\n", legacyPath, e.Key, locked)
+					entries[i].Fallback = locked
+				}
+			}
+			for from, to := range fileRenames {
+				renames[from] = to
+			}
+
+			langPath := outFormat.Path(*outputDir, lang)
+			if err := outFormat.Write(entries, lang, langPath, false, emit.Encoding{}, nil); err != nil {
+				log.Printf("Failed to generate %s: %v\n", filepath.Base(langPath), err)
+				failed = true
+				continue
+			}
+			log.Printf("%s migrated to %s (%d keys, %d renamed).", legacyPath, filepath.Base(langPath), len(entries), len(fileRenames))
+		}
+
+		// Renamed keys keep the stable ids of their legacy keys
+		if *locksName != "" {
+			idsPath := filepath.Join(*outputDir, *locksName)
+			ids, err := loadKeyIDs(idsPath)
+			if err != nil {
+				return fmt.Errorf("failed to load key ids: %w", err)
+			}
+			if len(ids) > 0 {
+				ids.assign(nil, renames)
+				if err := writeKeyIDs(ids, idsPath); err != nil {
+					return fmt.Errorf("failed to write key ids: %w", err)
+				}
+			}
+		}
+
+		data, err := json.MarshalIndent(renames, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode rename map: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(*outputDir, *renameMapName), append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write rename map: %w", err)
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// keyMapping translates the keys of a legacy naming scheme to the generated
+// keys: through a lookup table first, then through rewriting rules.
+type keyMapping struct {
+	table map[string]string
+	rules []keyTransform
+}
+
+// loadKeyMapping reads the lookup table of a key mapping from a JSON object of
+// legacy keys to generated keys, when a file is given, and parses its rules.
+func loadKeyMapping(filePath string, rules []string) (keyMapping, error) {
+	var mapping keyMapping
+	var err error
+	if mapping.rules, err = parseKeyTransforms(rules); err != nil {
+		return mapping, err
+	}
+	if filePath == "" {
+		return mapping, nil
+	}
+	data, err := readFile(filePath, nil)
+	if err != nil {
+		return mapping, err
+	}
+	if err := json.Unmarshal(data, &mapping.table); err != nil {
+		return mapping, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return mapping, nil
+}
+
+// target returns the key a legacy key maps to, or the key itself when the
+// mapping leaves it unchanged.
+func (m keyMapping) target(key string) string {
+	if target, ok := m.table[key]; ok {
+		return target
+	}
+	for _, t := range m.rules {
+		key = t.apply(key)
+	}
+	return key
+}
+
+// migrateEntries builds the entries of a migrated language file: the extracted
+// entries in declaration order, followed by legacy keys without a counterpart in
+// the protos in alphabetical order. Legacy keys are mapped first and then
+// matched ignoring case and separators, and their values become the fallback of
+// their entry. It returns the legacy keys that were renamed.
+func migrateEntries(extracted []extract.Entry, legacy map[string]string, mapping keyMapping) ([]extract.Entry, map[string]string) {
+	canonical := make(map[string]string)
+	for _, e := range extracted {
+		if _, ok := canonical[normalizeKey(e.Key)]; !ok {
+			canonical[normalizeKey(e.Key)] = e.Key
+		}
+	}
+
+	values := make(map[string]string)
+	renames := make(map[string]string)
+	var unmatched []string
+	for key, value := range legacy {
+		target, ok := canonical[normalizeKey(mapping.target(key))]
+		if !ok {
+			if mapped, listed := mapping.table[key]; listed {
+				log.Printf("Warning: %s is mapped to %s, which is not extracted from the protos; keeping it as it is\n", key, mapped)
+			}
+			unmatched = append(unmatched, key)
+			continue
+		}
+		if target != key {
+			renames[key] = target
+		}
+		if values[target] == "" {
+			values[target] = value
+		}
+	}
+	sort.Strings(unmatched)
+
+	var entries []extract.Entry
+	for _, e := range extracted {
+		if value, ok := values[e.Key]; ok {
+			e.Fallback = value
+		} else {
+			e.Fallback = e.Message
+		}
+		entries = append(entries, e)
+	}
+	for _, key := range unmatched {
+		entries = append(entries, extract.Entry{Key: key, Name: key, Fallback: legacy[key]})
+	}
+	return entries, renames
+}
+
+// normalizeKey reduces a key to its lowercase letters and digits, so that
+// UserNotFound, user-not-found and USER_NOT_FOUND compare equal.
+func normalizeKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// loadLegacyBundle reads a hand-written language file. JSON files may be flat or
+// nested; TOML files may use bare key = "value" pairs or tables with an other key.
+func loadLegacyBundle(filePath string) (map[string]string, error) {
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		return loadLanguage(emit.Formats["i18next"], filePath, nil)
+	}
+
+	data, err := readFile(filePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read legacy file: %w", err)
+	}
+	items, _, err := toml.Parse(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]string)
+	var table string
+	for _, item := range items {
+		value := textutil.Escape(item.Value)
+		switch {
+		case item.Header:
+			table = item.Key
+		case table == "":
+			entries[item.Key] = value
+		case item.Key == "other":
+			entries[table] = value
+		default:
+			entries[table+"."+item.Key] = value
+		}
+	}
+	return entries, nil
+}
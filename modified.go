@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// modification records when a text last changed, identified by its hash, and
+// the commit of the protos at that time.
+type modification struct {
+	Hash     string    `json:"hash"`
+	Modified time.Time `json:"modified"`
+	Commit   string    `json:"commit,omitempty"`
+}
+
+// modifiedStore tracks the last modification of the source message of every
+// key and of its translation in each language, so that translations older
+// than their source can be reported as outdated.
+type modifiedStore struct {
+	Source    map[string]modification            `json:"source"`
+	Languages map[string]map[string]modification `json:"languages"`
+}
+
+// loadModified reads a last-modified file, returning an empty store when it
+// does not exist.
+func loadModified(filePath string) (*modifiedStore, error) {
+	store := &modifiedStore{}
+	data, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, store); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(filePath), err)
+		}
+	}
+	if store.Source == nil {
+		store.Source = make(map[string]modification)
+	}
+	if store.Languages == nil {
+		store.Languages = make(map[string]map[string]modification)
+	}
+	return store, nil
+}
+
+// writeModified writes the store as JSON, with keys sorted.
+func writeModified(store *modifiedStore, filePath string) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, append(data, '\n'), 0644)
+}
+
+// textHash returns a short hash identifying a text.
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// touch updates the modification of key in times when its text changed.
+func touch(times map[string]modification, key, text string, now time.Time, commit string) {
+	hash := textHash(text)
+	if m, ok := times[key]; ok && m.Hash == hash {
+		return
+	}
+	times[key] = modification{Hash: hash, Modified: now, Commit: commit}
+}
+
+// confirm records the translation of a key as up to date with its source, as
+// when a reviewer accepts it unchanged.
+func (s *modifiedStore) confirm(lang, key, text string, now time.Time) {
+	if s.Languages[lang] == nil {
+		s.Languages[lang] = make(map[string]modification)
+	}
+	s.Languages[lang][key] = modification{Hash: textHash(text), Modified: now}
+}
+
+// prune removes the keys of times that are not in keep.
+func prune(times map[string]modification, keep map[string]bool) {
+	for key := range times {
+		if !keep[key] {
+			delete(times, key)
+		}
+	}
+}
+
+// updateSources records the source messages, and proto comments, of entries.
+func (s *modifiedStore) updateSources(entries []extract.Entry, now time.Time, commit string) {
+	keep := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		keep[e.Key] = true
+		touch(s.Source, e.Key, e.Message+"\x00"+e.Comment, now, commit)
+	}
+	prune(s.Source, keep)
+}
+
+// updateLanguage records the translations of a language file.
+func (s *modifiedStore) updateLanguage(lang string, entries []extract.Entry, translations map[string]string, now time.Time, commit string) {
+	times := s.Languages[lang]
+	if times == nil {
+		times = make(map[string]modification)
+		s.Languages[lang] = times
+	}
+	keep := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		keep[e.Key] = true
+		touch(times, e.Key, translations[e.Key], now, commit)
+	}
+	prune(times, keep)
+}
+
+// outdated returns the keys of a language whose translation was last modified
+// before their source message, with how long the source has been ahead.
+func (s *modifiedStore) outdated(lang string) map[string]time.Duration {
+	keys := make(map[string]time.Duration)
+	for key, m := range s.Languages[lang] {
+		if source, ok := s.Source[key]; ok && m.Modified.Before(source.Modified) {
+			keys[key] = time.Since(source.Modified)
+		}
+	}
+	return keys
+}
+
+// recordModified updates the last-modified file with the source messages of
+// entries and the translations of the generated language files, each with the
+// keys of the namespaces it ships.
+func recordModified(entries []extract.Entry, outFormat emit.Format, outputDir string, langs []string, namespaces namespaceLanguages, filePath, protoDir string) error {
+	store, err := loadModified(filePath)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	commit := sourceCommit(protoDir)
+	store.updateSources(entries, now, commit)
+	for _, lang := range langs {
+		translations, err := loadLanguage(outFormat, outFormat.Path(outputDir, lang), nil)
+		if err != nil {
+			return err
+		}
+		store.updateLanguage(lang, namespaces.entries(entries, lang), translations, now, commit)
+	}
+	return writeModified(store, filePath)
+}
+
+// sourceCommit returns the abbreviated git commit of the directory, or an
+// empty string outside of a git work tree.
+func sourceCommit(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
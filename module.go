@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// moduleRef matches the reference of a module of a buf registry:
+// <remote>/<owner>/<repository>, optionally followed by :<ref>, a label,
+// commit or tag.
+var moduleRef = regexp.MustCompile(`^[\w.-]+(:\d+)?/[\w.-]+/[\w.-]+(:[\w./-]+)?$`)
+
+// moduleFiles extracts the entries of the files of a module of the Buf Schema
+// Registry, such as buf.build/acme/errors:v1.2.0, without a local checkout.
+// The image of the module is built by the buf CLI, the binary in $BUF or buf
+// on the PATH, which resolves the reference, authenticates with the
+// credentials of buf registry login or $BUF_TOKEN and caches the module. The
+// files of the modules it depends on are left out.
+func moduleFiles(module string, opts extract.Options) []extract.File {
+	if !moduleRef.MatchString(module) || strings.HasPrefix(module, "-") {
+		return []extract.File{{Path: module, Err: fmt.Errorf("invalid module reference %q, want <remote>/<owner>/<repository>[:<ref>]", module)}}
+	}
+	bin := os.Getenv("BUF")
+	if bin == "" {
+		bin = "buf"
+	}
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return []extract.File{{Path: module, Err: fmt.Errorf("the buf CLI is needed to fetch modules: %w", err)}}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, "build", module, "--exclude-imports", "--as-file-descriptor-set", "-o", "-")
+	cmd.Stderr = &stderr
+	image, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return []extract.File{{Path: module, Err: fmt.Errorf("buf build: %w", err)}}
+	}
+	return decodeDescriptorSet(module, image, opts)
+}
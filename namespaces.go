@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// namespaceRule restricts the keys of the proto packages matching a pattern to
+// a subset of the languages.
+type namespaceRule struct {
+	pattern *regexp.Regexp
+	langs   []string
+}
+
+// namespaceLanguages are the languages each namespace ships in. Keys of the
+// namespaces no rule matches, or of every namespace when there are no rules,
+// go into every language.
+type namespaceLanguages []namespaceRule
+
+// loadNamespaceLanguages reads a CODEOWNERS-style file with one rule per line:
+//
+//	# package     languages
+//	*             en,zh,ja,ko,fr,de,es,pt,it,ru,ar,hi
+//	admin.*       en,zh
+//
+// Patterns match whole proto packages, * matching any run of characters; keys
+// without a package only match *. As in CODEOWNERS, the last matching rule
+// wins.
+func loadNamespaceLanguages(filePath string) (namespaceLanguages, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules namespaceLanguages
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a package pattern and a comma-separated list of languages", filePath, lineNum)
+		}
+		parts := strings.Split(fields[0], "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		rules = append(rules, namespaceRule{
+			pattern: regexp.MustCompile("^" + strings.Join(parts, ".*") + "$"),
+			langs:   splitLanguages(fields[1]),
+		})
+	}
+	return rules, scanner.Err()
+}
+
+// includes reports whether the key of an entry ships in a language.
+func (n namespaceLanguages) includes(e extract.Entry, lang string) bool {
+	for i := len(n) - 1; i >= 0; i-- {
+		if !n[i].pattern.MatchString(e.Package) {
+			continue
+		}
+		for _, l := range n[i].langs {
+			if strings.EqualFold(l, lang) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// entries returns the entries whose keys ship in a language, in order.
+func (n namespaceLanguages) entries(entries []extract.Entry, lang string) []extract.Entry {
+	if len(n) == 0 {
+		return entries
+	}
+	var included []extract.Entry
+	for _, e := range entries {
+		if n.includes(e, lang) {
+			included = append(included, e)
+		}
+	}
+	return included
+}
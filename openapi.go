@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// openAPIExample is an OpenAPI Example Object.
+type openAPIExample struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Value       any    `json:"value"`
+}
+
+// errorStatus is the JSON form of google.rpc.Status, as returned by gRPC
+// gateways and Google style HTTP APIs.
+type errorStatus struct {
+	Code    int              `json:"code"`
+	Message string           `json:"message"`
+	Details []map[string]any `json:"details"`
+}
+
+// writeOpenAPIExamples writes an OpenAPI examples object with an error response
+// per enum value and language, named <key>.<lang>, to embed below the examples
+// of an error response or in components. Each example is a google.rpc.Status
+// with the gRPC code of the value (UNKNOWN without one), the translation as
+// message, and ErrorInfo and LocalizedMessage details. Translations are read
+// from the language files, falling back to the written fallback.
+func writeOpenAPIExamples(entries []extract.Entry, outFormat emit.Format, outputDir string, langs []string, namespaces namespaceLanguages, filePath string) error {
+	examples := make(map[string]openAPIExample)
+	for _, lang := range langs {
+		values, err := loadLanguage(outFormat, outFormat.Path(outputDir, lang), nil)
+		if err != nil {
+			return err
+		}
+		for _, e := range namespaces.entries(entries, lang) {
+			if e.Kind != extract.KindEnum {
+				continue
+			}
+			message := textutil.Unescape(emit.EntryValue(values, e))
+			examples[e.Key+"."+lang] = openAPIExample{
+				Summary:     fmt.Sprintf("%s (%s)", e.Key, lang),
+				Description: e.Comment,
+				Value: errorStatus{
+					Code:    grpcCodeNumber(e.GRPCCode),
+					Message: message,
+					Details: []map[string]any{
+						{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": e.Name, "domain": e.Package},
+						{"@type": "type.googleapis.com/google.rpc.LocalizedMessage", "locale": lang, "message": message},
+					},
+				},
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(examples, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, append(data, '\n'), 0644)
+}
+
+// grpcCodeNumber returns the number of a gRPC code given by name or number,
+// or that of UNKNOWN when it is empty or not a code.
+func grpcCodeNumber(code string) int {
+	if i := slices.Index(grpcCodesByNumber, code); i >= 0 {
+		return i
+	}
+	if n, err := strconv.Atoi(code); err == nil && n >= 0 {
+		return n
+	}
+	return slices.Index(grpcCodesByNumber, "UNKNOWN")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emicklei/proto"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Targets of an option rule.
+const (
+	optionTargetKey     = "key"
+	optionTargetMessage = "message"
+)
+
+// extension is a custom option declared in an extend block.
+type extension struct {
+	Name     string            // fully qualified name, e.g. xerr.info
+	Extendee string            // extended options message, e.g. google.protobuf.EnumValueOptions
+	Type     string            // declared type of the option
+	Fields   map[string]string // field types when the option is message typed
+}
+
+// optionRule maps a custom option, or a field of a message typed one, to the key
+// or default message of the enum values it is set on.
+type optionRule struct {
+	Option string // fully qualified option name
+	Field  string // field of a message typed option, if any
+	Target string // optionTargetKey or optionTargetMessage
+}
+
+// loadExtensions parses the proto files and, transitively, the files they import
+// (looked up below the include paths) and returns the custom options declared in
+// their extend blocks by fully qualified name.
+func loadExtensions(protoFiles []string, includePaths []string) (map[string]extension, error) {
+	extensions := make(map[string]extension)
+	messages := make(map[string]map[string]string) // message fields by qualified message name
+	var pending []extension
+	packages := make(map[string]string) // package of each pending extension
+
+	seen := make(map[string]bool)
+	queue := append([]string{}, protoFiles...)
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+
+		definition, err := parseProtoDefinition(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		pkg := extract.PackageName(definition)
+
+		proto.Walk(definition,
+			proto.WithImport(func(i *proto.Import) {
+				if resolved := resolveImport(i.Filename, includePaths); resolved != "" {
+					queue = append(queue, resolved)
+				}
+			}),
+			proto.WithMessage(func(m *proto.Message) {
+				if !m.IsExtend {
+					fields := make(map[string]string)
+					for _, elem := range m.Elements {
+						if field, ok := elem.(*proto.NormalField); ok {
+							fields[field.Name] = field.Type
+						}
+					}
+					messages[qualify(pkg, m.Name)] = fields
+					return
+				}
+				for _, elem := range m.Elements {
+					if field, ok := elem.(*proto.NormalField); ok {
+						ext := extension{Name: qualify(pkg, field.Name), Extendee: m.Name, Type: field.Type}
+						pending = append(pending, ext)
+						packages[ext.Name] = pkg
+					}
+				}
+			}),
+		)
+	}
+
+	// Resolve message typed options once all messages are known
+	for _, ext := range pending {
+		if fields, ok := messages[qualify(packages[ext.Name], ext.Type)]; ok {
+			ext.Fields = fields
+		} else if fields, ok := messages[strings.TrimPrefix(ext.Type, ".")]; ok {
+			ext.Fields = fields
+		}
+		extensions[ext.Name] = ext
+	}
+	return extensions, nil
+}
+
+// parseProtoDefinition parses a proto file.
+func parseProtoDefinition(filePath string) (*proto.Proto, error) {
+	data, err := readFile(filePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open proto file: %w", err)
+	}
+
+	return extract.ParseSource(filePath, data)
+}
+
+// resolveImport returns the path of an imported file below the first include
+// path containing it, or an empty string if none does.
+func resolveImport(importPath string, includePaths []string) string {
+	for _, dir := range includePaths {
+		candidate := filepath.Join(dir, filepath.FromSlash(importPath))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// qualify prefixes a name with a package unless the package is empty.
+func qualify(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// parseOptionRules parses rules of the form <option>[.<field>]=<key|message>
+// against the declared extensions.
+func parseOptionRules(rules []string, extensions map[string]extension) ([]optionRule, error) {
+	var parsed []optionRule
+	for _, rule := range rules {
+		path, target, ok := strings.Cut(rule, "=")
+		if !ok || (target != optionTargetKey && target != optionTargetMessage) {
+			return nil, fmt.Errorf("%q: expected <option>[.<field>]=key|message", rule)
+		}
+		path = strings.Trim(path, "()")
+
+		// The option is the longest declared prefix of the path
+		var r *optionRule
+		for name := path; name != ""; {
+			if ext, ok := extensions[name]; ok {
+				field := strings.TrimPrefix(strings.TrimPrefix(path, name), ".")
+				switch {
+				case field == "" && ext.Fields != nil:
+					return nil, fmt.Errorf("%q: option %s is a message, name one of its fields", rule, name)
+				case field != "" && ext.Fields[field] == "":
+					return nil, fmt.Errorf("%q: option %s has no field %s", rule, name, field)
+				}
+				r = &optionRule{Option: name, Field: field, Target: target}
+				break
+			}
+			i := strings.LastIndex(name, ".")
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+		if r == nil {
+			return nil, fmt.Errorf("%q: option is not declared in any imported file (check -I)", rule)
+		}
+		parsed = append(parsed, *r)
+	}
+	return parsed, nil
+}
+
+// applyOptionRules sets the key or default message of every entry whose options
+// match a rule.
+func applyOptionRules(entries []extract.Entry, rules []optionRule) {
+	for i, e := range entries {
+		for _, option := range e.Options {
+			name := strings.Trim(option.Name, "()")
+			for _, rule := range rules {
+				if name != rule.Option && qualify(e.Package, name) != rule.Option {
+					continue
+				}
+				literal := &option.Constant
+				if rule.Field != "" {
+					var ok bool
+					if literal, ok = option.Constant.OrderedMap.Get(rule.Field); !ok {
+						continue
+					}
+				}
+				if literal.Source == "" {
+					continue
+				}
+				switch rule.Target {
+				case optionTargetKey:
+					entries[i].Key = textutil.Unescape(literal.Source)
+				case optionTargetMessage:
+					entries[i].Message = literal.Source
+				}
+			}
+		}
+	}
+}
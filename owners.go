@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// unowned is the owner reported for keys no owners rule matches.
+const unowned = "(unowned)"
+
+// ownerRule assigns the keys matching a pattern to an owner, optionally
+// notified through a webhook.
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owner   string
+	webhook string
+}
+
+// loadOwners reads a CODEOWNERS-style file with one rule per line:
+//
+//	# pattern  owner       [webhook]
+//	*          @platform
+//	user.*     @identity   https://hooks.example.com/identity
+//
+// Patterns match whole keys, * matching any run of characters. As in
+// CODEOWNERS, the last matching rule wins.
+func loadOwners(filePath string) ([]ownerRule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []ownerRule
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected a pattern, an owner and an optional webhook", filePath, lineNum)
+		}
+		parts := strings.Split(fields[0], "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		rule := ownerRule{
+			pattern: regexp.MustCompile("^" + strings.Join(parts, ".*") + "$"),
+			owner:   fields[1],
+		}
+		if len(fields) == 3 {
+			rule.webhook = fields[2]
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// ownerOf returns the last rule matching the key, or nil.
+func ownerOf(rules []ownerRule, key string) *ownerRule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(key) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// newKeyNotice lists the new keys of one owner.
+type newKeyNotice struct {
+	Owner string   `json:"owner"`
+	Keys  []string `json:"keys"`
+
+	webhook string
+}
+
+// routeNewKeys groups the entries missing from the existing translations by
+// owner, in the order owners first appear.
+func routeNewKeys(entries []extract.Entry, existing map[string]string, rules []ownerRule) []newKeyNotice {
+	var notices []newKeyNotice
+	index := make(map[string]int)
+	for _, e := range entries {
+		if _, ok := existing[e.Key]; ok {
+			continue
+		}
+		owner, webhook := unowned, ""
+		if rule := ownerOf(rules, e.Key); rule != nil {
+			owner, webhook = rule.owner, rule.webhook
+		}
+		i, ok := index[owner]
+		if !ok {
+			i = len(notices)
+			index[owner] = i
+			notices = append(notices, newKeyNotice{Owner: owner, webhook: webhook})
+		}
+		notices[i].Keys = append(notices[i].Keys, e.Key)
+	}
+	return notices
+}
+
+// notifyOwner posts the notice as JSON to the owner's webhook.
+func notifyOwner(notice newKeyNotice) error {
+	body, err := json.Marshal(struct {
+		newKeyNotice
+		Text string `json:"text"`
+	}{notice, fmt.Sprintf("%d new i18n key(s) for %s: %s", len(notice.Keys), notice.Owner, strings.Join(notice.Keys, ", "))})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(notice.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", notice.webhook, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+)
+
+// packModTime is the modification time of every archived file, so that packing
+// the same bundles twice yields identical archives.
+var packModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// packFile is a file to archive, named relative to the archive root.
+type packFile struct {
+	name string
+	data []byte
+}
+
+// packCommand implements the pack command, which bundles the language files of
+// the output directory with the manifest, if any, a VERSION file and SHA-256
+// checksums into a single tar.gz or zip archive.
+func packCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	manifestName := fs.String("manifest", "", "Name of the manifest in the output directory to pack along, as gen -manifest (optional)")
+	packVersion := fs.String("version", "", "Version of the language pack (required)")
+	archiveFormat := fs.String("archive", "tar.gz", "Archive format (tar.gz, zip)")
+	archivePath := fs.String("o", "", "Path of the archive (default i18n-<version>.<archive>)")
+
+	return func(_ []string) error {
+		if *packVersion == "" {
+			return usageErrorf("missing -version")
+		}
+		if *archiveFormat != "tar.gz" && *archiveFormat != "zip" {
+			return usageErrorf("unknown archive format: %s", *archiveFormat)
+		}
+		packFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		if *archivePath == "" {
+			*archivePath = fmt.Sprintf("i18n-%s.%s", *packVersion, *archiveFormat)
+		}
+
+		files, err := collectPackFiles(*outputDir, packFormat, splitLanguages(*languages), *manifestName)
+		if err != nil {
+			return fmt.Errorf("failed to collect language files: %w", err)
+		}
+
+		files = append(files, packFile{name: "VERSION", data: []byte(*packVersion + "\n")})
+		files = append(files, packFile{name: "checksums.txt", data: packChecksums(files)})
+
+		root := "i18n-" + *packVersion
+		var buffer bytes.Buffer
+		if *archiveFormat == "zip" {
+			err = writeZip(&buffer, root, files)
+		} else {
+			err = writeTarGz(&buffer, root, files)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to build archive: %w", err)
+		}
+		if err := os.WriteFile(*archivePath, buffer.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		log.Printf("%s packed with %d file(s).", *archivePath, len(files))
+		return nil
+	}
+}
+
+// collectPackFiles reads the files of the languages below dir in the format,
+// its default resources and the manifest if named, in path order. Everything
+// else in the directory, such as the review or locks file, is left out.
+func collectPackFiles(dir string, format emit.Format, langs []string, manifestName string) ([]packFile, error) {
+	var paths []string
+	for _, lang := range langs {
+		paths = append(paths, format.Path(dir, lang))
+	}
+	if format.DefaultPath != nil {
+		paths = append(paths, format.DefaultPath(dir))
+	}
+	if manifestName != "" {
+		paths = append(paths, filepath.Join(dir, manifestName))
+	}
+
+	files := make([]packFile, 0, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, packFile{name: filepath.ToSlash(rel), data: data})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// packChecksums returns the SHA-256 checksums of the files in sha256sum format.
+func packChecksums(files []packFile) []byte {
+	var buffer strings.Builder
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		buffer.WriteString(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), f.name))
+	}
+	return []byte(buffer.String())
+}
+
+func writeTarGz(w io.Writer, root string, files []packFile) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		header := &tar.Header{
+			Name:    root + "/" + f.name,
+			Mode:    0644,
+			Size:    int64(len(f.data)),
+			ModTime: packModTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, root string, files []packFile) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     root + "/" + f.name,
+			Method:   zip.Deflate,
+			Modified: packModTime,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
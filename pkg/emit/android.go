@@ -0,0 +1,170 @@
+package emit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// androidKeyComment introduces the comment naming the key of a resource whose
+// name had to be changed.
+const androidKeyComment = "key: "
+
+// androidPath names the language files values-<qualifier>/strings.xml.
+func androidPath(outputDir, lang string) string {
+	return filepath.Join(outputDir, "values-"+androidQualifier(lang), "strings.xml")
+}
+
+// androidDefaultPath names the default resources, values/strings.xml.
+func androidDefaultPath(outputDir string) string {
+	return filepath.Join(outputDir, "values", "strings.xml")
+}
+
+// androidQualifier returns the resource qualifier of a BCP 47 tag: the
+// language alone, language-rREGION, or the b+ form for anything else, e.g.
+// zh, pt-rBR and b+zh+Hans.
+func androidQualifier(lang string) string {
+	parts := strings.Split(strings.ReplaceAll(lang, "_", "-"), "-")
+	switch {
+	case len(parts) == 1:
+		return strings.ToLower(parts[0])
+	case len(parts) == 2 && (len(parts[1]) == 2 || len(parts[1]) == 3 && parts[1][0] >= '0' && parts[1][0] <= '9'):
+		return strings.ToLower(parts[0]) + "-r" + strings.ToUpper(parts[1])
+	}
+	return "b+" + strings.Join(parts, "+")
+}
+
+// androidName returns the resource name of a key: lower case, with every
+// character other than letters, digits and underscores replaced by an
+// underscore, and an underscore prepended when it starts with a digit.
+func androidName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, key)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// androidCollisions returns a CollisionError for the keys whose resource name
+// is taken by an earlier key, such as user.not_found after USER_NOT_FOUND.
+func androidCollisions(entries []extract.Entry, _ string) error {
+	names := make(map[string]string)
+	var keys []string
+	for _, entry := range entries {
+		name := androidName(entry.Key)
+		if other, ok := names[name]; ok {
+			keys = append(keys, fmt.Sprintf("%s (%s of %s)", entry.Key, name, other))
+			continue
+		}
+		names[name] = entry.Key
+	}
+	return collisionError(keys, "take the Android resource name of another key")
+}
+
+// generateAndroid updates or creates an Android strings.xml resource file.
+// Keys are converted to resource names, with a "key:" comment naming the key
+// when that changed it, and named placeholders become positional format
+// arguments %1$s, %2$s, ... Keys whose resource name is taken by an earlier
+// key fail it.
+func generateAndroid(entries []extract.Entry, filePath string) error {
+	existingEntries, err := loadExistingAndroid(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing Android strings: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<resources>\n")
+	if err := androidCollisions(entries, ""); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := androidName(entry.Key)
+
+		value := EntryValue(existingEntries, entry)
+		comment := fmt.Sprintf("%s:%d %s%s", entry.File, entry.Line, entry.Path, idNote(entry))
+		if name != entry.Key {
+			comment += "\n         " + androidKeyComment + entry.Key
+		}
+		buffer.WriteString(fmt.Sprintf("    <!-- %s -->\n", strings.ReplaceAll(comment, "--", "- -")))
+		text := numberPlaceholders(textutil.Unescape(value), func(_ string, index int) string {
+			return "%" + strconv.Itoa(index+1) + "$s"
+		})
+		buffer.WriteString(fmt.Sprintf("    <string name=\"%s\">%s</string>\n", name, textutil.EscapeAndroid(text)))
+	}
+	buffer.WriteString("</resources>\n")
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("create resource directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write Android strings: %w", err)
+	}
+	return nil
+}
+
+// loadExistingAndroid parses an existing strings.xml into a map of keys with
+// their values. Resources preceded by a "key:" comment are mapped back to that
+// key, the others are taken by name.
+func loadExistingAndroid(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read Android strings: %w", err)
+	}
+
+	decoder := textutil.NewXMLDecoder(data)
+	var key string // key named by the last comment
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse Android strings: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.Comment:
+			key = ""
+			if _, named, ok := strings.Cut(string(t), androidKeyComment); ok {
+				key = strings.TrimSpace(named)
+			}
+		case xml.StartElement:
+			if t.Name.Local != "string" {
+				continue
+			}
+			var s struct {
+				Name string `xml:"name,attr"`
+				Text string `xml:",chardata"`
+			}
+			if err := decoder.DecodeElement(&s, &t); err != nil {
+				return nil, fmt.Errorf("parse Android strings: %w", err)
+			}
+			if key == "" || androidName(key) != s.Name {
+				key = s.Name
+			}
+			entries[key] = textutil.Escape(textutil.UnescapeAndroid(s.Text))
+			key = ""
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,58 @@
+package emit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+func TestWriteCollisions(t *testing.T) {
+	tests := []struct {
+		format   string
+		keys     []string          // keys of the entries written, in order
+		packages map[string]string // proto packages of the keys
+		want     []string          // keys of the CollisionError
+	}{
+		{format: "i18next", keys: []string{"Req.email", "Req.email.bad"}, want: []string{"Req.email.bad"}},
+		{format: "i18next", keys: []string{"Req.email.bad", "Req.email", "Req.name"}, want: []string{"Req.email"}},
+		{format: "i18next", keys: []string{"Req.email", "Req.name"}},
+		{format: "yaml-nested", keys: []string{"Req.email", "Req.email.bad"}, want: []string{"Req.email.bad"}},
+		{format: "yaml-nested", keys: []string{"Req.email", "Req.name"}},
+		{format: "i18next-ns", keys: []string{"USER_NOT_FOUND", "demo"}, packages: map[string]string{"USER_NOT_FOUND": "demo.v1"}, want: []string{"demo"}},
+		{format: "i18next-ns", keys: []string{"Req.email", "Req.email.bad"}},
+		{format: "android", keys: []string{"USER_NOT_FOUND", "user.not_found", "user.taken"}, want: []string{"user.not_found (user_not_found of USER_NOT_FOUND)"}},
+		{format: "android", keys: []string{"USER_NOT_FOUND", "user.taken"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			entries := make([]extract.Entry, len(tt.keys))
+			for i, key := range tt.keys {
+				entries[i] = extract.Entry{Key: key, Name: key, Kind: extract.KindEnum, File: "errors.proto", Line: i + 1, Package: tt.packages[key], Fallback: key}
+			}
+			format := Formats[tt.format]
+			filePath := format.Path(t.TempDir(), "en")
+			err := format.Write(entries, "en", filePath, false, Encoding{}, nil)
+
+			var collision *CollisionError
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &collision) {
+				t.Fatalf("Write returned %v, want a CollisionError", err)
+			}
+			if !slices.Equal(collision.Keys, tt.want) {
+				t.Errorf("colliding keys %q, want %q", collision.Keys, tt.want)
+			}
+			if _, err := os.Stat(filePath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s written despite the collision", filepath.Base(filePath))
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package emit
+
+import (
+	"sort"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Emitter writes and reads back the language files of an output format.
+// Emitters are registered by name with Register, so formats can be added by
+// other packages and selected like the built-in ones.
+type Emitter interface {
+	// Path returns the file of a language below the output directory.
+	Path(outputDir, lang string) string
+	// Generate creates or updates a language file, keeping existing translations.
+	Generate(entries []extract.Entry, lang, filePath string) error
+	// Load reads the translations of an existing language file by key.
+	Load(filePath string) (map[string]string, error)
+}
+
+// RuneEscaper is implemented by emitters able to escape a non-ASCII character
+// in the syntax of their format, for the ascii encoding.
+type RuneEscaper interface {
+	EscapeRune(r rune) string
+}
+
+// DefaultPather is implemented by emitters whose format has default resources,
+// a copy of the file of the default language.
+type DefaultPather interface {
+	DefaultPath(outputDir string) string
+}
+
+// Commenter is implemented by emitters whose format has comments, returning a
+// line of text as a comment line, for the header.
+type Commenter interface {
+	Comment(text string) string
+}
+
+// Collider is implemented by emitters whose format cannot write some keys
+// alongside others, returning a CollisionError for them.
+type Collider interface {
+	Collisions(entries []extract.Entry, lang string) error
+}
+
+// Register adds an emitter to Formats by name. It panics if the name is
+// already taken, as registering twice is a programming error.
+func Register(name string, e Emitter) {
+	if _, ok := Formats[name]; ok {
+		panic("emit: format " + name + " registered twice")
+	}
+	f := Format{Path: e.Path, Generate: e.Generate, Load: e.Load}
+	if x, ok := e.(RuneEscaper); ok {
+		f.EscapeRune = x.EscapeRune
+	}
+	if x, ok := e.(DefaultPather); ok {
+		f.DefaultPath = x.DefaultPath
+	}
+	if x, ok := e.(Commenter); ok {
+		f.Comment = x.Comment
+	}
+	if x, ok := e.(Collider); ok {
+		f.Collisions = x.Collisions
+	}
+	Formats[name] = f
+}
+
+// FormatNames returns the names of the formats, sorted.
+func FormatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,118 @@
+package emit
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+)
+
+// Encoding is how the writers encode the language files, for consumers
+// that need more than the default UTF-8. The zero value writes UTF-8 as
+// generated.
+type Encoding struct {
+	nfc    bool               // normalize to Unicode NFC
+	ascii  bool               // escape non-ASCII characters with the escapes of the format
+	utf16  bool               // encode as UTF-16 with a byte order mark
+	endian unicode.Endianness // byte order of UTF-16
+}
+
+// ParseEncoding parses a comma-separated list of encoding options: utf-8,
+// nfc, ascii, utf-16le and utf-16be. ascii needs a format able to escape
+// characters.
+func ParseEncoding(spec string, format Format) (Encoding, error) {
+	var enc Encoding
+	for _, option := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(option)) {
+		case "", "utf-8", "utf8":
+		case "nfc":
+			enc.nfc = true
+		case "ascii":
+			if format.EscapeRune == nil {
+				return enc, fmt.Errorf("the format cannot escape characters as ASCII")
+			}
+			enc.ascii = true
+		case "utf-16le":
+			enc.utf16, enc.endian = true, unicode.LittleEndian
+		case "utf-16be":
+			enc.utf16, enc.endian = true, unicode.BigEndian
+		default:
+			return enc, fmt.Errorf("unknown encoding option %s", option)
+		}
+	}
+	return enc, nil
+}
+
+// UTF8 reports whether the language files are written as UTF-8, which all
+// encodings but UTF-16 are.
+func (enc Encoding) UTF8() bool {
+	return !enc.utf16
+}
+
+// encode applies the encoding to the UTF-8 contents of a language file.
+func (enc Encoding) encode(data []byte, escapeRune func(r rune) string) ([]byte, error) {
+	if enc.nfc {
+		data = norm.NFC.Bytes(data)
+	}
+	if enc.ascii {
+		var b strings.Builder
+		for _, r := range string(data) {
+			if r < utf8.RuneSelf {
+				b.WriteRune(r)
+			} else {
+				b.WriteString(escapeRune(r))
+			}
+		}
+		data = []byte(b.String())
+	}
+	if enc.utf16 {
+		return unicode.UTF16(enc.endian, unicode.UseBOM).NewEncoder().Bytes(data)
+	}
+	return data, nil
+}
+
+// escapeUnicodeRune escapes a character as \uXXXX, or \UXXXXXXXX outside of
+// the Basic Multilingual Plane, as in TOML and YAML.
+func escapeUnicodeRune(r rune) string {
+	if r > 0xFFFF {
+		return fmt.Sprintf(`\U%08X`, r)
+	}
+	return fmt.Sprintf(`\u%04X`, r)
+}
+
+// escapeJSONRune escapes a character as \uXXXX, with a surrogate pair outside
+// of the Basic Multilingual Plane.
+func escapeJSONRune(r rune) string {
+	if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+		return fmt.Sprintf(`\u%04X\u%04X`, r1, r2)
+	}
+	return fmt.Sprintf(`\u%04X`, r)
+}
+
+// escapeStringsRune escapes a character as \UXXXX, with a surrogate pair
+// outside of the Basic Multilingual Plane, as in Apple .strings files.
+func escapeStringsRune(r rune) string {
+	return strings.ReplaceAll(escapeJSONRune(r), `\u`, `\U`)
+}
+
+// escapeXMLRune escapes a character as a numeric character reference.
+func escapeXMLRune(r rune) string {
+	return fmt.Sprintf("&#x%X;", r)
+}
+
+// FileEncoding returns the name of the encoding the loaders transcode a
+// language file from, such as UTF-16 or GBK saved by some Windows editors, or
+// an empty name for UTF-8, for the caller to warn about.
+func FileEncoding(filePath string) (string, error) {
+	file, encoding, err := textutil.OpenFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	return encoding, nil
+}
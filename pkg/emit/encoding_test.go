@@ -0,0 +1,133 @@
+package emit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+func TestEncodingRoundTrip(t *testing.T) {
+	const (
+		decomposed = "Café 😀 中文"
+		composed   = "Café 😀 中文"
+	)
+	entries := []extract.Entry{
+		{Key: "ERR_CAFE", Name: "ERR_CAFE", Kind: extract.KindEnum, Path: "ErrorReason", Package: "errors", File: "errors.proto", Line: 3, Fallback: decomposed},
+		{Key: "ERR_PLAIN", Name: "ERR_PLAIN", Kind: extract.KindEnum, Path: "ErrorReason", Package: "errors", File: "errors.proto", Line: 4, Fallback: "plain"},
+	}
+	encodings := []struct {
+		spec  string
+		ascii bool   // needs a format that escapes characters
+		want  string // value of ERR_CAFE read back
+		bom   []byte // leading bytes of the file
+	}{
+		{spec: "utf-8", want: decomposed},
+		{spec: "nfc", want: composed},
+		{spec: "ascii", ascii: true, want: decomposed},
+		{spec: "nfc,ascii", ascii: true, want: composed},
+		{spec: "utf-16le", want: decomposed, bom: []byte{0xFF, 0xFE}},
+		{spec: "nfc,utf-16be", want: composed, bom: []byte{0xFE, 0xFF}},
+		{spec: "ascii,utf-16le", ascii: true, want: decomposed, bom: []byte{0xFF, 0xFE}},
+	}
+
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		format := Formats[name]
+		for _, e := range encodings {
+			if e.ascii && format.EscapeRune == nil {
+				continue
+			}
+			t.Run(name+"/"+e.spec, func(t *testing.T) {
+				enc, err := ParseEncoding(e.spec, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				filePath := format.Path(t.TempDir(), "fr")
+				if name == "ts" {
+					// Qt Linguist files leave messages unfinished rather than
+					// falling back, so the translations are seeded
+					seed := "<TS version=\"2.1\" language=\"fr\"><context><name>ErrorReason</name>" +
+						"<message id=\"ERR_CAFE\"><source>ERR_CAFE</source><translation>" + decomposed + "</translation></message>" +
+						"<message id=\"ERR_PLAIN\"><source>ERR_PLAIN</source><translation>plain</translation></message>" +
+						"</context></TS>\n"
+					if err := os.WriteFile(filePath, []byte(seed), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := format.Write(entries, "fr", filePath, name != "ts", enc, nil); err != nil {
+					t.Fatal(err)
+				}
+
+				raw, err := os.ReadFile(filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.HasPrefix(raw, e.bom) {
+					t.Errorf("file starts with % X, want % X", raw[:min(len(raw), 2)], e.bom)
+				}
+				data, encoding, err := textutil.ReadFile(filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wantEncoding := ""
+				if e.bom != nil {
+					wantEncoding = "UTF-16"
+				}
+				if encoding != wantEncoding {
+					t.Errorf("ReadFile transcoded from %q, want %q", encoding, wantEncoding)
+				}
+				if encoding, err := FileEncoding(filePath); err != nil || encoding != wantEncoding {
+					t.Errorf("FileEncoding = %q, %v, want %q", encoding, err, wantEncoding)
+				}
+				if !utf8.Valid(data) {
+					t.Errorf("ReadFile returned invalid UTF-8")
+				}
+				if e.ascii {
+					for i, b := range data {
+						if b >= utf8.RuneSelf {
+							t.Fatalf("non-ASCII byte %#x at %d in %q", b, i, data)
+						}
+					}
+				}
+				streamed, err := readStream(filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(streamed, data) {
+					t.Errorf("OpenFile read %q, ReadFile %q", streamed, data)
+				}
+
+				loaded, err := format.Load(filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := textutil.Unescape(loaded["ERR_CAFE"]); got != e.want {
+					t.Errorf("ERR_CAFE = %q, want %q", got, e.want)
+				}
+				if got := textutil.Unescape(loaded["ERR_PLAIN"]); got != "plain" {
+					t.Errorf("ERR_PLAIN = %q, want %q", got, "plain")
+				}
+			})
+		}
+	}
+}
+
+// readStream reads a file through textutil.OpenFile.
+func readStream(filePath string) ([]byte, error) {
+	f, _, err := textutil.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
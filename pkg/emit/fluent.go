@@ -0,0 +1,266 @@
+package emit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/yaml"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+var (
+	// fluentIDRe matches valid Fluent message identifiers.
+	fluentIDRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	// fluentMessageRe matches the first line of a message or term.
+	fluentMessageRe = regexp.MustCompile(`^(-?[A-Za-z][A-Za-z0-9_-]*) *= *(.*)$`)
+	// fluentAttributeRe matches the first line of an attribute.
+	fluentAttributeRe = regexp.MustCompile(`^ +\.[A-Za-z][A-Za-z0-9_-]* *= *(.*)$`)
+	// fluentReferenceRe matches a pattern that only references another message.
+	fluentReferenceRe = regexp.MustCompile(`^\{ *[A-Za-z][A-Za-z0-9_-]* *\}$`)
+	// fluentKeyCommentRe matches the comment naming the key of a sanitized id.
+	fluentKeyCommentRe = regexp.MustCompile(`^# key: (.+)$`)
+	// goPlaceholderRe matches go-i18n {{.Name}} placeholders that are written as
+	// Fluent variables.
+	goPlaceholderRe = regexp.MustCompile(`\{\{\s*\.([A-Za-z]\w*)\s*\}\}`)
+	// fluentBraces escapes the braces of literal text as string literals.
+	fluentBraces = strings.NewReplacer("{", `{"{"}`, "}", `{"}"}`)
+)
+
+// fluentID returns the message identifier of a key: the key itself if it is a
+// valid identifier, else the key with every other character replaced by a
+// hyphen, prefixed with "k-" unless it starts with a letter.
+func fluentID(key string) string {
+	if fluentIDRe.MatchString(key) {
+		return key
+	}
+	id := strings.Map(func(r rune) rune {
+		if r < 0x80 && (r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return r
+		}
+		return '-'
+	}, key)
+	if !fluentIDRe.MatchString(id) {
+		id = "k-" + id
+	}
+	return id
+}
+
+// generateFluent updates or creates a Mozilla Fluent .ftl file. Every key is a
+// message preceded by its source location, with the proto comment as its
+// .description attribute and {{.Name}} placeholders written as { $Name }
+// variables. Keys that are not valid identifiers are written under the id of
+// fluentID and named in a "# key:" comment, which the loader maps back.
+func generateFluent(entries []extract.Entry, filePath string) error {
+	existingEntries, err := loadExistingFluent(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing Fluent: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	for i, entry := range entries {
+		value := EntryValue(existingEntries, entry)
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(fmt.Sprintf("# %s:%d %s%s\n", entry.File, entry.Line, entry.Path, idNote(entry)))
+		id := fluentID(entry.Key)
+		if id != entry.Key {
+			buffer.WriteString(fmt.Sprintf("# key: %s\n", entry.Key))
+		}
+		if entry.Reference {
+			buffer.WriteString(fmt.Sprintf("%s = { %s }\n", id, fluentID(entry.Alias)))
+		} else {
+			buffer.WriteString(fmt.Sprintf("%s = %s\n", id, fluentPattern(textutil.Unescape(value))))
+		}
+		if entry.Comment != "" {
+			buffer.WriteString(fmt.Sprintf("    .description = %s\n", fluentPattern(entry.Comment)))
+		}
+	}
+
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write Fluent file: %w", err)
+	}
+	return nil
+}
+
+// fluentPattern returns text as a Fluent pattern. Braces, and the blanks and
+// characters Fluent would strip or take as syntax at the start and end of a
+// line, are written as string literals; further lines are indented.
+func fluentPattern(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		var b strings.Builder
+		body := strings.TrimLeft(line, " ")
+		if lead := line[:len(line)-len(body)]; lead != "" {
+			b.WriteString(`{"` + lead + `"}`)
+		} else if i > 0 && body != "" && strings.ContainsAny(body[:1], "[*.") {
+			b.WriteString(`{"` + body[:1] + `"}`)
+			body = body[1:]
+		}
+		trimmed := strings.TrimRight(body, " ")
+		last := 0
+		for _, m := range goPlaceholderRe.FindAllStringSubmatchIndex(trimmed, -1) {
+			b.WriteString(fluentBraces.Replace(trimmed[last:m[0]]))
+			b.WriteString("{ $" + trimmed[m[2]:m[3]] + " }")
+			last = m[1]
+		}
+		b.WriteString(fluentBraces.Replace(trimmed[last:]))
+		if trail := body[len(trimmed):]; trail != "" {
+			b.WriteString(`{"` + trail + `"}`)
+		}
+		if b.Len() == 0 {
+			b.WriteString(`{""}`)
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n    ")
+}
+
+// loadExistingFluent parses an existing Fluent file into a map of keys with their
+// values. Attributes, and aliases referencing another message, are derived
+// from the protos and skipped. Terms and
+// placeables other than variables and string literals cannot be represented
+// and are rejected.
+func loadExistingFluent(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open Fluent file: %w", err)
+	}
+
+	var (
+		key     string   // key of the current message
+		lines   []string // pattern lines of the current message
+		blanks  int      // blank lines not yet known to be part of the pattern
+		inValue bool     // continuation lines belong to the value, not an attribute
+		named   string   // key named by a "# key:" comment
+		alias   bool     // the current message is an alias, skipped
+	)
+	flush := func() {
+		if key != "" {
+			if len(lines) > 0 && lines[0] == "" {
+				lines = lines[1:] // pattern starts on the next line
+			}
+			entries[key] = textutil.Escape(strings.Join(lines, "\n"))
+		}
+		key, lines, blanks, inValue, alias = "", nil, 0, false, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		switch {
+		case line == "":
+			blanks++
+		case strings.HasPrefix(line, "#"):
+			flush()
+			if m := fluentKeyCommentRe.FindStringSubmatch(line); m != nil {
+				named = m[1]
+			}
+		case strings.HasPrefix(line, " "):
+			if alias {
+				continue
+			}
+			if key == "" {
+				return nil, fmt.Errorf("%s:%d: indented line outside of a message", filePath, lineNum)
+			}
+			if fluentAttributeRe.MatchString(line) {
+				inValue = false
+				continue
+			}
+			if !inValue {
+				continue // continuation of an attribute
+			}
+			text, err := fluentText(strings.TrimLeft(line, " "))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filePath, lineNum, err)
+			}
+			for ; blanks > 0; blanks-- {
+				lines = append(lines, "")
+			}
+			lines = append(lines, text)
+		default:
+			flush()
+			m := fluentMessageRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("%s:%d: unrecognized line: %s", filePath, lineNum, line)
+			}
+			if strings.HasPrefix(m[1], "-") {
+				return nil, fmt.Errorf("%s:%d: unsupported term %s", filePath, lineNum, m[1])
+			}
+			if fluentReferenceRe.MatchString(m[2]) {
+				alias, named = true, ""
+				continue // derived from the aliased message
+			}
+			text, err := fluentText(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filePath, lineNum, err)
+			}
+			key, lines, inValue = m[1], []string{text}, true
+			if named != "" && fluentID(named) == m[1] {
+				key = named
+			}
+			named = ""
+		}
+		if line != "" {
+			blanks = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read Fluent file: %w", err)
+	}
+	flush()
+	return entries, nil
+}
+
+// fluentText returns the plain text of a line of a pattern, with variables
+// turned back into {{.Name}} placeholders and string literals unquoted.
+func fluentText(line string) (string, error) {
+	var b strings.Builder
+	for line != "" {
+		open := strings.IndexByte(line, '{')
+		if end := strings.IndexByte(line, '}'); end >= 0 && (open < 0 || end < open) {
+			return "", fmt.Errorf("unexpected } in %q", line)
+		}
+		if open < 0 {
+			b.WriteString(line)
+			break
+		}
+		b.WriteString(line[:open])
+		inner := strings.TrimLeft(line[open+1:], " ")
+		var rest string
+		switch {
+		case strings.HasPrefix(inner, "\""):
+			end := yaml.ClosingQuote(inner)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated string literal in %q", line)
+			}
+			b.WriteString(textutil.Unescape(inner[1:end]))
+			rest = inner[end+1:]
+		case strings.HasPrefix(inner, "$"):
+			rest = strings.TrimLeft(inner[1:], "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-")
+			name := inner[1 : len(inner)-len(rest)]
+			if name == "" {
+				return "", fmt.Errorf("variable without a name in %q", line)
+			}
+			b.WriteString("{{." + name + "}}")
+		default:
+			return "", fmt.Errorf("unsupported placeable in %q", line)
+		}
+		rest = strings.TrimLeft(rest, " ")
+		if !strings.HasPrefix(rest, "}") {
+			return "", fmt.Errorf("unsupported placeable in %q", line)
+		}
+		line = rest[1:]
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,174 @@
+// Package emit writes the entries extracted from proto files to the language
+// files of every supported format, keeping the translations and comments of
+// existing files, and reads those files back.
+package emit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Format describes how the language files of one format are named, written
+// and read back.
+type Format struct {
+	// Path returns the file of a language below the output directory.
+	Path func(outputDir, lang string) string
+	// Generate creates or updates a language file, keeping existing translations.
+	Generate func(entries []extract.Entry, lang, filePath string) error
+	// Load reads the translations of an existing language file by key.
+	Load func(filePath string) (map[string]string, error)
+	// DefaultPath, if set, returns the file of the default resources below the
+	// output directory, a copy of the file of the default language.
+	DefaultPath func(outputDir string) string
+	// EscapeRune, if set, escapes a non-ASCII character in the syntax of the
+	// format, for the ascii encoding.
+	EscapeRune func(r rune) string
+	// Comment, if set, returns a line of text as a comment line of the format,
+	// for the header. Formats without comments have no header.
+	Comment func(text string) string
+	// Collisions, if set, returns a CollisionError for the entries whose keys
+	// collide with others in the structure of the format, such as a key nested
+	// in the namespace of a key with a value. Write fails with it.
+	Collisions func(entries []extract.Entry, lang string) error
+}
+
+// CollisionError lists the keys of a language file that collide with others
+// in the structure of its format, which cannot be written alongside them.
+type CollisionError struct {
+	Keys   []string // the colliding keys, in the order of the entries
+	Reason string   // how they collide, e.g. "collide with the namespace of another key"
+}
+
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("%d key(s) %s: %s", len(e.Keys), e.Reason, strings.Join(e.Keys, ", "))
+}
+
+// collisionError returns a CollisionError for the keys, or nil when there are
+// none.
+func collisionError(keys []string, reason string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &CollisionError{Keys: keys, Reason: reason}
+}
+
+// Formats lists the supported output formats by name, with the emitters added
+// by Register.
+var Formats = map[string]Format{
+	"jsonc":       {Path: extPath(".jsonc"), Generate: withoutLang(generateJSONC), Load: loadExistingJSONC, EscapeRune: escapeJSONRune, Comment: lineComment("//")},
+	"resx":        {Path: resxPath, Generate: withoutLang(generateResx), Load: loadExistingResx, EscapeRune: escapeXMLRune, Comment: xmlComment},
+	"ts":          {Path: extPath(".ts"), Generate: generateQtTS, Load: loadExistingQtTS, EscapeRune: escapeXMLRune, Comment: xmlComment},
+	"po":          {Path: extPath(".po"), Generate: generatePO, Load: loadExistingPO, Comment: lineComment("#")},
+	"fluent":      {Path: extPath(".ftl"), Generate: withoutLang(generateFluent), Load: loadExistingFluent, Comment: lineComment("###")},
+	"android":     {Path: androidPath, Generate: withoutLang(generateAndroid), Load: loadExistingAndroid, DefaultPath: androidDefaultPath, EscapeRune: escapeXMLRune, Comment: xmlComment, Collisions: androidCollisions},
+	"ios":         {Path: iosPath, Generate: generateIOS, Load: loadExistingStrings, EscapeRune: escapeStringsRune, Comment: lineComment("//")},
+	"i18next":     {Path: extPath(".json"), Generate: withoutLang(generateI18next), Load: loadExistingI18next, EscapeRune: escapeJSONRune, Collisions: nestedCollisions},
+	"i18next-ns":  {Path: i18nextNamespacePath, Generate: generateI18nextNamespace, Load: loadExistingI18nextNamespace, EscapeRune: escapeJSONRune, Collisions: i18nextNamespaceCollisions},
+	"yaml":        {Path: extPath(".yaml"), Generate: withoutLang(generateYAML), Load: loadYAML, EscapeRune: escapeUnicodeRune, Comment: lineComment("#")},
+	"yaml-nested": {Path: extPath(".yml"), Generate: generateRailsYAML, Load: loadExistingRailsYAML, EscapeRune: escapeUnicodeRune, Comment: lineComment("#"), Collisions: nestedCollisions},
+}
+
+// extPath returns a path function naming language files <lang><ext>.
+func extPath(ext string) func(outputDir, lang string) string {
+	return func(outputDir, lang string) string {
+		return filepath.Join(outputDir, lang+ext)
+	}
+}
+
+// lineComment returns a comment function writing lines after a marker.
+func lineComment(marker string) func(text string) string {
+	return func(text string) string {
+		return marker + " " + text
+	}
+}
+
+// xmlComment returns a line of text as an XML comment.
+func xmlComment(text string) string {
+	return "<!-- " + text + " -->"
+}
+
+// withoutLang adapts a generator whose output does not depend on the language.
+func withoutLang(generate func(entries []extract.Entry, filePath string) error) func(entries []extract.Entry, lang, filePath string) error {
+	return func(entries []extract.Entry, _, filePath string) error {
+		return generate(entries, filePath)
+	}
+}
+
+// EntryValue returns the value a writer writes for an entry: its translation in
+// the existing file, or its fallback when there is none or it is an alias.
+func EntryValue(existing map[string]string, entry extract.Entry) string {
+	if value := existing[entry.Key]; value != "" && entry.Alias == "" {
+		return value
+	}
+	return entry.Fallback
+}
+
+// idNote returns the stable numeric id of an entry as written after its
+// location in the comments of the formats that have them, or an empty string
+// when it has none.
+func idNote(entry extract.Entry) string {
+	if entry.ID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (id %d)", entry.ID)
+}
+
+// Write generates a language file, from scratch when fresh is set, in the
+// given encoding, unless keys collide in the format. The header, if set and the format has comments, replaces
+// that of the existing file, which is otherwise kept as is, and so do CRLF
+// line endings.
+func (f Format) Write(entries []extract.Entry, lang, filePath string, fresh bool, enc Encoding, header *Header) error {
+	if f.Collisions != nil {
+		if err := f.Collisions(entries, lang); err != nil {
+			return err
+		}
+	}
+	existing, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var block []string
+	if err == nil {
+		if block, err = stripHeader(filePath, fresh); err != nil {
+			return err
+		}
+	}
+	if header != nil && f.Comment != nil {
+		block = block[:0]
+		for _, line := range header.lines() {
+			block = append(block, f.Comment(line))
+		}
+	}
+	if err := f.Generate(entries, lang, filePath); err != nil {
+		return err
+	}
+	if len(block) > 0 {
+		if err := writeHeader(filePath, block); err != nil {
+			return err
+		}
+	}
+	if existing, _, err = textutil.Decode(existing); err != nil {
+		return err
+	}
+	crlf := bytes.Contains(existing, []byte("\r\n"))
+	if !crlf && enc == (Encoding{}) {
+		return nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if crlf {
+		data = bytes.ReplaceAll(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+	}
+	if data, err = enc.encode(data, f.EscapeRune); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
@@ -0,0 +1,219 @@
+package emit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+)
+
+// headerBegin and headerEnd delimit the metadata block at the top of a
+// language file, whatever the comment syntax of its format.
+const (
+	headerBegin = "i18n-gen:meta"
+	headerEnd   = "i18n-gen:end"
+)
+
+// Header is the metadata table written in comments at the top of a language
+// file, for runtimes and dashboards that read it without scanning the whole
+// file. Formats without comments, such as i18next, have none:
+//
+//	# i18n-gen:meta
+//	# | field     | value                |
+//	# |-----------|----------------------|
+//	# | language  | zh                   |
+//	# | plurals   | other                |
+//	# | coverage  | 87.5% (7/8 keys)     |
+//	# | generated | 2026-01-02T15:04:05Z |
+//	# i18n-gen:end
+type Header struct {
+	Language   string    // language tag
+	Plurals    []string  // CLDR plural categories of the language
+	Translated int       // number of the keys translated
+	Keys       int       // number of the keys, aliases left out
+	Generated  time.Time // generation time, left out when zero
+}
+
+// NewHeader returns the header of a language file with translated of its keys
+// translated, with the plural categories of the language.
+func NewHeader(lang string, translated, keys int, generated time.Time) *Header {
+	return &Header{Language: lang, Plurals: pluralCategories(lang), Translated: translated, Keys: keys, Generated: generated}
+}
+
+// Coverage returns the percentage of the keys translated, 100 without keys.
+func (h Header) Coverage() float64 {
+	if h.Keys == 0 {
+		return 100
+	}
+	return float64(h.Translated) * 100 / float64(h.Keys)
+}
+
+// lines returns the lines of the header, delimiters included: a table of its
+// fields, aligned to be read as is.
+func (h *Header) lines() []string {
+	rows := [][2]string{
+		{"field", "value"},
+		{"language", h.Language},
+		{"plurals", strings.Join(h.Plurals, ",")},
+		{"coverage", fmt.Sprintf("%s%% (%d/%d keys)", strconv.FormatFloat(h.Coverage(), 'f', 1, 64), h.Translated, h.Keys)},
+	}
+	if !h.Generated.IsZero() {
+		rows = append(rows, [2]string{"generated", h.Generated.UTC().Format(time.RFC3339)})
+	}
+	var widths [2]int
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	lines := []string{headerBegin}
+	for i, row := range rows {
+		lines = append(lines, fmt.Sprintf("| %-*s | %-*s |", widths[0], row[0], widths[1], row[1]))
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat("-", widths[0]+2)+"|"+strings.Repeat("-", widths[1]+2)+"|")
+		}
+	}
+	return append(lines, headerEnd)
+}
+
+// headerCoverageRe matches the coverage of a header, with the counts of keys
+// it was computed from.
+var headerCoverageRe = regexp.MustCompile(`^([0-9.]+)% \((\d+)/(\d+) keys\)$`)
+
+// ReadHeader reads the header of a language file, reporting whether it has
+// one.
+func ReadHeader(filePath string) (Header, bool, error) {
+	data, _, err := textutil.ReadFile(filePath)
+	if err != nil {
+		return Header{}, false, err
+	}
+	block, _, ok := splitHeader(data)
+	if !ok {
+		return Header{}, false, nil
+	}
+	var h Header
+	for _, line := range block {
+		name, value, ok := headerField(headerText(line))
+		if !ok {
+			continue
+		}
+		switch name {
+		case "language":
+			h.Language = value
+		case "plurals":
+			h.Plurals = strings.Split(value, ",")
+		case "coverage":
+			m := headerCoverageRe.FindStringSubmatch(value)
+			if m == nil {
+				return Header{}, false, fmt.Errorf("%s: header coverage: invalid %q", filePath, value)
+			}
+			h.Translated, _ = strconv.Atoi(m[2])
+			h.Keys, _ = strconv.Atoi(m[3])
+		case "generated":
+			if h.Generated, err = time.Parse(time.RFC3339, value); err != nil {
+				return Header{}, false, fmt.Errorf("%s: header generation time: %w", filePath, err)
+			}
+		}
+	}
+	return h, true, nil
+}
+
+// headerField returns the field and value of a row of the header table.
+func headerField(text string) (string, string, bool) {
+	cells := strings.Split(strings.TrimSpace(text), "|")
+	if len(cells) != 4 || cells[0] != "" || cells[3] != "" {
+		return "", "", false
+	}
+	return strings.TrimSpace(cells[1]), strings.TrimSpace(cells[2]), true
+}
+
+// headerText returns the text of a comment line of the header, without the
+// comment syntax of its format.
+func headerText(line string) string {
+	line = strings.TrimSpace(line)
+	for _, affix := range [][2]string{{"<!--", "-->"}, {"/*", "*/"}, {"//", ""}} {
+		if rest, ok := strings.CutPrefix(line, affix[0]); ok {
+			return strings.TrimSpace(strings.TrimSuffix(rest, affix[1]))
+		}
+	}
+	return strings.TrimSpace(strings.TrimLeft(line, "#"))
+}
+
+// splitHeader returns the lines of the header at the top of a language file,
+// after the XML declaration if any, and the file without it, reporting
+// whether there is one. The blank line separating the header from the rest of
+// the file goes with it.
+func splitHeader(data []byte) ([]string, []byte, bool) {
+	var declaration []byte
+	rest := data
+	if bytes.HasPrefix(rest, []byte("<?xml")) {
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			declaration, rest = rest[:i+1], rest[i+1:]
+		}
+	}
+	first, _, _ := bytes.Cut(rest, []byte("\n"))
+	if headerText(string(first)) != headerBegin {
+		return nil, data, false
+	}
+	var block []string
+	for len(rest) > 0 {
+		line, next, _ := bytes.Cut(rest, []byte("\n"))
+		block, rest = append(block, strings.TrimRight(string(line), "\r")), next
+		if headerText(string(line)) == headerEnd {
+			break
+		}
+	}
+	if line, next, _ := bytes.Cut(rest, []byte("\n")); len(bytes.TrimSpace(line)) == 0 && len(rest) > 0 {
+		rest = next
+	}
+	return block, append(declaration, rest...), true
+}
+
+// stripHeader removes the header from an existing language file, so that the
+// generators do not take its comments for those of the first key, or the
+// whole file when fresh is set, and returns its lines.
+func stripHeader(filePath string, fresh bool) ([]string, error) {
+	data, _, err := textutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	block, rest, ok := splitHeader(data)
+	switch {
+	case fresh:
+		return block, os.Remove(filePath)
+	case ok:
+		return block, os.WriteFile(filePath, rest, 0644)
+	}
+	return nil, nil
+}
+
+// writeHeader adds the comment lines of a header at the top of a generated
+// language file, after the XML declaration if any.
+func writeHeader(filePath string, block []string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var declaration []byte
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			declaration, data = data[:i+1], data[i+1:]
+		}
+	}
+	var buffer bytes.Buffer
+	buffer.Write(declaration)
+	for _, line := range block {
+		buffer.WriteString(line)
+		buffer.WriteString("\n")
+	}
+	if declaration == nil {
+		buffer.WriteString("\n")
+	}
+	buffer.Write(data)
+	return os.WriteFile(filePath, buffer.Bytes(), 0644)
+}
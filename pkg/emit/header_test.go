@@ -0,0 +1,48 @@
+package emit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	entries := []extract.Entry{{Key: "ERR_A", Name: "ERR_A", Kind: extract.KindEnum, Path: "ErrorReason", File: "errors.proto", Line: 3, Fallback: "A"}}
+	for _, name := range []string{"toml", "po", "android", "yaml"} {
+		t.Run(name, func(t *testing.T) {
+			format := Formats[name]
+			header := NewHeader("fr", 7, 8, time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC))
+			filePath := format.Path(t.TempDir(), "fr")
+			if err := format.Write(entries, "fr", filePath, true, Encoding{}, header); err != nil {
+				t.Fatal(err)
+			}
+			got, ok, err := ReadHeader(filePath)
+			if err != nil || !ok {
+				t.Fatalf("ReadHeader = %v, %v", ok, err)
+			}
+			if !reflect.DeepEqual(got, *header) {
+				t.Errorf("ReadHeader = %+v, want %+v", got, *header)
+			}
+			if got.Coverage() != 87.5 {
+				t.Errorf("Coverage = %v, want 87.5", got.Coverage())
+			}
+		})
+	}
+}
+
+func TestHeaderLines(t *testing.T) {
+	want := []string{
+		"i18n-gen:meta",
+		"| field    | value            |",
+		"|----------|------------------|",
+		"| language | zh               |",
+		"| plurals  | other            |",
+		"| coverage | 50.0% (3/6 keys) |",
+		"i18n-gen:end",
+	}
+	if got := NewHeader("zh", 3, 6, time.Time{}).lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("lines =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package emit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// generateI18next updates or creates an i18next JSON file nested by the dot
+// separated namespaces of each key, failing when keys collide with the
+// namespace of another.
+func generateI18next(entries []extract.Entry, filePath string) error {
+	existingEntries, err := loadExistingI18next(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing JSON: %w", err)
+	}
+
+	tree := newKeyTree()
+	var collisions []string
+	for _, entry := range entries {
+		value := EntryValue(existingEntries, entry)
+		if entry.Reference {
+			value = textutil.Escape("$t(" + entry.Alias + ")")
+		}
+		if !tree.set(strings.Split(entry.Key, "."), value) {
+			collisions = append(collisions, entry.Key)
+		}
+	}
+	if err := collisionError(collisions, nestedReason); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	writeJSONTree(&buffer, tree, 0)
+	buffer.WriteString("\n")
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write JSON file: %w", err)
+	}
+	return nil
+}
+
+// writeJSONTree appends the tree as a JSON object at the given indentation level.
+func writeJSONTree(buffer *bytes.Buffer, tree *keyTree, indent int) {
+	prefix := strings.Repeat("  ", indent+1)
+	buffer.WriteString("{\n")
+	for i, key := range tree.keys {
+		buffer.WriteString(fmt.Sprintf("%s\"%s\": ", prefix, textutil.Escape(key)))
+		if child, ok := tree.children[key]; ok {
+			writeJSONTree(buffer, child, indent+1)
+		} else {
+			buffer.WriteString(fmt.Sprintf("\"%s\"", textutil.Normalize(tree.values[key])))
+		}
+		if i < len(tree.keys)-1 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString(strings.Repeat("  ", indent) + "}")
+}
+
+// loadExistingI18next parses an existing nested JSON file into a map of dot joined
+// keys with their values, kept as they appear between the quotes.
+func loadExistingI18next(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read JSON file: %w", err)
+	}
+
+	if err := flattenJSON(data, "", entries); err != nil {
+		return nil, fmt.Errorf("parse JSON file: %w", err)
+	}
+	return entries, nil
+}
+
+// flattenJSON adds the string values of a nested JSON object to entries, keyed by
+// their dot joined path below prefix.
+func flattenJSON(data []byte, prefix string, entries map[string]string) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	for key, raw := range object {
+		value := strings.TrimSpace(string(raw))
+		switch {
+		case strings.HasPrefix(value, "{"):
+			if err := flattenJSON(raw, prefix+key+".", entries); err != nil {
+				return err
+			}
+		case strings.HasPrefix(value, "\""):
+			entries[prefix+key] = value[1 : len(value)-1]
+		}
+	}
+	return nil
+}
+
+// TypeScriptKeys writes a TypeScript union type of all keys.
+func TypeScriptKeys(entries []extract.Entry, filePath string) error {
+	var buffer bytes.Buffer
+	buffer.WriteString("// Code generated by i18n-gen. DO NOT EDIT.\n\nexport type TranslationKey =\n")
+	for i, entry := range entries {
+		buffer.WriteString(fmt.Sprintf("  | \"%s\"", textutil.Escape(entry.Key)))
+		if i == len(entries)-1 {
+			buffer.WriteString(";")
+		}
+		buffer.WriteString("\n")
+	}
+
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write TypeScript file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package emit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// i18nextNamespace is the i18next namespace, and file name, of the catalog.
+const i18nextNamespace = "errors"
+
+// i18nextPluralSuffixRe matches the suffix of a plural form of the i18next v3
+// JSON format: _plural, or the index of the form for languages with more than
+// two.
+var i18nextPluralSuffixRe = regexp.MustCompile(`_(plural|\d+)$`)
+
+// i18nextNamespacePath names the language files <lang>/errors.json.
+func i18nextNamespacePath(outputDir, lang string) string {
+	return filepath.Join(outputDir, lang, i18nextNamespace+".json")
+}
+
+// i18nextInterpolation replaces go-i18n placeholders with i18next
+// interpolations, {{.Name}} with {{Name}} and the plural count with {{count}}.
+// Brace style placeholders are kept, as {name} is also what {{name}} contains.
+func i18nextInterpolation(text string) string {
+	return namedPlaceholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		name := namedPlaceholderRe.FindStringSubmatch(match)[1]
+		switch name {
+		case "":
+			return match
+		case "Count", "PluralCount":
+			name = "count"
+		}
+		return "{{" + name + "}}"
+	})
+}
+
+// i18nextPluralKeys returns the keys of the plural forms of a key in the i18next
+// v3 JSON format for the plural categories of a language, in their order: the
+// key alone for languages without plural forms, the key and key_plural for
+// languages with one and other, and key_0, key_1, ... for the others.
+func i18nextPluralKeys(key string, categories []string) []string {
+	switch len(categories) {
+	case 1:
+		return []string{key}
+	case 2:
+		return []string{key, key + "_plural"}
+	}
+	keys := make([]string, len(categories))
+	for i := range categories {
+		keys[i] = key + "_" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+// i18nextNamespaceReason is how keys collide in the i18next namespace files.
+const i18nextNamespaceReason = "collide with another key, group or package"
+
+// i18nextObjectPath returns the path of the object holding the key of an
+// entry in an i18next namespace file: its dot separated i18n-group, or else
+// proto package.
+func i18nextObjectPath(entry extract.Entry) []string {
+	switch {
+	case entry.Group != "":
+		return strings.Split(entry.Group, ".")
+	case entry.Package != "":
+		return strings.Split(entry.Package, ".")
+	}
+	return nil
+}
+
+// i18nextNamespaceCollisions returns a CollisionError for the keys, or plural
+// form keys, that collide with another key, or with a group or package holding
+// keys, once nested below their group or package.
+func i18nextNamespaceCollisions(entries []extract.Entry, lang string) error {
+	categories := pluralCategories(lang)
+	tree := newKeyTree()
+	var keys []string
+	for _, entry := range entries {
+		path := i18nextObjectPath(entry)
+		formKeys := []string{entry.Key}
+		if isPlural(entry, nil) {
+			formKeys = i18nextPluralKeys(entry.Key, categories)
+		}
+		for _, key := range formKeys {
+			if !tree.set(append(path[:len(path):len(path)], key), "") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return collisionError(keys, i18nextNamespaceReason)
+}
+
+// generateI18nextNamespace updates or creates the i18next namespace file of a
+// language, nested by the dot separated i18n-group, or else proto package, of
+// each key, with the keys themselves kept whole. Placeholders become i18next interpolations, and
+// plural messages get a key per plural form of the language. Keys colliding
+// with another key, group or package fail it.
+func generateI18nextNamespace(entries []extract.Entry, lang, filePath string) error {
+	existingEntries, err := loadI18nextLeaves(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing JSON: %w", err)
+	}
+	resolved := resolveI18nextPlurals(existingEntries)
+	categories := pluralCategories(lang)
+
+	tree := newKeyTree()
+	var collisions []string
+	for _, entry := range entries {
+		path := i18nextObjectPath(entry)
+		value := textutil.Normalize(i18nextInterpolation(textutil.Unescape(EntryValue(resolved, entry))))
+		if entry.Reference {
+			value = textutil.Escape("$t(" + strings.Join(append(path, entry.Alias), ".") + ")")
+		}
+
+		// Existing plural forms keep a message plural
+		keys := []string{entry.Key}
+		plural := isPlural(entry, nil)
+		for _, key := range i18nextPluralKeys(entry.Key, categories) {
+			if _, ok := existingEntries[key]; ok && key != entry.Key && entry.Alias == "" {
+				plural = true
+			}
+		}
+		if plural {
+			keys = i18nextPluralKeys(entry.Key, categories)
+		}
+		for _, key := range keys {
+			formValue := value
+			if existing := existingEntries[key]; existing != "" && key != keys[len(keys)-1] && entry.Alias == "" {
+				formValue = existing
+			}
+			if !tree.set(append(path[:len(path):len(path)], key), formValue) {
+				collisions = append(collisions, key)
+			}
+		}
+	}
+	if err := collisionError(collisions, i18nextNamespaceReason); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	writeJSONTree(&buffer, tree, 0)
+	buffer.WriteString("\n")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("create language directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write JSON file: %w", err)
+	}
+	return nil
+}
+
+// loadExistingI18nextNamespace parses an existing i18next namespace file into a
+// map of keys with their values, the last plural form for plural messages.
+func loadExistingI18nextNamespace(filePath string) (map[string]string, error) {
+	leaves, err := loadI18nextLeaves(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return resolveI18nextPlurals(leaves), nil
+}
+
+// resolveI18nextPlurals maps the plural forms of a key to the key, keeping the
+// last form: key_plural, or the highest numbered one. Keys ending in _plural
+// without the key itself, or in _1, _2, ... without key_0, are no forms.
+func resolveI18nextPlurals(leaves map[string]string) map[string]string {
+	entries := make(map[string]string)
+	plurals := make(map[string]string)
+	index := make(map[string]int)
+	for key, value := range leaves {
+		if m := i18nextPluralSuffixRe.FindStringSubmatch(key); m != nil {
+			base := key[:len(key)-len(m[0])]
+			_, hasBase := leaves[base]
+			_, hasFirst := leaves[base+"_0"]
+			if m[1] == "plural" && hasBase || m[1] != "plural" && hasFirst {
+				n, _ := strconv.Atoi(m[1]) // _plural is the second form
+				if m[1] == "plural" {
+					n = 1
+				}
+				if i, ok := index[base]; !ok || n > i {
+					index[base] = n
+					plurals[base] = value
+				}
+				continue
+			}
+		}
+		entries[key] = value
+	}
+	for key, value := range plurals {
+		entries[key] = value
+	}
+	return entries
+}
+
+// loadI18nextLeaves returns the string values of a nested JSON file by the
+// name of their innermost property, kept as they appear between the quotes.
+func loadI18nextLeaves(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read JSON file: %w", err)
+	}
+
+	var collect func(data []byte) error
+	collect = func(data []byte) error {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		for key, raw := range object {
+			value := strings.TrimSpace(string(raw))
+			switch {
+			case strings.HasPrefix(value, "{"):
+				if err := collect(raw); err != nil {
+					return err
+				}
+			case strings.HasPrefix(value, "\""):
+				entries[key] = value[1 : len(value)-1]
+			}
+		}
+		return nil
+	}
+	if err := collect(data); err != nil {
+		return nil, fmt.Errorf("parse JSON file: %w", err)
+	}
+	return entries, nil
+}
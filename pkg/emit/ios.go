@@ -0,0 +1,291 @@
+package emit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/yaml"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// stringsdictHeader starts a .stringsdict property list.
+const stringsdictHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`
+
+// countFormatRe matches the positional format argument of the plural count.
+var countFormatRe = regexp.MustCompile(`%(\d+)\$d`)
+
+// iosPath names the language files <lang>.lproj/Localizable.strings.
+func iosPath(outputDir, lang string) string {
+	return filepath.Join(outputDir, strings.ReplaceAll(lang, "_", "-")+".lproj", "Localizable.strings")
+}
+
+// stringsdictPath returns the .stringsdict file next to a .strings file.
+func stringsdictPath(stringsPath string) string {
+	return strings.TrimSuffix(stringsPath, ".strings") + ".stringsdict"
+}
+
+// iosFormat replaces named placeholders with positional format arguments
+// numbered in order of first appearance: %1$d for the plural count, %1$@ for
+// the others.
+func iosFormat(text string) string {
+	return numberPlaceholders(text, func(name string, index int) string {
+		if name == "Count" || name == "PluralCount" {
+			return "%" + strconv.Itoa(index+1) + "$d"
+		}
+		return "%" + strconv.Itoa(index+1) + "$@"
+	})
+}
+
+// generateIOS updates or creates an Apple Localizable.strings file, and for
+// plural messages the Localizable.stringsdict next to it, with the plural
+// categories of the language. Named placeholders become positional format
+// arguments. Plural forms are only kept while the .strings file exists, so
+// regenerating it from scratch also resets them.
+func generateIOS(entries []extract.Entry, lang, filePath string) error {
+	existingEntries, err := loadExistingStrings(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing strings: %w", err)
+	}
+	dictPath := stringsdictPath(filePath)
+	existingForms := make(map[string]map[string]string)
+	if len(existingEntries) > 0 {
+		if existingForms, err = loadStringsdict(dictPath); err != nil {
+			return fmt.Errorf("load existing stringsdict: %w", err)
+		}
+	}
+	categories := pluralCategories(lang)
+
+	var buffer, dict bytes.Buffer
+	for _, entry := range entries {
+		value := EntryValue(existingEntries, entry)
+		comment := fmt.Sprintf("%s:%d %s%s", entry.File, entry.Line, entry.Path, idNote(entry))
+		if entry.Comment != "" {
+			comment += "\n   " + strings.ReplaceAll(entry.Comment, "\n", "\n   ")
+		}
+		buffer.WriteString(fmt.Sprintf("/* %s */\n", strings.ReplaceAll(comment, "*/", "* /")))
+		other := iosFormat(textutil.Unescape(value))
+		buffer.WriteString(fmt.Sprintf("\"%s\" = \"%s\";\n\n", textutil.EscapeStrings(entry.Key), textutil.EscapeStrings(other)))
+
+		forms := existingForms[entry.Key]
+		if entry.Alias != "" {
+			forms = nil
+		}
+		if !isPlural(entry, forms) {
+			continue
+		}
+		count := "1"
+		if m := countFormatRe.FindStringSubmatch(iosFormat(textutil.Unescape(entry.Fallback))); m != nil {
+			count = m[1]
+		}
+		dict.WriteString(fmt.Sprintf("\t<key>%s</key>\n\t<dict>\n", textutil.EscapeXML(entry.Key)))
+		dict.WriteString(fmt.Sprintf("\t\t<key>NSStringLocalizedFormatKey</key>\n\t\t<string>%%%s$#@count@</string>\n", count))
+		dict.WriteString("\t\t<key>count</key>\n\t\t<dict>\n")
+		dict.WriteString("\t\t\t<key>NSStringFormatSpecTypeKey</key>\n\t\t\t<string>NSStringPluralRuleType</string>\n")
+		dict.WriteString("\t\t\t<key>NSStringFormatValueTypeKey</key>\n\t\t\t<string>d</string>\n")
+		for _, form := range pluralForms {
+			text, ok := forms[form]
+			switch {
+			case form == "other":
+				text = other
+			case !ok && !slices.Contains(categories, form):
+				continue
+			case text == "":
+				text = other
+			default:
+				text = iosFormat(textutil.Unescape(text))
+			}
+			dict.WriteString(fmt.Sprintf("\t\t\t<key>%s</key>\n\t\t\t<string>%s</string>\n", form, textutil.EscapeXML(text)))
+		}
+		dict.WriteString("\t\t</dict>\n\t</dict>\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("create lproj directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write strings: %w", err)
+	}
+	if dict.Len() == 0 {
+		if err := os.Remove(dictPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove stringsdict: %w", err)
+		}
+		return nil
+	}
+	content := stringsdictHeader + dict.String() + "</dict>\n</plist>\n"
+	if err := os.WriteFile(dictPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("write stringsdict: %w", err)
+	}
+	return nil
+}
+
+// loadExistingStrings parses an existing .strings file into a map of keys with
+// their values. Besides "key" = "value"; pairs it only accepts comments.
+func loadExistingStrings(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read strings: %w", err)
+	}
+
+	s := string(data)
+	i, lineNum := 0, 1
+	skip := func() error {
+		for i < len(s) {
+			switch {
+			case s[i] == '\n':
+				lineNum++
+				i++
+			case s[i] == ' ' || s[i] == '\t' || s[i] == '\r':
+				i++
+			case strings.HasPrefix(s[i:], "/*"):
+				end := strings.Index(s[i+2:], "*/")
+				if end < 0 {
+					return fmt.Errorf("%s:%d: unterminated comment", filePath, lineNum)
+				}
+				lineNum += strings.Count(s[i:i+2+end], "\n")
+				i += end + 4
+			case strings.HasPrefix(s[i:], "//"):
+				if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+					i += end
+				} else {
+					i = len(s)
+				}
+			default:
+				return nil
+			}
+		}
+		return nil
+	}
+	expect := func(what string, c byte) error {
+		if err := skip(); err != nil {
+			return err
+		}
+		if i == len(s) || s[i] != c {
+			return fmt.Errorf("%s:%d: expected %s", filePath, lineNum, what)
+		}
+		i++
+		return nil
+	}
+	quoted := func(what string) (string, error) {
+		if err := skip(); err != nil {
+			return "", err
+		}
+		if i == len(s) || s[i] != '"' {
+			return "", fmt.Errorf("%s:%d: expected %s", filePath, lineNum, what)
+		}
+		end := yaml.ClosingQuote(s[i:])
+		if end < 0 {
+			return "", fmt.Errorf("%s:%d: unterminated string", filePath, lineNum)
+		}
+		text := s[i+1 : i+end]
+		lineNum += strings.Count(text, "\n")
+		i += end + 1
+		return textutil.UnescapeStrings(text), nil
+	}
+
+	for {
+		if err := skip(); err != nil {
+			return nil, err
+		}
+		if i == len(s) {
+			break
+		}
+		key, err := quoted("a quoted key")
+		if err != nil {
+			return nil, err
+		}
+		if err := expect("=", '='); err != nil {
+			return nil, err
+		}
+		value, err := quoted("a quoted value")
+		if err != nil {
+			return nil, err
+		}
+		if err := expect(";", ';'); err != nil {
+			return nil, err
+		}
+		entries[key] = textutil.Escape(value)
+	}
+	return entries, nil
+}
+
+// plistNode is an element of a property list.
+type plistNode struct {
+	XMLName xml.Name
+	Text    string      `xml:",chardata"`
+	Nodes   []plistNode `xml:",any"`
+}
+
+// dictValues returns the values of a <dict> by key.
+func (n plistNode) dictValues() map[string]plistNode {
+	values := make(map[string]plistNode)
+	for i := 0; i+1 < len(n.Nodes); i++ {
+		if n.Nodes[i].XMLName.Local == "key" {
+			values[n.Nodes[i].Text] = n.Nodes[i+1]
+			i++
+		}
+	}
+	return values
+}
+
+// loadStringsdict parses an existing .stringsdict file into a map of keys with
+// their values by plural form, taken from the first plural rule variable of
+// each key.
+func loadStringsdict(filePath string) (map[string]map[string]string, error) {
+	entries := make(map[string]map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read stringsdict: %w", err)
+	}
+
+	var root plistNode
+	if err := textutil.UnmarshalXML(data, &root); err != nil {
+		return nil, fmt.Errorf("parse stringsdict: %w", err)
+	}
+	if root.XMLName.Local != "plist" || len(root.Nodes) != 1 || root.Nodes[0].XMLName.Local != "dict" {
+		return nil, fmt.Errorf("parse stringsdict: %s is not a property list of a dictionary", filePath)
+	}
+	for key, message := range root.Nodes[0].dictValues() {
+		variables := message.dictValues()
+		names := make([]string, 0, len(variables))
+		for name := range variables {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			rule := variables[name].dictValues()
+			if rule["NSStringFormatSpecTypeKey"].Text != "NSStringPluralRuleType" {
+				continue
+			}
+			forms := make(map[string]string)
+			for _, form := range pluralForms {
+				if value, ok := rule[form]; ok {
+					forms[form] = textutil.Escape(value.Text)
+				}
+			}
+			entries[key] = forms
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,104 @@
+package emit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// generateJSONC updates or creates a JSON with comments file in which every key
+// is preceded by its source location and proto comment.
+func generateJSONC(entries []extract.Entry, filePath string) error {
+	existingEntries, err := loadExistingJSONC(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing JSONC: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("{\n")
+	for i, entry := range entries {
+		buffer.WriteString(fmt.Sprintf("  // %s:%d %s%s\n", entry.File, entry.Line, entry.Path, idNote(entry)))
+		if entry.Comment != "" {
+			for _, line := range strings.Split(entry.Comment, "\n") {
+				buffer.WriteString("  // " + line + "\n")
+			}
+		}
+		value := EntryValue(existingEntries, entry)
+		buffer.WriteString(fmt.Sprintf("  \"%s\": \"%s\"", textutil.Escape(entry.Key), textutil.Normalize(value)))
+		if i < len(entries)-1 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString("}\n")
+
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write JSONC file: %w", err)
+	}
+	return nil
+}
+
+// loadExistingJSONC parses an existing JSON with comments file into a map of keys
+// with their values. Values are kept as they appear between the quotes, matching
+// loadExistingTOML.
+func loadExistingJSONC(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read JSONC file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(stripJSONComments(data), &raw); err != nil {
+		return nil, fmt.Errorf("parse JSONC file: %w", err)
+	}
+	for key, value := range raw {
+		entries[key] = strings.TrimSuffix(strings.TrimPrefix(string(value), "\""), "\"")
+	}
+	return entries, nil
+}
+
+// stripJSONComments blanks out // and /* */ comments outside of string literals.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			if c == '\\' && i+1 < len(data) {
+				out = append(out, c, data[i+1])
+				i++
+				continue
+			}
+			if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			c = '\n'
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
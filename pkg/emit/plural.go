@@ -0,0 +1,79 @@
+package emit
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"regexp"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// pluralForms lists the CLDR plural categories in the order go-i18n writes them.
+var pluralForms = []string{"zero", "one", "two", "few", "many", "other"}
+
+// pluralFormNames names the plural forms of golang.org/x/text.
+var pluralFormNames = map[plural.Form]string{
+	plural.Zero:  "zero",
+	plural.One:   "one",
+	plural.Two:   "two",
+	plural.Few:   "few",
+	plural.Many:  "many",
+	plural.Other: "other",
+}
+
+// pluralCountRe matches the template actions go-i18n passes the plural count to.
+var pluralCountRe = regexp.MustCompile(`\{\{[^}]*\.(Count|PluralCount)\b`)
+
+// pluralCategories returns the plural categories a language distinguishes, in
+// pluralForms order, found by matching sample integers and decimals against its
+// CLDR rules. Unknown languages only have "other".
+func pluralCategories(lang string) []string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return []string{"other"}
+	}
+	seen := map[string]bool{"other": true}
+	for n := 0; n <= 1000; n++ {
+		seen[pluralFormNames[plural.Cardinal.MatchPlural(tag, n, 0, 0, 0, 0)]] = true
+	}
+	seen[pluralFormNames[plural.Cardinal.MatchPlural(tag, 1000000, 0, 0, 0, 0)]] = true
+	for n := 0; n <= 2; n++ {
+		seen[pluralFormNames[plural.Cardinal.MatchPlural(tag, n, 1, 1, 5, 5)]] = true // n.5
+	}
+
+	var categories []string
+	for _, form := range pluralForms {
+		if seen[form] {
+			categories = append(categories, form)
+		}
+	}
+	return categories
+}
+
+// isPlural reports whether a message needs plural forms: its default message
+// passes a count to go-i18n, or its translation already has forms besides other.
+func isPlural(entry extract.Entry, forms map[string]string) bool {
+	if pluralCountRe.MatchString(textutil.Unescape(entry.Message)) {
+		return true
+	}
+	for form := range forms {
+		if form != "other" {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageHash identifies the source a message was translated from the way
+// go-i18n's merge command does: a SHA-1 of its description and default message.
+func MessageHash(entry extract.Entry) string {
+	h := sha1.New()
+	io.WriteString(h, entry.Comment)
+	io.WriteString(h, textutil.Unescape(entry.Message))
+	return fmt.Sprintf("sha1-%x", h.Sum(nil))
+}
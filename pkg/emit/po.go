@@ -0,0 +1,190 @@
+package emit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/yaml"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// potName is the gettext template written next to the .po files.
+const potName = "messages.pot"
+
+// generatePO updates or creates the gettext catalog of a language, and the
+// template of all messages next to it. Every key is written as the msgctxt of
+// an entry whose msgid is the default message from the proto, or the key when
+// there is none, preceded by the proto comment and source location.
+func generatePO(entries []extract.Entry, lang, filePath string) error {
+	existingEntries, err := loadExistingPO(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing PO: %w", err)
+	}
+
+	po := poCatalog(entries, lang, func(entry extract.Entry) string {
+		return EntryValue(existingEntries, entry)
+	})
+	if err := os.WriteFile(filePath, po, 0644); err != nil {
+		return fmt.Errorf("write PO file: %w", err)
+	}
+
+	pot := poCatalog(entries, "", func(extract.Entry) string { return "" })
+	if err := os.WriteFile(filepath.Join(filepath.Dir(filePath), potName), pot, 0644); err != nil {
+		return fmt.Errorf("write POT file: %w", err)
+	}
+	return nil
+}
+
+// poCatalog renders a PO file, or a template when lang is empty, taking the
+// msgstr of every entry from value.
+func poCatalog(entries []extract.Entry, lang string, value func(extract.Entry) string) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	if lang != "" {
+		buffer.WriteString(fmt.Sprintf("\"Language: %s\\n\"\n", lang))
+	}
+	buffer.WriteString("\"MIME-Version: 1.0\\n\"\n")
+	buffer.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	buffer.WriteString("\"Content-Transfer-Encoding: 8bit\\n\"\n")
+
+	for _, entry := range entries {
+		buffer.WriteString("\n")
+		if entry.Comment != "" {
+			for _, line := range strings.Split(entry.Comment, "\n") {
+				buffer.WriteString(fmt.Sprintf("#. %s\n", line))
+			}
+		}
+		if note := strings.TrimSpace(entry.Path + idNote(entry)); note != "" {
+			buffer.WriteString(fmt.Sprintf("#. %s\n", note))
+		}
+		if entry.File != "" {
+			buffer.WriteString(fmt.Sprintf("#: %s:%d\n", entry.File, entry.Line))
+		}
+		msgid := textutil.Unescape(entry.Message)
+		if msgid == "" {
+			msgid = entry.Key
+		}
+		buffer.WriteString(fmt.Sprintf("msgctxt \"%s\"\n", textutil.EscapePO(entry.Key)))
+		buffer.WriteString(fmt.Sprintf("msgid \"%s\"\n", textutil.EscapePO(msgid)))
+		buffer.WriteString(fmt.Sprintf("msgstr \"%s\"\n", textutil.EscapePO(textutil.Unescape(value(entry)))))
+	}
+	return buffer.Bytes()
+}
+
+// loadExistingPO parses an existing PO file into a map of keys, taken from the
+// msgctxt of each entry, with their msgstr. Entries without a context are not
+// written by this tool and are rejected, as are lines it does not understand.
+func loadExistingPO(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open PO file: %w", err)
+	}
+
+	var (
+		field   string // keyword the current string belongs to
+		context string
+		msgid   string
+		msgstr  string
+		started bool
+	)
+	flush := func(lineNum int) error {
+		if !started {
+			return nil
+		}
+		started = false
+		if context == "" && msgid == "" {
+			return nil // header
+		}
+		if context == "" {
+			return fmt.Errorf("%s:%d: entry %q has no msgctxt", filePath, lineNum, msgid)
+		}
+		entries[textutil.Unescape(context)] = msgstr
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "\"") {
+			value, ok := poString(line)
+			if !ok || field == "" {
+				return nil, fmt.Errorf("%s:%d: unexpected string: %s", filePath, lineNum, line)
+			}
+			switch field {
+			case "msgctxt":
+				context += value
+			case "msgid":
+				msgid += value
+			case "msgstr":
+				msgstr += value
+			}
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		value, ok := poString(strings.TrimSpace(rest))
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unrecognized line: %s", filePath, lineNum, line)
+		}
+		switch keyword {
+		case "msgctxt":
+			if err := flush(lineNum); err != nil {
+				return nil, err
+			}
+			started, context, msgid, msgstr = true, value, "", ""
+		case "msgid":
+			if field != "msgctxt" {
+				if err := flush(lineNum); err != nil {
+					return nil, err
+				}
+				started, context, msgstr = true, "", ""
+			}
+			msgid = value
+		case "msgstr", "msgstr[0]":
+			msgstr = value
+			keyword = "msgstr"
+		case "msgid_plural":
+		default:
+			if !strings.HasPrefix(keyword, "msgstr[") {
+				return nil, fmt.Errorf("%s:%d: unrecognized line: %s", filePath, lineNum, line)
+			}
+			keyword = "msgstr_plural" // further plural forms are not kept
+		}
+		field = keyword
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read PO file: %w", err)
+	}
+	if err := flush(lineNum); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// poString returns the contents of a double-quoted PO string, which uses the
+// same backslash escapes as the internal value form.
+func poString(s string) (string, bool) {
+	if !strings.HasPrefix(s, "\"") {
+		return "", false
+	}
+	end := yaml.ClosingQuote(s)
+	if end != len(s)-1 {
+		return "", false
+	}
+	return s[1:end], true
+}
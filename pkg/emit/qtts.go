@@ -0,0 +1,116 @@
+package emit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// generateQtTS updates or creates a Qt Linguist .ts file. Entries are grouped into
+// contexts named after their source enum or message.
+func generateQtTS(entries []extract.Entry, lang, filePath string) error {
+	existingEntries, err := loadExistingQtTS(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing ts: %w", err)
+	}
+
+	// Group entries by context while maintaining order
+	var contexts []string
+	grouped := make(map[string][]extract.Entry)
+	for _, entry := range entries {
+		context := qtContext(entry)
+		if _, ok := grouped[context]; !ok {
+			contexts = append(contexts, context)
+		}
+		grouped[context] = append(grouped[context], entry)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<!DOCTYPE TS>\n")
+	buffer.WriteString(fmt.Sprintf("<TS version=\"2.1\" language=\"%s\">\n", textutil.EscapeXML(lang)))
+	for _, context := range contexts {
+		buffer.WriteString("<context>\n")
+		buffer.WriteString(fmt.Sprintf("    <name>%s</name>\n", textutil.EscapeXML(context)))
+		for _, entry := range grouped[context] {
+			source := entry.Message
+			if source == "" {
+				source = entry.Key
+			}
+			buffer.WriteString(fmt.Sprintf("    <message id=\"%s\">\n", textutil.EscapeXML(entry.Key)))
+			buffer.WriteString(fmt.Sprintf("        <location filename=\"%s\" line=\"%d\"/>\n", textutil.EscapeXML(entry.File), entry.Line))
+			buffer.WriteString(fmt.Sprintf("        <source>%s</source>\n", textutil.EscapeXML(textutil.Unescape(source))))
+			if entry.Comment != "" {
+				buffer.WriteString(fmt.Sprintf("        <comment>%s</comment>\n", textutil.EscapeXML(entry.Comment)))
+			}
+			value := existingEntries[entry.Key]
+			if entry.Alias != "" {
+				value = entry.Fallback
+			}
+			if value != "" {
+				buffer.WriteString(fmt.Sprintf("        <translation>%s</translation>\n", textutil.EscapeXML(textutil.Unescape(value))))
+			} else {
+				buffer.WriteString("        <translation type=\"unfinished\"></translation>\n")
+			}
+			buffer.WriteString("    </message>\n")
+		}
+		buffer.WriteString("</context>\n")
+	}
+	buffer.WriteString("</TS>\n")
+
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write ts file: %w", err)
+	}
+	return nil
+}
+
+// loadExistingQtTS parses an existing Qt Linguist .ts file into a map of message
+// ids with their translations.
+func loadExistingQtTS(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read ts file: %w", err)
+	}
+
+	var ts struct {
+		Contexts []struct {
+			Messages []struct {
+				ID          string `xml:"id,attr"`
+				Source      string `xml:"source"`
+				Translation string `xml:"translation"`
+			} `xml:"message"`
+		} `xml:"context"`
+	}
+	if err := textutil.UnmarshalXML(data, &ts); err != nil {
+		return nil, fmt.Errorf("parse ts file: %w", err)
+	}
+	for _, context := range ts.Contexts {
+		for _, m := range context.Messages {
+			key := m.ID
+			if key == "" {
+				key = m.Source
+			}
+			entries[key] = textutil.Escape(m.Translation)
+		}
+	}
+	return entries, nil
+}
+
+// qtContext returns the enum name of enum entries and the message name of
+// validation entries and labels.
+func qtContext(entry extract.Entry) string {
+	if entry.Kind == extract.KindCEL || entry.Kind == extract.KindRule || entry.Kind == extract.KindPGV || entry.Kind == extract.KindLabel {
+		if i := strings.LastIndex(entry.Path, "."); i >= 0 {
+			return entry.Path[:i]
+		}
+	}
+	return entry.Path
+}
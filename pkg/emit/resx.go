@@ -0,0 +1,113 @@
+package emit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+const resxHeader = `<?xml version="1.0" encoding="utf-8"?>
+<root>
+  <resheader name="resmimetype">
+    <value>text/microsoft-resx</value>
+  </resheader>
+  <resheader name="version">
+    <value>2.0</value>
+  </resheader>
+  <resheader name="reader">
+    <value>System.Resources.ResXResourceReader, System.Windows.Forms, Version=4.0.0.0, Culture=neutral, PublicKeyToken=b77a5c561934e089</value>
+  </resheader>
+  <resheader name="writer">
+    <value>System.Resources.ResXResourceWriter, System.Windows.Forms, Version=4.0.0.0, Culture=neutral, PublicKeyToken=b77a5c561934e089</value>
+  </resheader>
+`
+
+// namedPlaceholderRe matches go-i18n style {{.Name}} and brace style {name} placeholders.
+var namedPlaceholderRe = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}|\{([A-Za-z_]\w*)\}`)
+
+// resxPath names the language files Resources.<lang>.resx.
+func resxPath(outputDir, lang string) string {
+	return filepath.Join(outputDir, "Resources."+lang+".resx")
+}
+
+// generateResx updates or creates a .NET .resx resource file. Named placeholders
+// are converted to the composite format style {0}, {1}, ...
+func generateResx(entries []extract.Entry, filePath string) error {
+	existingEntries, err := loadExistingResx(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing resx: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(resxHeader)
+	for _, entry := range entries {
+		value := EntryValue(existingEntries, entry)
+		buffer.WriteString(fmt.Sprintf("  <data name=\"%s\" xml:space=\"preserve\">\n", textutil.EscapeXML(entry.Key)))
+		buffer.WriteString(fmt.Sprintf("    <value>%s</value>\n", textutil.EscapeXML(indexedPlaceholders(textutil.Unescape(value)))))
+		buffer.WriteString(fmt.Sprintf("    <comment>%s:%d %s%s</comment>\n", textutil.EscapeXML(entry.File), entry.Line, textutil.EscapeXML(entry.Path), idNote(entry)))
+		buffer.WriteString("  </data>\n")
+	}
+	buffer.WriteString("</root>\n")
+
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write resx file: %w", err)
+	}
+	return nil
+}
+
+// loadExistingResx parses an existing .resx file into a map of keys with their values.
+func loadExistingResx(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read resx file: %w", err)
+	}
+
+	var root struct {
+		Data []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"data"`
+	}
+	if err := textutil.UnmarshalXML(data, &root); err != nil {
+		return nil, fmt.Errorf("parse resx file: %w", err)
+	}
+	for _, d := range root.Data {
+		entries[d.Name] = textutil.Escape(d.Value)
+	}
+	return entries, nil
+}
+
+// indexedPlaceholders replaces named placeholders with {0}, {1}, ... numbered in
+// order of first appearance.
+func indexedPlaceholders(text string) string {
+	return numberPlaceholders(text, func(_ string, index int) string {
+		return "{" + strconv.Itoa(index) + "}"
+	})
+}
+
+// numberPlaceholders replaces named placeholders with the placeholder of their
+// name and index, numbered from 0 in order of first appearance.
+func numberPlaceholders(text string, placeholder func(name string, index int) string) string {
+	indexes := make(map[string]int)
+	return namedPlaceholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := namedPlaceholderRe.FindStringSubmatch(match)
+		name := m[1] + m[2]
+		index, ok := indexes[name]
+		if !ok {
+			index = len(indexes)
+			indexes[name] = index
+		}
+		return placeholder(name, index)
+	})
+}
@@ -0,0 +1,261 @@
+package emit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/toml"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+func init() {
+	Register("toml", tomlEmitter{})
+}
+
+// tomlMetadata are the fields of a TOML message derived from the protos rather
+// than translated. go-i18n ignores key_id.
+var tomlMetadata = []string{"description", "hash", "key_id"}
+
+// tomlEmitter writes the go-i18n v2 TOML files of the toml format.
+type tomlEmitter struct{}
+
+func (tomlEmitter) Path(outputDir, lang string) string {
+	return extPath(".toml")(outputDir, lang)
+}
+
+func (tomlEmitter) Generate(entries []extract.Entry, lang, filePath string) error {
+	return generateTOML(entries, lang, filePath)
+}
+
+func (tomlEmitter) Load(filePath string) (map[string]string, error) {
+	return loadExistingTOML(filePath)
+}
+
+func (tomlEmitter) EscapeRune(r rune) string {
+	return escapeUnicodeRune(r)
+}
+
+func (tomlEmitter) Comment(text string) string {
+	return lineComment("#")(text)
+}
+
+// TOML writes the go-i18n v2 TOML file of a language for the entries of a
+// catalog to w, as a new file: every value is the fallback of its entry.
+func TOML(c extract.Catalog, lang string, w io.Writer) error {
+	_, err := w.Write(renderTOML(c.Entries, lang, nil, nil, nil))
+	return err
+}
+
+// generateTOML updates or creates a TOML file based on the provided entries and maintains order.
+func generateTOML(entries []extract.Entry, lang, filePath string) error {
+	existingMessages, err := loadTOMLMessages(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing TOML: %w", err)
+	}
+	comments, trailing, err := loadTOMLComments(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing TOML: %w", err)
+	}
+
+	// Write the updated content to the file
+	if err := os.WriteFile(filePath, renderTOML(entries, lang, existingMessages, comments, trailing), 0644); err != nil {
+		return fmt.Errorf("write TOML file: %w", err)
+	}
+
+	return nil
+}
+
+// renderTOML returns the contents of a TOML file of the entries, keeping the
+// existing translations and the comment lines written above keys and after
+// the last one. Every key is a go-i18n v2 message table with the proto comment
+// as description, the hash of the source it was translated from, its stable
+// id if it has one and its plural forms: only other, unless the message is
+// plural, in which case every category of the language is written, defaulting
+// to the other value.
+func renderTOML(entries []extract.Entry, lang string, existingMessages map[string]map[string]string, comments map[string][]string, trailing []string) []byte {
+	categories := pluralCategories(lang)
+
+	// Generate TOML content
+	var buffer bytes.Buffer
+	writeComments := func(lines []string) {
+		for _, line := range lines {
+			buffer.WriteString(strings.TrimSpace("# " + line))
+			buffer.WriteString("\n")
+		}
+	}
+	for _, entry := range entries {
+		forms := existingMessages[entry.Key]
+		if entry.Alias != "" {
+			forms = nil
+		}
+		other := forms["other"]
+		if other == "" {
+			other = entry.Fallback
+		}
+
+		writeComments(comments[entry.Key])
+		if entry.Commented {
+			buffer.WriteString(fmt.Sprintf("# %s\n# [%s]\n# other = %s\n\n", entry.Comment, toml.Key(entry.Key), toml.String(textutil.Unescape(other))))
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("[%s]\n", toml.Key(entry.Key)))
+		if entry.Comment != "" {
+			writeComments(comments[entry.Key+"\x00description"])
+			buffer.WriteString(fmt.Sprintf("description = %s\n", toml.String(entry.Comment)))
+		}
+		writeComments(comments[entry.Key+"\x00hash"])
+		buffer.WriteString(fmt.Sprintf("hash = %s\n", toml.String(MessageHash(entry))))
+		if entry.ID > 0 {
+			writeComments(comments[entry.Key+"\x00key_id"])
+			buffer.WriteString(fmt.Sprintf("key_id = %s\n", toml.String(strconv.Itoa(entry.ID))))
+		}
+		plural := isPlural(entry, forms)
+		for _, form := range pluralForms {
+			value, ok := forms[form]
+			switch {
+			case form == "other":
+				value = other
+			case !plural || (!ok && !slices.Contains(categories, form)):
+				continue
+			case value == "":
+				value = other
+			}
+			writeComments(comments[entry.Key+"\x00"+form])
+			buffer.WriteString(fmt.Sprintf("%s = %s\n", form, toml.String(textutil.Unescape(value))))
+		}
+		buffer.WriteString("\n")
+	}
+	writeComments(trailing)
+	return buffer.Bytes()
+}
+
+// loadExistingTOML parses an existing TOML file into a map of keys with their values.
+func loadExistingTOML(filePath string) (map[string]string, error) {
+	messages, err := loadTOMLMessages(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]string)
+	for key, forms := range messages {
+		if value, ok := forms["other"]; ok {
+			entries[key] = value
+		}
+	}
+	return entries, nil
+}
+
+// loadTOMLMessages parses an existing TOML file into a map of keys with their
+// values by plural form, validating every item as it is read. The
+// description, hash and key_id fields are derived from the protos and not
+// returned.
+func loadTOMLMessages(filePath string) (map[string]map[string]string, error) {
+	entries := make(map[string]map[string]string)
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+
+	// Every field must be understood: a field skipped here would be dropped from
+	// the file when it is rewritten.
+	var currentKey string
+	_, err := scanTOML(filePath, func(item toml.Item) error {
+		switch {
+		case item.Header:
+			currentKey = item.Key
+		case !slices.Contains(tomlMetadata, item.Key) && !slices.Contains(pluralForms, item.Key):
+			return fmt.Errorf("%s:%d:%d: unsupported field %s in [%s]", filePath, item.Line, item.Column, item.Key, currentKey)
+		case currentKey == "":
+			return fmt.Errorf("%s:%d:%d: value outside of a [key] table", filePath, item.Line, item.Column)
+		case slices.Contains(tomlMetadata, item.Key):
+		default:
+			if entries[currentKey] == nil {
+				entries[currentKey] = make(map[string]string)
+			}
+			entries[currentKey][item.Key] = textutil.Escape(item.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// scanTOML calls fn with every item of a TOML file as it is read, without
+// holding the file in memory, and returns the comment lines after the last
+// item. Errors opening the file are returned unwrapped.
+func scanTOML(filePath string, fn func(item toml.Item) error) ([]string, error) {
+	file, _, err := textutil.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := toml.NewScanner(filePath, file)
+	for scanner.Scan() {
+		if err := fn(scanner.Item()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return scanner.Trailing(), nil
+}
+
+// TOMLHashes returns the hash of the source every key of a TOML file was
+// translated from, as written by MessageHash, by key. Keys without a hash are
+// left out.
+func TOMLHashes(filePath string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	var currentKey string
+	_, err := scanTOML(filePath, func(item toml.Item) error {
+		switch {
+		case item.Header:
+			currentKey = item.Key
+		case item.Key == "hash" && currentKey != "":
+			hashes[currentKey] = item.Value
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("open TOML file: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// loadTOMLComments returns the comment lines of an existing TOML file, such as
+// notes of translators, by the table they precede, or by the table and field
+// separated by a zero byte, and the comment lines at the end of the file.
+func loadTOMLComments(filePath string) (map[string][]string, []string, error) {
+	comments := make(map[string][]string)
+
+	var currentKey string
+	trailing, err := scanTOML(filePath, func(item toml.Item) error {
+		key := item.Key
+		if item.Header {
+			currentKey = item.Key
+		} else {
+			key = currentKey + "\x00" + item.Key
+		}
+		if len(item.Leading) > 0 {
+			comments[key] = item.Leading
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return comments, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return comments, trailing, nil
+}
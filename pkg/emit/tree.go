@@ -0,0 +1,63 @@
+package emit
+
+import (
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// keyTree is a mapping that preserves the order of its keys, used to nest keys by
+// their dot separated namespaces. A key holds either a subtree or a value.
+type keyTree struct {
+	keys     []string
+	children map[string]*keyTree
+	values   map[string]string
+}
+
+func newKeyTree() *keyTree {
+	return &keyTree{children: make(map[string]*keyTree), values: make(map[string]string)}
+}
+
+// set stores value under the path, creating intermediate subtrees. It reports
+// false when the path collides with an existing value or subtree.
+func (t *keyTree) set(path []string, value string) bool {
+	key := path[0]
+	if len(path) == 1 {
+		if _, ok := t.children[key]; ok {
+			return false
+		}
+		if _, ok := t.values[key]; !ok {
+			t.keys = append(t.keys, key)
+		}
+		t.values[key] = value
+		return true
+	}
+	if _, ok := t.values[key]; ok {
+		return false
+	}
+	child, ok := t.children[key]
+	if !ok {
+		child = newKeyTree()
+		t.children[key] = child
+		t.keys = append(t.keys, key)
+	}
+	return child.set(path[1:], value)
+}
+
+// nestedReason is how keys collide in the formats nested by the dot separated
+// namespaces of the keys.
+const nestedReason = "collide with the namespace of another key"
+
+// nestedCollisions returns a CollisionError for the keys that collide with the
+// namespace of another key once nested by their dot separated namespaces, such
+// as a.b.c after a.b or a.b after a.b.c.
+func nestedCollisions(entries []extract.Entry, _ string) error {
+	tree := newKeyTree()
+	var keys []string
+	for _, entry := range entries {
+		if !tree.set(strings.Split(entry.Key, "."), "") {
+			keys = append(keys, entry.Key)
+		}
+	}
+	return collisionError(keys, nestedReason)
+}
@@ -0,0 +1,162 @@
+package emit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/yaml"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// plainYAMLKeyRe matches keys that can be written without quotes.
+var plainYAMLKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// writeYAMLTree appends the tree as YAML block mappings at the given indentation level.
+func writeYAMLTree(buffer *bytes.Buffer, tree *keyTree, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	for _, key := range tree.keys {
+		if child, ok := tree.children[key]; ok {
+			buffer.WriteString(fmt.Sprintf("%s%s:\n", prefix, yamlKey(key)))
+			writeYAMLTree(buffer, child, indent+1)
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("%s%s: \"%s\"\n", prefix, yamlKey(key), textutil.Normalize(tree.values[key])))
+	}
+}
+
+// yamlKey quotes a mapping key unless it only contains safe characters.
+func yamlKey(key string) string {
+	if plainYAMLKeyRe.MatchString(key) {
+		return key
+	}
+	return "\"" + textutil.Escape(key) + "\""
+}
+
+// generateRailsYAML updates or creates a Rails-style YAML file, nested below the
+// language code and then by the dot separated namespaces of each key, failing
+// when keys collide with the namespace of another.
+func generateRailsYAML(entries []extract.Entry, lang, filePath string) error {
+	existingEntries, err := loadExistingRailsYAML(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing YAML: %w", err)
+	}
+
+	tree := newKeyTree()
+	var collisions []string
+	for _, entry := range entries {
+		value := EntryValue(existingEntries, entry)
+		if !tree.set(append([]string{lang}, strings.Split(entry.Key, ".")...), value) {
+			collisions = append(collisions, entry.Key)
+		}
+	}
+	if err := collisionError(collisions, nestedReason); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	writeYAMLTree(&buffer, tree, 0)
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write YAML file: %w", err)
+	}
+	return nil
+}
+
+// generateYAML updates or creates a flat YAML file mapping every key to its
+// value, as read by go-i18n and by loaders that take the language from the
+// file name.
+func generateYAML(entries []extract.Entry, filePath string) error {
+	existingEntries, err := loadYAML(filePath)
+	if err != nil {
+		return fmt.Errorf("load existing YAML: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	for _, entry := range entries {
+		value := EntryValue(existingEntries, entry)
+		buffer.WriteString(fmt.Sprintf("%s: \"%s\"\n", yamlKey(entry.Key), textutil.Normalize(value)))
+	}
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write YAML file: %w", err)
+	}
+	return nil
+}
+
+// loadExistingRailsYAML parses an existing Rails-style YAML file into a map of
+// keys with their values, dropping the language code at the root.
+func loadExistingRailsYAML(filePath string) (map[string]string, error) {
+	nested, err := loadYAML(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]string)
+	for key, value := range nested {
+		if i := strings.Index(key, "."); i >= 0 {
+			entries[key[i+1:]] = value
+		}
+	}
+	return entries, nil
+}
+
+// loadYAML parses the block mappings of a YAML file into a map of dot joined key
+// paths with their scalar values. Only the subset written by this tool and simple
+// hand edits (plain, single and double quoted scalars) are understood.
+func loadYAML(filePath string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, _, err := textutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil // File does not exist, return empty map
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open YAML file: %w", err)
+	}
+
+	type level struct {
+		indent int
+		key    string
+	}
+	var stack []level
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || line == "---" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		// Reject what is not understood rather than dropping it on rewrite
+		key, rest, ok := yaml.SplitLine(line)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unrecognized line: %s", filePath, lineNum, line)
+		}
+		if strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">") || strings.HasPrefix(rest, "- ") {
+			return nil, fmt.Errorf("%s:%d: block scalars and sequences are not supported", filePath, lineNum)
+		}
+		if (strings.HasPrefix(rest, "\"") || strings.HasPrefix(rest, "'")) && yaml.ClosingQuote(rest) < 0 {
+			return nil, fmt.Errorf("%s:%d: unterminated quoted value", filePath, lineNum)
+		}
+		if rest == "" {
+			stack = append(stack, level{indent: indent, key: key})
+			continue
+		}
+		path := make([]string, 0, len(stack)+1)
+		for _, l := range stack {
+			path = append(path, l.key)
+		}
+		entries[strings.Join(append(path, key), ".")] = yaml.Scalar(rest)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read YAML file: %w", err)
+	}
+	return entries, nil
+}
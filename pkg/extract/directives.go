@@ -0,0 +1,95 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/emicklei/proto"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+)
+
+// directivePrefix starts the comment lines giving the extraction of an enum
+// value or field directives, e.g. // i18n:ignore or // i18n:key=user.missing.
+const directivePrefix = "i18n:"
+
+// groupTag starts the comment lines of an enum or enum value giving the group
+// of its keys, e.g. // i18n-group: billing, the same as // i18n:group=billing.
+const groupTag = "i18n-group:"
+
+// Directives are the comment directives of an enum value or field.
+type Directives struct {
+	Ignore bool   // i18n:ignore skips the declaration
+	Key    string // i18n:key=<key> overrides the key derived from its name
+	Group  string // i18n-group: <group> or i18n:group=<group> labels its keys
+}
+
+// ParseDirectives returns the directives among the trimmed lines of a comment,
+// and the comment without their lines.
+func ParseDirectives(comment string) (Directives, string) {
+	var d Directives
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		if group, ok := strings.CutPrefix(line, groupTag); ok {
+			d.Group = strings.TrimSpace(group)
+			continue
+		}
+		directive, ok := strings.CutPrefix(line, directivePrefix)
+		if !ok {
+			lines = append(lines, line)
+			continue
+		}
+		name, value, _ := strings.Cut(directive, "=")
+		switch strings.TrimSpace(name) {
+		case "ignore":
+			d.Ignore = true
+		case "key":
+			d.Key = strings.TrimSpace(value)
+		case "group":
+			d.Group = strings.TrimSpace(value)
+		default:
+			lines = append(lines, line) // not a directive of the generator
+		}
+	}
+	return d, strings.Join(lines, "\n")
+}
+
+// declarationDirectives returns the directives of the leading and inline
+// comments of a declaration, and its leading comment without them.
+func declarationDirectives(leading, inline *proto.Comment) (Directives, string) {
+	d, comment := ParseDirectives(commentText(leading))
+	inlineDirectives, _ := ParseDirectives(commentText(inline))
+	d.Ignore = d.Ignore || inlineDirectives.Ignore
+	if inlineDirectives.Key != "" {
+		d.Key = inlineDirectives.Key
+	}
+	if inlineDirectives.Group != "" {
+		d.Group = inlineDirectives.Group
+	}
+	return d, comment
+}
+
+// commentLanguageRe matches the @<lang>: markers of a trailing comment giving
+// the default translations of an enum value.
+var commentLanguageRe = regexp.MustCompile(`(?:^|\s)@([A-Za-z]{2,3}(?:[-_][A-Za-z0-9]+)*):`)
+
+// CommentTranslations returns the default translations of a trailing comment
+// of the form @en: Not found @zh: 未找到, each the text up to the next marker,
+// by language as written. It returns nil if there are none.
+func CommentTranslations(comment string) map[string]string {
+	markers := commentLanguageRe.FindAllStringSubmatchIndex(comment, -1)
+	if len(markers) == 0 {
+		return nil
+	}
+	translations := make(map[string]string, len(markers))
+	for i, m := range markers {
+		end := len(comment)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		if text := strings.Join(strings.Fields(comment[m[1]:end]), " "); text != "" {
+			translations[comment[m[2]:m[3]]] = textutil.Escape(text)
+		}
+	}
+	return translations
+}
@@ -0,0 +1,235 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/emicklei/proto"
+)
+
+// protoErrorRe matches the position and message of an error of the proto
+// parser, e.g. errors.proto:7:13: found "non integer" but expected [enum field integer].
+var protoErrorRe = regexp.MustCompile(`(?m)^(.*?):(\d+):(\d+): (.*)$`)
+
+// protoExpectedRe matches the message of a parser error about an unexpected
+// token, which names its kind rather than the token itself.
+var protoExpectedRe = regexp.MustCompile(`^found .* but expected \[(.*)\]$`)
+
+// SyntaxError is a syntax error in a proto file, at the line and column of
+// the offending token.
+type SyntaxError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// SyntaxErrors are the syntax errors of a proto file that was parsed
+// without the declarations containing them.
+type SyntaxErrors []*SyntaxError
+
+func (errs SyntaxErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// AsSyntaxErrors returns the syntax errors behind an error of FromFile, or nil
+// if it is no syntax error.
+func AsSyntaxErrors(err error) []*SyntaxError {
+	var errs SyntaxErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return []*SyntaxError{syntaxErr}
+	}
+	return nil
+}
+
+// ParseSource parses the contents of a proto file. A syntax error is
+// returned as a *SyntaxError naming the offending token.
+func ParseSource(filePath string, data []byte) (*proto.Proto, error) {
+	parser := proto.NewParser(bytes.NewReader(data))
+	parser.Filename(filePath)
+	definition, err := parser.Parse()
+	if err != nil {
+		return nil, newSyntaxError(filePath, data, err)
+	}
+	return definition, nil
+}
+
+// RecoverSource parses the contents of a proto file, blanking out every
+// top-level declaration, such as an enum or message, containing a syntax error
+// and parsing the rest again. It returns the definition of what is left, the
+// contents it was parsed from, with the same lines and columns as the file, and
+// the syntax errors. The definition is nil if nothing could be parsed.
+func RecoverSource(filePath string, data []byte) (*proto.Proto, []byte, SyntaxErrors) {
+	var errs SyntaxErrors
+	data = bytes.Clone(data)
+	for {
+		definition, err := ParseSource(filePath, data)
+		if err == nil {
+			return definition, data, errs
+		}
+		syntaxErr := err.(*SyntaxError)
+		errs = append(errs, syntaxErr)
+		start, end := protoDeclaration(data, protoOffset(data, syntaxErr.Line, syntaxErr.Column))
+		if start == end {
+			return nil, data, errs
+		}
+		for i := start; i < end; i++ {
+			if data[i] != '\n' {
+				data[i] = ' '
+			}
+		}
+	}
+}
+
+// newSyntaxError turns an error of the proto parser into a
+// *SyntaxError, with the token found at its position in the source.
+func newSyntaxError(filePath string, data []byte, err error) *SyntaxError {
+	m := protoErrorRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return &SyntaxError{File: filePath, Message: err.Error()}
+	}
+	line, _ := strconv.Atoi(m[2])
+	column, _ := strconv.Atoi(m[3])
+	syntaxErr := &SyntaxError{File: filePath, Line: line, Column: column, Message: m[4]}
+	offset := protoOffset(data, line, column)
+	if token := protoToken(data[offset:]); token != "" {
+		syntaxErr.Token = token
+		if expected := protoExpectedRe.FindStringSubmatch(m[4]); expected != nil {
+			syntaxErr.Message = fmt.Sprintf("unexpected %q, expected %s", token, expected[1])
+		}
+	} else if expected := protoExpectedRe.FindStringSubmatch(m[4]); expected != nil {
+		syntaxErr.Message = fmt.Sprintf("unexpected end of file, expected %s", expected[1])
+	}
+	return syntaxErr
+}
+
+// protoOffset returns the byte offset of a line and column, in characters, both
+// counted from 1.
+func protoOffset(data []byte, line, column int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		i := bytes.IndexByte(data[offset:], '\n')
+		if i < 0 {
+			return len(data)
+		}
+		offset += i + 1
+	}
+	for ; column > 1 && offset < len(data) && data[offset] != '\n'; column-- {
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// protoToken returns the token at the start of the source: an identifier or
+// number, a quoted string, or a single character.
+func protoToken(source []byte) string {
+	isWord := func(r rune) bool { return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	r, size := utf8.DecodeRune(source)
+	switch {
+	case size == 0 || unicode.IsSpace(r):
+		return ""
+	case r == '"' || r == '\'':
+		if end := bytes.IndexAny(source[1:], string(r)+"\n"); end >= 0 && source[1+end] == byte(r) {
+			return string(source[:end+2])
+		}
+		return string(source[:1])
+	case isWord(r):
+		end := bytes.IndexFunc(source, func(r rune) bool { return !isWord(r) })
+		if end < 0 {
+			end = len(source)
+		}
+		return string(source[:end])
+	}
+	return string(source[:size])
+}
+
+// protoDeclarationRe matches a line starting a top-level declaration, which
+// ends a declaration left open before it.
+var protoDeclarationRe = regexp.MustCompile(`^(syntax|edition|package|import|option|message|enum|service|extend)\b`)
+
+// protoDeclaration returns the byte range of the top-level declaration of a
+// proto file containing an offset: from its first token to its closing
+// semicolon or brace, or, if it is not closed, to the next line starting a
+// top-level declaration or the end of the file. Comments and strings are
+// skipped when matching braces. When the offset lies outside of any
+// declaration, as for an error at the end of the file, the range is that of
+// the last declaration left open, or empty if there is none.
+func protoDeclaration(data []byte, offset int) (start, end int) {
+	start = -1
+	depth := 0
+	var openStart, openEnd int
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c == '\n' && depth > 0 && protoDeclarationRe.Match(data[i+1:]) {
+			if offset >= start && offset <= i {
+				return start, i
+			}
+			openStart, openEnd = start, i
+			start, depth = -1, 0
+		}
+		switch {
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			if j := bytes.Index(data[i+2:], []byte("*/")); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(data)
+			}
+			continue
+		case unicode.IsSpace(rune(c)):
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		switch c {
+		case '"', '\'':
+			for i++; i < len(data) && data[i] != c && data[i] != '\n'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth <= 0 && (c == ';' || c == '}') {
+			if offset >= start && offset <= i {
+				return start, i + 1
+			}
+			start, depth = -1, 0
+		}
+	}
+	if start >= 0 && offset >= start {
+		return start, len(data)
+	}
+	return openStart, openEnd
+}
@@ -0,0 +1,374 @@
+// Package extract reads the translatable keys of proto files: the values of
+// enums, the ids of the cel rules of buf.validate, its standard rules, the
+// rules of the legacy protoc-gen-validate and the labels of fields, with their
+// default messages, comments and options, as the i18n-gen command writes them
+// to the language files.
+package extract
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/proto"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+)
+
+// Entry is a single translatable key extracted from a proto file.
+type Entry struct {
+	Key     string // key written to the language files
+	Name    string // enum value name or validation id as declared in the proto
+	Kind    string // "enum", "cel", "rule", "pgv" or "label"
+	Path    string // enclosing enum name, or Message.field for validation rules and labels
+	Message string // default message, if the proto declares one
+	// Expression is the CEL expression of a validation rule, or the standard or
+	// PGV rule with its value, e.g. string.min_len = 1.
+	Expression string
+	Comment    string // leading comment of the enum value in the proto
+	// Fallback is written when a language file has no translation for the key.
+	Fallback  string
+	Number    int             // number of the enum value
+	ID        int             // stable numeric id of the key, if ids are assigned
+	CodeRange *CodeRange      // range declared with (i18n.code_range) on the enum, if any
+	Package   string          // proto package of the file
+	Options   []*proto.Option // options set on the enum value
+	GRPCCode  string          // gRPC status code set with a grpc_code option, e.g. NOT_FOUND
+	File      string
+	Line      int
+	// Column is that of a field of a cel rule on Line, which tells the rules
+	// written on the same line apart.
+	Column int
+	// Translations are the default translations set with (i18n.msg) on the enum
+	// value, or in its trailing comment, by language as named there, e.g. en or
+	// zh_hant.
+	Translations map[string]string
+	// Canonical is set on a value of an enum with allow_alias that shares its
+	// number with a value declared before it, to the key of that value.
+	Canonical string
+	// Group is the group set with an i18n-group comment tag on the enum value,
+	// or else on its enum, which bundles, reports and ticket exports follow
+	// rather than the proto package.
+	Group string
+	// Suggested is set when the proto declares no id and Name was derived instead.
+	Suggested bool
+	// Alias is the key this entry is a deprecated alias of. Its value follows
+	// that key: writers use the fallback instead of the existing value, or a
+	// reference to the key when Reference is set.
+	Alias     string
+	Reference bool
+	// Commented marks a stale key written commented out, for the formats able
+	// to comment keys out.
+	Commented bool
+}
+
+// Kinds of entries.
+const (
+	KindEnum  = "enum"
+	KindCEL   = "cel"
+	KindRule  = "rule"  // a standard protovalidate rule, such as string.min_len
+	KindPGV   = "pgv"   // a legacy protoc-gen-validate rule
+	KindLabel = "label" // the UI label of a field, set with (i18n.label)
+)
+
+// FromFile reads a .proto file and extracts its enum values and validation ids
+// as entries, in order. Only the enums opts.SelectEnum selects are read, at any
+// nesting depth in messages, their path recording the enclosing messages as
+// that of validation ids does, and only the keys opts.SelectKeys selects are
+// returned. With opts.SuggestIDs, validation rules without an id get one
+// derived from their position. Files of the packages opts.SelectPackage does
+// not select have no entries. With opts.Recover, the top-level
+// declarations containing syntax errors are skipped and the entries of the
+// rest of the file are returned along with the SyntaxErrors.
+func FromFile(filePath string, opts Options) ([]Entry, error) {
+	data, encoding, err := textutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open proto file: %w", err)
+	}
+	if encoding != "" && opts.Transcoded != nil {
+		opts.Transcoded(filePath, encoding)
+	}
+	return FromSource(filePath, data, opts)
+}
+
+// FromSource extracts the entries of the source of a .proto file, such as one
+// read from standard input, as FromFile does. The entries and errors name it
+// filePath.
+func FromSource(filePath string, data []byte, opts Options) ([]Entry, error) {
+	var (
+		err        error
+		entries    []Entry
+		definition *proto.Proto
+		syntaxErrs SyntaxErrors
+	)
+	if opts.Recover {
+		definition, data, syntaxErrs = RecoverSource(filePath, data)
+		if definition == nil {
+			return nil, syntaxErrs
+		}
+	} else if definition, err = ParseSource(filePath, data); err != nil {
+		return nil, err
+	}
+
+	pkg := PackageName(definition)
+	if !opts.SelectPackage(pkg) {
+		if len(syntaxErrs) > 0 {
+			return nil, syntaxErrs
+		}
+		return nil, nil
+	}
+	c := ruleCollector{pkg: pkg, file: filePath, lines: strings.Split(string(data), "\n"), suggestIDs: opts.SuggestIDs, ruleIndex: make(map[string]int)}
+
+	// First pass: collect enum entries
+	proto.Walk(definition,
+		proto.WithEnum(func(e *proto.Enum) {
+			messages := enclosingMessages(e)
+			path := strings.Join(append(slices.Clip(messages), e.Name), ".")
+			if !opts.SelectEnum(e.Name, path) {
+				return
+			}
+			codes := enumCodeRange(e)
+			enumDirectives, _ := ParseDirectives(commentText(e.Comment))
+			allowAlias := enumAllowAlias(e)
+			canonical := make(map[int]string) // number -> key of its first value
+			for _, elem := range e.Elements {
+				if field, ok := elem.(*proto.EnumField); ok {
+					directives, comment := declarationDirectives(field.Comment, field.InlineComment)
+					if directives.Ignore || opts.SkipValue(field.Name, field.Integer) {
+						continue
+					}
+					key := field.Name
+					switch {
+					case directives.Key != "":
+						key = directives.Key
+					case opts.PrefixNestedEnums && len(messages) > 0:
+						key = strings.Join(messages, ".") + "." + field.Name
+					}
+					group := enumDirectives.Group
+					if directives.Group != "" {
+						group = directives.Group
+					}
+					first, aliased := canonical[field.Integer]
+					switch {
+					case !allowAlias:
+					case !aliased:
+						canonical[field.Integer] = key
+					case !opts.EnumAliases:
+						continue
+					}
+					options := fieldOptions(field.Elements)
+					entry := Entry{
+						Key:          key,
+						Name:         field.Name,
+						Kind:         KindEnum,
+						Path:         path,
+						Comment:      comment,
+						Number:       field.Integer,
+						CodeRange:    codes,
+						Package:      pkg,
+						Options:      options,
+						GRPCCode:     grpcCode(options),
+						Translations: c.translations(options, field.InlineComment),
+						Group:        group,
+						File:         filePath,
+						Line:         field.Position.Line,
+					}
+					if aliased {
+						entry.Canonical = first
+					}
+					entries = append(entries, entry)
+				}
+			}
+		}),
+	)
+
+	// Second pass: collect the cel rules and standard rules of buf.validate and
+	// the PGV rules from the field options
+	for _, elem := range definition.Elements {
+		if m, ok := elem.(*proto.Message); ok {
+			c.message(nil, m.Name, m.Elements)
+		}
+	}
+	entries = opts.SelectKeys(append(entries, c.entries...))
+
+	if len(syntaxErrs) > 0 {
+		return entries, syntaxErrs
+	}
+	return entries, nil
+}
+
+// suggestID derives a stable validation id from the field path and the index of
+// the cel rule on that field, e.g. create_user_request.email.cel_0.
+func suggestID(path string, index int) string {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		parts[i] = textutil.CamelToSnake(part)
+	}
+	return fmt.Sprintf("%s.cel_%d", strings.Join(parts, "."), index)
+}
+
+// PackageName returns the package declared by a parsed proto file.
+func PackageName(definition *proto.Proto) string {
+	for _, elem := range definition.Elements {
+		if p, ok := elem.(*proto.Package); ok {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// fieldOptions returns the options among the elements of an enum value.
+func fieldOptions(elements []proto.Visitee) []*proto.Option {
+	var options []*proto.Option
+	for _, elem := range elements {
+		if option, ok := elem.(*proto.Option); ok {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// commentText returns the trimmed lines of a proto comment joined by newlines.
+func commentText(c *proto.Comment) string {
+	if c == nil {
+		return ""
+	}
+	lines := make([]string, 0, len(c.Lines))
+	for _, line := range c.Lines {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// enclosingMessages returns the names of the messages an enum is nested in,
+// outermost first.
+func enclosingMessages(e *proto.Enum) []string {
+	var messages []string
+	for parent := e.Parent; parent != nil; {
+		m, ok := parent.(*proto.Message)
+		if !ok {
+			break
+		}
+		messages = append([]string{m.Name}, messages...)
+		parent = m.Parent
+	}
+	return messages
+}
+
+// msgOption is the enum value option holding its default translations.
+const msgOption = "(i18n.msg)"
+
+// translations returns the default translations set with (i18n.msg) among the
+// options of an enum value, one per language, (i18n.msg) = {lang: "en", text:
+// "..."}, over those of its trailing comment. The fields of the former
+// i18n.Messages are read as well, as an aggregate, (i18n.msg) = {en: "..."},
+// or one language at a time, (i18n.msg).en = "...". It returns nil if none are
+// set.
+func (c *ruleCollector) translations(options []*proto.Option, trailing *proto.Comment) map[string]string {
+	translations := CommentTranslations(commentText(trailing))
+	set := func(lang string, lit *proto.Literal) {
+		if lit == nil || !lit.IsString {
+			return
+		}
+		if translations == nil {
+			translations = make(map[string]string)
+		}
+		translations[lang] = c.stringValue(lit)
+	}
+	for _, option := range options {
+		name := strings.ReplaceAll(option.Name, " ", "")
+		rest, ok := strings.CutPrefix(name, msgOption)
+		switch {
+		case !ok:
+		case rest == "":
+			lang, langOK := option.Constant.OrderedMap.Get("lang")
+			text, textOK := option.Constant.OrderedMap.Get("text")
+			if langOK && textOK {
+				if lang.IsString {
+					set(lang.Source, text)
+				}
+				continue
+			}
+			for _, named := range option.Constant.OrderedMap {
+				set(named.Name, named.Literal)
+			}
+		default:
+			set(strings.TrimPrefix(rest, "."), &option.Constant)
+		}
+	}
+	return translations
+}
+
+// labelOption is the field option marking a field as a translatable UI label.
+const labelOption = "(i18n.label)"
+
+// label adds the entry of the UI label set with (i18n.label) on the field at
+// path, keyed <keyPath>.label, keyPath being <Message>.<field> unless a
+// directive overrides it, so that it does not collide with the rules of the
+// field in nested formats. The label is the default message, or there is none
+// when the option is set to true.
+func (c *ruleCollector) label(path, keyPath, comment string, field *proto.Field, option *proto.Option) {
+	key := keyPath + ".label"
+	entry := Entry{Key: key, Name: key, Kind: KindLabel, Path: path, Comment: comment, Package: c.pkg, File: c.file, Line: field.Position.Line}
+	if option.Constant.IsString {
+		entry.Message = c.stringValue(&option.Constant)
+	} else if option.Constant.Source != "true" {
+		return
+	}
+	c.entries = append(c.entries, entry)
+}
+
+// enumAllowAlias reports whether an enum sets allow_alias, letting values
+// share a number.
+func enumAllowAlias(e *proto.Enum) bool {
+	for _, elem := range e.Elements {
+		if option, ok := elem.(*proto.Option); ok && option.Name == "allow_alias" {
+			return option.Constant.Source == "true"
+		}
+	}
+	return false
+}
+
+// codeRangeOption is the enum option declaring the numeric code range of its values.
+const codeRangeOption = "(i18n.code_range)"
+
+// CodeRange is the inclusive range of numbers the values of an enum must fall into.
+type CodeRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// enumCodeRange returns the range declared with (i18n.code_range) on the enum, or
+// nil if it declares none.
+func enumCodeRange(e *proto.Enum) *CodeRange {
+	for _, elem := range e.Elements {
+		option, ok := elem.(*proto.Option)
+		if !ok || option.Name != codeRangeOption {
+			continue
+		}
+		minLit, _ := option.Constant.OrderedMap.Get("min")
+		maxLit, _ := option.Constant.OrderedMap.Get("max")
+		minValue, minErr := strconv.Atoi(minLit.Source)
+		maxValue, maxErr := strconv.Atoi(maxLit.Source)
+		if minErr != nil || maxErr != nil {
+			return nil
+		}
+		return &CodeRange{Min: minValue, Max: maxValue}
+	}
+	return nil
+}
+
+// grpcCode returns the value of a grpc_code option, such as (i18n.grpc_code) or
+// (xerr.grpc_code), among the options of an enum value.
+func grpcCode(options []*proto.Option) string {
+	for _, option := range options {
+		name := strings.Trim(option.Name, "()")
+		if name == "grpc_code" || strings.HasSuffix(name, ".grpc_code") {
+			return option.Constant.Source
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultExtractor is the extractor used when Options name none: the enum
+// values and cel rules of proto files, read by FromFile.
+const DefaultExtractor = "proto"
+
+// Extractor reads the entries of one kind of input file. Extractors are
+// registered by name with Register, so input sources other than proto files
+// can be added by other packages and selected with Options.Extractor.
+type Extractor interface {
+	// Match reports whether the extractor reads a file found below the
+	// directory of a pattern.
+	Match(filePath string) bool
+	// Extract reads the entries of a file, in order. It may return entries
+	// along with an error, for what could be recovered from a broken file.
+	Extract(filePath string, opts Options) ([]Entry, error)
+}
+
+// extractors lists the registered extractors by name.
+var extractors = map[string]Extractor{
+	DefaultExtractor: protoExtractor{},
+}
+
+// Register makes an extractor available by name. It panics if the name is
+// already taken, as registering twice is a programming error.
+func Register(name string, x Extractor) {
+	if _, ok := extractors[name]; ok {
+		panic("extract: extractor " + name + " registered twice")
+	}
+	extractors[name] = x
+}
+
+// Lookup returns the extractor registered by name, or the default one for an
+// empty name.
+func Lookup(name string) (Extractor, error) {
+	if name == "" {
+		name = DefaultExtractor
+	}
+	x, ok := extractors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown extractor %s (%s)", name, strings.Join(Extractors(), ", "))
+	}
+	return x, nil
+}
+
+// Extractors returns the names of the registered extractors, sorted.
+func Extractors() []string {
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// protoExtractor reads the enum values and the ids of the cel rules of proto
+// files.
+type protoExtractor struct{}
+
+func (protoExtractor) Match(filePath string) bool {
+	return strings.HasSuffix(filePath, ".proto")
+}
+
+func (protoExtractor) Extract(filePath string, opts Options) ([]Entry, error) {
+	return FromFile(filePath, opts)
+}
@@ -0,0 +1,312 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Options select the files and enums entries are extracted from, and how.
+type Options struct {
+	Extractor  string   // name of the registered extractor reading the files; proto if empty
+	Pattern    string   // path pattern of the files, see Find
+	Exclude    []string // patterns of the paths Find skips below the root of Pattern; DefaultExcludes if nil
+	Files      []string // files read instead of those of Pattern, if set
+	EnumPrefix string   // only read enums with this prefix
+	EnumSuffix string   // only read enums with this suffix
+	SuggestIDs bool     // derive ids for validation rules without one
+	Recover    bool     // skip the declarations containing syntax errors, see FromFile
+	Workers    int      // number of files parsed concurrently; the number of CPUs if not positive
+	// PrefixNestedEnums prefixes the keys of the values of enums nested in
+	// messages with the enclosing messages, e.g. Order.PENDING, as proto
+	// scopes the values.
+	PrefixNestedEnums bool
+	// EnumAliases keeps every name of the values of enums with allow_alias,
+	// the later names of a number marked with the key of the first; only the
+	// first is kept otherwise.
+	EnumAliases bool
+	// SkipUnspecified skips the values numbered 0, the sentinels such as
+	// ERR_UNSPECIFIED = 0 nobody translates.
+	SkipUnspecified bool
+	// Unspecified skips the values whose name it matches, whatever their
+	// number, if set.
+	Unspecified *regexp.Regexp
+	// EnumRegex and EnumExclude select the enums whose path, Message.Enum for
+	// nested enums, the first matches and the second does not, if set.
+	EnumRegex, EnumExclude *regexp.Regexp
+	// KeyRegex and KeyExclude select the keys, of enum values and validation
+	// rules alike, the first matches and the second does not, if set.
+	KeyRegex, KeyExclude *regexp.Regexp
+	// Packages and ExcludePackages select the files whose proto package one
+	// of the first patterns matches and none of the second does, if set.
+	// Patterns match whole packages, * matching any run of characters, e.g.
+	// myapp.errors.*; files without a package only match *.
+	Packages, ExcludePackages []string
+	// Parsed is called by ParseFiles with every file as soon as it is
+	// parsed, from the goroutine that parsed it, if set.
+	Parsed func(File)
+	// Transcoded is called by FromFile with every file read in another
+	// encoding than UTF-8 and the name of that encoding, e.g. UTF-16 or GBK,
+	// for the caller to warn about, if set. ParseFiles calls it from the
+	// goroutine that read the file.
+	Transcoded func(filePath, encoding string)
+}
+
+// SkipValue reports whether the enum value of the given name and number is a
+// sentinel skipped with SkipUnspecified or Unspecified.
+func (o Options) SkipValue(name string, number int) bool {
+	return (o.SkipUnspecified && number == 0) || (o.Unspecified != nil && o.Unspecified.MatchString(name))
+}
+
+// SelectEnum reports whether the values of the enum of the given name and
+// path are extracted, according to EnumPrefix, EnumSuffix, EnumRegex and
+// EnumExclude.
+func (o Options) SelectEnum(name, path string) bool {
+	switch {
+	case o.EnumPrefix != "" && !strings.HasPrefix(name, o.EnumPrefix):
+	case o.EnumSuffix != "" && !strings.HasSuffix(name, o.EnumSuffix):
+	case o.EnumRegex != nil && !o.EnumRegex.MatchString(path):
+	case o.EnumExclude != nil && o.EnumExclude.MatchString(path):
+	default:
+		return true
+	}
+	return false
+}
+
+// SelectPackage reports whether the entries of a file of the given proto
+// package are extracted, according to Packages and ExcludePackages.
+func (o Options) SelectPackage(pkg string) bool {
+	if len(o.Packages) > 0 && !matchPackage(o.Packages, pkg) {
+		return false
+	}
+	return !matchPackage(o.ExcludePackages, pkg)
+}
+
+// matchPackage reports whether one of the patterns matches a whole package.
+func matchPackage(patterns []string, pkg string) bool {
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		if regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectKeys returns the entries whose key KeyRegex and KeyExclude select.
+func (o Options) SelectKeys(entries []Entry) []Entry {
+	if o.KeyRegex == nil && o.KeyExclude == nil {
+		return entries
+	}
+	selected := entries[:0:0]
+	for _, e := range entries {
+		if (o.KeyRegex == nil || o.KeyRegex.MatchString(e.Key)) && (o.KeyExclude == nil || !o.KeyExclude.MatchString(e.Key)) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// File is the outcome of parsing one file.
+type File struct {
+	Path    string
+	Entries []Entry
+	Err     error // error reading or parsing the file; Entries holds what was recovered
+}
+
+// Catalog is what was extracted from a set of files.
+type Catalog struct {
+	Entries []Entry // entries of every file, ordered by Merge, without duplicate keys
+	Files   []File
+}
+
+// Err returns the errors of the files that failed to parse, or nil.
+func (c Catalog) Err() error {
+	var errs []error
+	for _, f := range c.Files {
+		if f.Err != nil {
+			errs = append(errs, f.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromFiles extracts the entries of the files selected by opts. Files
+// that fail to parse do not fail the extraction: their errors are kept in the
+// catalog, see Catalog.Err. An error is returned when the files cannot be
+// found, or none are.
+func FromFiles(opts Options) (Catalog, error) {
+	x, err := Lookup(opts.Extractor)
+	if err != nil {
+		return Catalog{}, err
+	}
+	files := opts.Files
+	if files == nil {
+		exclude := opts.Exclude
+		if exclude == nil {
+			exclude = DefaultExcludes
+		}
+		if files, err = Find(opts.Pattern, x, exclude...); err != nil {
+			return Catalog{}, err
+		}
+		if len(files) == 0 {
+			return Catalog{}, fmt.Errorf("no files match %s", opts.Pattern)
+		}
+	}
+	if opts.Workers < 1 {
+		opts.Workers = runtime.NumCPU()
+	}
+	parsed := ParseFiles(files, opts)
+	return Catalog{Entries: Unique(Merge(parsed)), Files: parsed}, nil
+}
+
+// FindFiles returns the .proto files a pattern matches, as Find does, but for
+// those of DefaultExcludes.
+func FindFiles(pattern string) ([]string, error) {
+	return Find(pattern, protoExtractor{}, DefaultExcludes...)
+}
+
+// Find returns the files an extractor reads among those a pattern matches,
+// skipping the files and directories the exclude patterns match by their path
+// relative to the root of the pattern, see excluded. A pattern is a glob, in
+// which ** matches any number of directories, e.g. api/**/*.proto, a
+// directory, matching every file below it, or a file.
+func Find(pattern string, x Extractor, exclude ...string) ([]string, error) {
+	if !hasMeta(pattern) {
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if !x.Match(pattern) {
+				return nil, nil
+			}
+			return []string{pattern}, nil
+		}
+	}
+	var files []string
+	root := PatternRoot(pattern)
+	glob := filepath.ToSlash(filepath.Clean(pattern))
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && excluded(exclude, filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && x.Match(path) && (!hasMeta(pattern) || MatchGlob(glob, filepath.ToSlash(path))) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// PatternRoot returns the directory Find walks for a pattern: the directory
+// of its elements before the first glob one, or the directory it names, or
+// that of the file it names.
+func PatternRoot(pattern string) string {
+	if !hasMeta(pattern) {
+		if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+			return filepath.Clean(pattern)
+		}
+		return filepath.Dir(pattern)
+	}
+	elements := strings.Split(filepath.ToSlash(pattern), "/")
+	static := 0
+	for static < len(elements) && !hasMeta(elements[static]) {
+		static++
+	}
+	switch root := strings.Join(elements[:static], "/"); {
+	case root != "":
+		return filepath.Clean(filepath.FromSlash(root))
+	case strings.HasPrefix(pattern, "/"):
+		return "/"
+	}
+	return "."
+}
+
+// hasMeta reports whether a path holds glob metacharacters.
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+// ParseFiles parses the files with the extractor of opts, with up to
+// opts.Workers goroutines, or one. The results are returned in the order of
+// files, whatever order the parsers finish in.
+func ParseFiles(files []string, opts Options) []File {
+	results := make([]File, len(files))
+	x, err := Lookup(opts.Extractor)
+	if err != nil {
+		for i, path := range files {
+			results[i] = File{Path: path, Err: err}
+		}
+		return results
+	}
+	workers := max(opts.Workers, 1)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries, err := x.Extract(files[i], opts)
+				results[i] = File{Path: files[i], Entries: entries, Err: err}
+				if opts.Parsed != nil {
+					opts.Parsed(results[i])
+				}
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// Merge reduces the entries of parsed files into one list, ordered by file path
+// and then by the order the parser returned them in, so the result does not
+// depend on how the files were found or parsed. Files that failed to parse
+// contribute nothing, or what was recovered from them. Duplicate keys are left
+// to Unique, which keeps the first one.
+func Merge(parsed []File) []Entry {
+	sorted := append([]File(nil), parsed...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	var merged []Entry
+	for _, p := range sorted {
+		merged = append(merged, p.Entries...)
+	}
+	return merged
+}
+
+// Unique drops entries whose key was already seen, keeping the first occurrence.
+func Unique(entries []Entry) []Entry {
+	seen := make(map[string]bool)
+	var unique []Entry
+	for _, e := range entries {
+		if seen[e.Key] {
+			continue
+		}
+		seen[e.Key] = true
+		unique = append(unique, e)
+	}
+	return unique
+}
@@ -0,0 +1,175 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestMergeUnique(t *testing.T) {
+	entry := func(key, file string, line int) Entry {
+		return Entry{Key: key, Kind: KindEnum, File: file, Line: line}
+	}
+	tests := []struct {
+		name   string
+		parsed []File
+		want   []Entry
+	}{
+		{
+			name: "ordered by file path",
+			parsed: []File{
+				{Path: "b.proto", Entries: []Entry{entry("B1", "b.proto", 3), entry("B2", "b.proto", 4)}},
+				{Path: "a.proto", Entries: []Entry{entry("A1", "a.proto", 3)}},
+				{Path: "c/a.proto", Entries: []Entry{entry("C1", "c/a.proto", 3)}},
+			},
+			want: []Entry{entry("A1", "a.proto", 3), entry("B1", "b.proto", 3), entry("B2", "b.proto", 4), entry("C1", "c/a.proto", 3)},
+		},
+		{
+			name: "parser order kept within a file",
+			parsed: []File{
+				{Path: "a.proto", Entries: []Entry{entry("Z", "a.proto", 9), entry("A", "a.proto", 2)}},
+			},
+			want: []Entry{entry("Z", "a.proto", 9), entry("A", "a.proto", 2)},
+		},
+		{
+			name: "first duplicate key wins",
+			parsed: []File{
+				{Path: "b.proto", Entries: []Entry{entry("DUP", "b.proto", 1), entry("B", "b.proto", 2)}},
+				{Path: "a.proto", Entries: []Entry{entry("A", "a.proto", 1), entry("DUP", "a.proto", 2)}},
+				{Path: "c.proto", Entries: []Entry{entry("DUP", "c.proto", 1)}},
+			},
+			want: []Entry{entry("A", "a.proto", 1), entry("DUP", "a.proto", 2), entry("B", "b.proto", 2)},
+		},
+		{
+			name: "duplicate key within a file",
+			parsed: []File{
+				{Path: "a.proto", Entries: []Entry{entry("DUP", "a.proto", 1), entry("DUP", "a.proto", 5)}},
+			},
+			want: []Entry{entry("DUP", "a.proto", 1)},
+		},
+		{
+			name: "recovered partial entries",
+			parsed: []File{
+				{Path: "c.proto", Entries: []Entry{entry("C", "c.proto", 1)}},
+				{Path: "b.proto", Entries: []Entry{entry("B", "b.proto", 1), entry("DUP", "b.proto", 7)}, Err: errors.New("b.proto:5:1: syntax error")},
+				{Path: "a.proto", Err: errors.New("a.proto:1:1: syntax error")},
+				{Path: "d.proto", Entries: []Entry{entry("DUP", "d.proto", 1)}},
+			},
+			want: []Entry{entry("B", "b.proto", 1), entry("DUP", "b.proto", 7), entry("C", "c.proto", 1)},
+		},
+		{
+			name:   "no files",
+			parsed: nil,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 20; i++ {
+				parsed := append([]File(nil), tt.parsed...)
+				r.Shuffle(len(parsed), func(i, j int) { parsed[i], parsed[j] = parsed[j], parsed[i] })
+				if got := Unique(Merge(parsed)); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("Unique(Merge(%v)) = %v, want %v", paths(parsed), keys(got), keys(tt.want))
+				}
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotModifyInput(t *testing.T) {
+	parsed := []File{{Path: "b.proto"}, {Path: "a.proto"}}
+	Merge(parsed)
+	if got := paths(parsed); !reflect.DeepEqual(got, []string{"b.proto", "a.proto"}) {
+		t.Errorf("Merge reordered its input to %v", got)
+	}
+}
+
+func TestParseFilesParsed(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for name, source := range map[string]string{
+		"a.proto": "syntax = \"proto3\";\nenum A {\n  A_ONE = 0;\n  A_TWO = 1;\n}\n",
+		"b.proto": "syntax = \"proto3\";\nenum B {\n  B_ONE = 0;\n}\n",
+		"c.proto": "syntax = \"proto3\";\nenum C {\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	var mu sync.Mutex
+	var parsed []File
+	results := ParseFiles(files, Options{Workers: 2, Parsed: func(f File) {
+		mu.Lock()
+		defer mu.Unlock()
+		parsed = append(parsed, f)
+	}})
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].Path < parsed[j].Path })
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	if !reflect.DeepEqual(parsed, results) {
+		t.Errorf("Parsed was called with %v, want %v", paths(parsed), paths(results))
+	}
+	if len(parsed) != 3 || len(parsed[0].Entries) != 2 || len(parsed[1].Entries) != 1 || parsed[2].Err == nil {
+		t.Errorf("Parsed was called with %+v", parsed)
+	}
+}
+
+func TestParseFilesTranscoded(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.proto")
+	// UTF-16LE with a byte order mark, as some Windows editors save it
+	source := []byte{0xFF, 0xFE}
+	for _, r := range "syntax = \"proto3\";\nenum A {\n  A_ONE = 0;\n}\n" {
+		source = append(source, byte(r), 0)
+	}
+	if err := os.WriteFile(path, source, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	var mu sync.Mutex
+	transcoded := make(map[string]string)
+	results := ParseFiles([]string{path}, Options{Transcoded: func(filePath, encoding string) {
+		mu.Lock()
+		defer mu.Unlock()
+		transcoded[filePath] = encoding
+	}})
+	if len(results) != 1 || results[0].Err != nil || len(results[0].Entries) != 1 {
+		t.Fatalf("ParseFiles returned %+v", results)
+	}
+	if want := map[string]string{path: "UTF-16"}; !reflect.DeepEqual(transcoded, want) {
+		t.Errorf("Transcoded was called with %v, want %v", transcoded, want)
+	}
+	if logged.Len() > 0 {
+		t.Errorf("ParseFiles logged %q, want the warning left to the caller", logged.String())
+	}
+}
+
+func paths(files []File) []string {
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func keys(entries []Entry) []string {
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key+"@"+e.File)
+	}
+	return keys
+}
@@ -0,0 +1,60 @@
+package extract
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultExcludes are the directories the walk of Find skips unless asked
+// not to: vendored dependencies and package caches, which hold protos such as
+// googleapis that are not the project's own.
+var DefaultExcludes = []string{"vendor", "third_party", "node_modules"}
+
+// MatchGlob reports whether a slash separated path matches a glob pattern, in
+// which * and ? do not match slashes and a ** element matches any number of
+// directories, e.g. api/**/*.proto matches api/a.proto and api/v1/b.proto.
+func MatchGlob(pattern, name string) bool {
+	return matchElements(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchElements matches the elements of a path against those of a pattern.
+func matchElements(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchElements(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// excluded reports whether an exclude pattern matches a path relative to the
+// walked directory. As in .gitignore, a pattern without a slash matches the
+// name of any file or directory, and one with a slash the whole path.
+func excluded(patterns []string, rel string) bool {
+	rel = strings.TrimPrefix(path.Clean(rel), "./")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if MatchGlob(strings.TrimPrefix(pattern, "/"), rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package extract
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emicklei/proto"
+)
+
+// pgvRulesOption is the option holding the legacy protoc-gen-validate rules of
+// a field, e.g. (validate.rules).string.min_len = 1.
+const pgvRulesOption = "(validate.rules)"
+
+// ruleModifiers are the fields of standard rules that change how other rules
+// apply rather than constrain the value, and have no message of their own.
+var ruleModifiers = []string{"ignore", "ignore_empty", "strict", "skip", "example"}
+
+// ruleMessages are the default messages of the standard rules of protovalidate
+// and PGV, by type and rule or by rule alone, following the name of the field.
+// %s is the value of the rule.
+var ruleMessages = map[string]string{
+	"const":        "must equal %s",
+	"lt":           "must be less than %s",
+	"lte":          "must be at most %s",
+	"gt":           "must be greater than %s",
+	"gte":          "must be at least %s",
+	"in":           "must be one of %s",
+	"not_in":       "must not be one of %s",
+	"lt_now":       "must be in the past",
+	"gt_now":       "must be in the future",
+	"within":       "must be within %s of now",
+	"defined_only": "must be a defined value",
+	"required":     "is required",
+
+	"string.len":       "must be exactly %s character(s) long",
+	"string.min_len":   "must be at least %s character(s) long",
+	"string.max_len":   "must be at most %s character(s) long",
+	"len_bytes":        "must be exactly %s byte(s) long",
+	"min_bytes":        "must be at least %s byte(s) long",
+	"max_bytes":        "must be at most %s byte(s) long",
+	"bytes.len":        "must be exactly %s byte(s) long",
+	"bytes.min_len":    "must be at least %s byte(s) long",
+	"bytes.max_len":    "must be at most %s byte(s) long",
+	"pattern":          "must match the pattern %s",
+	"prefix":           "must start with %s",
+	"suffix":           "must end with %s",
+	"contains":         "must contain %s",
+	"not_contains":     "must not contain %s",
+	"email":            "must be a valid email address",
+	"hostname":         "must be a valid hostname",
+	"ip":               "must be a valid IP address",
+	"ipv4":             "must be a valid IPv4 address",
+	"ipv6":             "must be a valid IPv6 address",
+	"uri":              "must be a valid URI",
+	"uri_ref":          "must be a valid URI reference",
+	"address":          "must be a valid hostname or IP address",
+	"uuid":             "must be a valid UUID",
+	"tuuid":            "must be a valid UUID without dashes",
+	"ulid":             "must be a valid ULID",
+	"host_and_port":    "must be a valid host and port",
+	"ip_prefix":        "must be a valid IP prefix",
+	"ipv4_prefix":      "must be a valid IPv4 prefix",
+	"ipv6_prefix":      "must be a valid IPv6 prefix",
+	"finite":           "must be a finite number",
+	"well_known_regex": "must be a valid %s",
+
+	"min_items": "must have at least %s item(s)",
+	"max_items": "must have at most %s item(s)",
+	"unique":    "must not contain duplicate items",
+	"min_pairs": "must have at least %s entries",
+	"max_pairs": "must have at most %s entries",
+	"no_sparse": "must not have unset values",
+}
+
+// ruleLeaf is a single value set in the standard rules of a field, at its path
+// below (buf.validate.field) or (validate.rules), e.g. string.min_len.
+type ruleLeaf struct {
+	at    []string
+	value *proto.Literal
+	line  int
+}
+
+// ruleLeaves appends the values set by a (buf.validate.field) or
+// (validate.rules) option, descending its aggregates, to leaves. Cel rules are
+// left to literal.
+func (c *ruleCollector) ruleLeaves(leaves []ruleLeaf, option *proto.Option, at []string, value *proto.Literal) []ruleLeaf {
+	if slices.Contains(at, "cel") {
+		return leaves
+	}
+	if len(value.OrderedMap) > 0 && !isDuration(value) {
+		for _, named := range value.OrderedMap {
+			if named.Literal != nil {
+				leaves = c.ruleLeaves(leaves, option, append(slices.Clip(at), named.Name), named.Literal)
+			}
+		}
+		return leaves
+	}
+	line := value.Position.Line
+	if line == 0 {
+		line = option.Position.Line
+	}
+	return append(leaves, ruleLeaf{at: at, value: value, line: line})
+}
+
+// standardRules adds an entry of the kind for every rule among the leaves of
+// the field at path, as StandardRules does.
+func (c *ruleCollector) standardRules(path, keyPath, kind string, leaves []ruleLeaf) {
+	var rules []StandardRule
+	for _, leaf := range leaves {
+		for _, v := range literalValues(leaf.value) {
+			rules = append(rules, StandardRule{At: leaf.at, Value: c.ruleValue(v), Line: leaf.line})
+		}
+	}
+	for _, entry := range StandardRules(path, keyPath, kind, rules) {
+		entry.Package, entry.File = c.pkg, c.file
+		c.entries = append(c.entries, entry)
+	}
+}
+
+// StandardRule is a value set in the standard rules of a field, at its path
+// below (buf.validate.field) or (validate.rules), e.g. string.min_len, written
+// as in messages: strings in single quotes and durations such as 5s.
+type StandardRule struct {
+	At    []string
+	Value string
+	Line  int
+}
+
+// StandardRules returns an entry of the kind for every rule set on the field
+// at path, keyed <keyPath>.<rule>, keyPath being <Message>.<field> unless a
+// directive overrides it, with a default message derived from the rule and its
+// value. The values of a rule set several times, such as in, are joined. The
+// package and file of the entries are left to the caller.
+func StandardRules(path, keyPath, kind string, values []StandardRule) []Entry {
+	var rules []string
+	byRule := make(map[string][]StandardRule)
+	for _, v := range values {
+		rule := strings.Join(v.At, ".")
+		if byRule[rule] == nil {
+			rules = append(rules, rule)
+		}
+		byRule[rule] = append(byRule[rule], v)
+	}
+
+	var entries []Entry
+	field := path[strings.LastIndex(path, ".")+1:]
+	for _, rule := range rules {
+		group := byRule[rule]
+		typ, name, subject, ok := splitRule(group[0].At)
+		if !ok || slices.Contains(ruleModifiers, name[strings.LastIndex(name, ".")+1:]) {
+			continue
+		}
+		values := make([]string, 0, len(group))
+		for _, v := range group {
+			values = append(values, v.Value)
+		}
+		last := name[strings.LastIndex(name, ".")+1:]
+		text, ok := ruleMessages[typ+"."+last]
+		if !ok {
+			text, ok = ruleMessages[last]
+		}
+		var message string
+		switch {
+		case !ok:
+			message = "must satisfy " + rule
+		case strings.Contains(text, "%s"):
+			message = fmt.Sprintf(text, strings.Join(values, ", "))
+		case slices.Equal(values, []string{"false"}):
+			continue // a rule such as email = false is not enforced
+		default:
+			message = text
+		}
+		if subject != "" {
+			subject += " of "
+		}
+
+		key := keyPath + "." + name
+		entries = append(entries, Entry{
+			Key:        key,
+			Name:       key,
+			Kind:       kind,
+			Path:       path,
+			Message:    subject + field + " " + message,
+			Expression: rule + " = " + strings.Join(values, ", "),
+			Line:       group[0].Line,
+		})
+	}
+	return entries
+}
+
+// splitRule splits the path of a standard rule into the type it applies to, the
+// name of the rule written in keys and what it applies to other than the field:
+// the items of repeated fields, or the keys or values of maps. Rules such as
+// required apply to fields of any type.
+func splitRule(at []string) (typ, name, subject string, ok bool) {
+	switch {
+	case len(at) == 0:
+		return "", "", "", false
+	case len(at) == 1:
+		return "", at[0], "", true
+	case at[0] == "repeated" && at[1] == "items" && len(at) >= 4:
+		return at[2], "items." + strings.Join(at[3:], "."), "each item", true
+	case at[0] == "map" && (at[1] == "keys" || at[1] == "values") && len(at) >= 4:
+		return at[2], at[1] + "." + strings.Join(at[3:], "."), "each " + strings.TrimSuffix(at[1], "s"), true
+	}
+	return at[0], strings.Join(at[1:], "."), "", true
+}
+
+// literalValues returns the items of a list literal, or the literal itself.
+func literalValues(lit *proto.Literal) []*proto.Literal {
+	if len(lit.Array) > 0 {
+		return lit.Array
+	}
+	return []*proto.Literal{lit}
+}
+
+// ruleValue returns the value of a rule as written in its message: strings in
+// single quotes and durations such as {seconds: 5} as 5s.
+func (c *ruleCollector) ruleValue(lit *proto.Literal) string {
+	switch {
+	case isDuration(lit):
+		seconds, _ := lit.OrderedMap.Get("seconds")
+		nanos, _ := lit.OrderedMap.Get("nanos")
+		s, _ := strconv.ParseInt(seconds.Source, 10, 64)
+		n, _ := strconv.ParseInt(nanos.Source, 10, 64)
+		return (time.Duration(s)*time.Second + time.Duration(n)).String()
+	case lit.IsString:
+		return "'" + c.stringValue(lit) + "'"
+	}
+	return lit.Source
+}
+
+// isDuration reports whether a literal is a google.protobuf.Duration or
+// Timestamp written as an aggregate of seconds and nanos.
+func isDuration(lit *proto.Literal) bool {
+	if len(lit.OrderedMap) == 0 {
+		return false
+	}
+	for _, named := range lit.OrderedMap {
+		if named.Name != "seconds" && named.Name != "nanos" {
+			return false
+		}
+	}
+	return true
+}
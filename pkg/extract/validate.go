@@ -0,0 +1,212 @@
+package extract
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// fieldRulesOption is the option holding the buf.validate rules of a field.
+const fieldRulesOption = "(buf.validate.field)"
+
+// celRulePaths are the paths below (buf.validate.field) that hold cel rules: on
+// the field itself and on the items of repeated fields or the keys and values
+// of maps.
+var celRulePaths = [][]string{
+	{"cel"},
+	{"repeated", "items", "cel"},
+	{"map", "keys", "cel"},
+	{"map", "values", "cel"},
+}
+
+// ruleCollector collects the cel rules and standard rules of protovalidate,
+// the legacy protoc-gen-validate rules and the labels set on the fields of the
+// messages of a parsed file, however their options are written: as one option
+// per rule, a single aggregate or a mix of both, on one line or spread across
+// several.
+type ruleCollector struct {
+	pkg        string
+	file       string
+	lines      []string // source lines, to read the strings the parser mangles
+	suggestIDs bool
+	ruleIndex  map[string]int // number of cel rules seen per field path
+	entries    []Entry
+}
+
+// message collects the rules of the fields of a message, or of a group, and of
+// the messages nested in it.
+func (c *ruleCollector) message(enclosing []string, name string, elements []proto.Visitee) {
+	names := append(slices.Clip(enclosing), name)
+	for _, elem := range elements {
+		switch e := elem.(type) {
+		case *proto.Message:
+			c.message(names, e.Name, e.Elements)
+		case *proto.Group:
+			c.message(names, e.Name, e.Elements)
+		case *proto.Oneof:
+			for _, oneofElem := range e.Elements {
+				if field, ok := oneofElem.(*proto.OneOfField); ok {
+					c.field(names, field.Field)
+				}
+			}
+		case *proto.NormalField:
+			c.field(names, e.Field)
+		case *proto.MapField:
+			c.field(names, e.Field)
+		}
+	}
+}
+
+// field collects the label, cel rules, standard rules and PGV rules among the
+// options of a field, in order, unless its comments tell to ignore it. A key
+// directive replaces the <Message>.<field> part of the keys of its label and
+// standard rules.
+func (c *ruleCollector) field(messages []string, field *proto.Field) {
+	path := strings.Join(append(slices.Clip(messages), field.Name), ".")
+	directives, comment := declarationDirectives(field.Comment, field.InlineComment)
+	if directives.Ignore {
+		return
+	}
+	keyPath := path
+	if directives.Key != "" {
+		keyPath = directives.Key
+	}
+	var standard, pgv []ruleLeaf
+	for _, option := range field.Options {
+		name := strings.ReplaceAll(option.Name, " ", "")
+		if name == labelOption {
+			c.label(path, keyPath, comment, field, option)
+		} else if rest, ok := strings.CutPrefix(name, fieldRulesOption); ok {
+			c.literal(path, option, optionPath(rest), &option.Constant)
+			standard = c.ruleLeaves(standard, option, optionPath(rest), &option.Constant)
+		} else if rest, ok := strings.CutPrefix(name, pgvRulesOption); ok {
+			pgv = c.ruleLeaves(pgv, option, optionPath(rest), &option.Constant)
+		}
+	}
+	c.standardRules(path, keyPath, KindRule, standard)
+	c.standardRules(path, keyPath, KindPGV, pgv)
+}
+
+// optionPath splits the part of an option name after the extension, e.g.
+// .repeated.items.cel, into its fields.
+func optionPath(rest string) []string {
+	if rest = strings.TrimPrefix(rest, "."); rest == "" {
+		return nil
+	}
+	return strings.Split(rest, ".")
+}
+
+// literal descends the value of a (buf.validate.field) option found at the
+// given path below it, collecting every cel rule it reaches. A rule path may be
+// spread between the option name and the aggregate value, e.g.
+// (buf.validate.field).repeated = { items: { cel: {...} } }, and repeated rules
+// may be written as several fields or as a list.
+func (c *ruleCollector) literal(path string, option *proto.Option, at []string, value *proto.Literal) {
+	if len(value.Array) > 0 {
+		for _, item := range value.Array {
+			c.literal(path, option, at, item)
+		}
+		return
+	}
+	if slices.ContainsFunc(celRulePaths, func(p []string) bool { return slices.Equal(p, at) }) {
+		c.rule(path, option, value)
+		return
+	}
+	for _, named := range value.OrderedMap {
+		if named.Literal != nil && isCELRulePrefix(append(slices.Clip(at), named.Name)) {
+			c.literal(path, option, append(slices.Clip(at), named.Name), named.Literal)
+		}
+	}
+}
+
+// isCELRulePrefix reports whether a path below (buf.validate.field) may lead
+// to cel rules.
+func isCELRulePrefix(at []string) bool {
+	return slices.ContainsFunc(celRulePaths, func(p []string) bool {
+		return len(at) <= len(p) && slices.Equal(p[:len(at)], at)
+	})
+}
+
+// rule adds the entry of a cel rule of the field at path. A rule without an id
+// is skipped unless ids are suggested.
+func (c *ruleCollector) rule(path string, option *proto.Option, value *proto.Literal) {
+	c.ruleIndex[path]++
+	// The positions of options and aggregates are those of the token before
+	// them, so the rule is placed at its id, or else its first field
+	entry := Entry{Kind: KindCEL, Path: path, Package: c.pkg, File: c.file, Line: option.Position.Line}
+	if len(value.OrderedMap) > 0 && value.OrderedMap[0].Literal != nil {
+		entry.Line, entry.Column = value.OrderedMap[0].Position.Line, value.OrderedMap[0].Position.Column
+	}
+	if id, ok := value.OrderedMap.Get("id"); ok && id.Source != "" {
+		entry.Key = c.stringValue(id)
+		entry.Name = entry.Key
+		entry.Line, entry.Column = id.Position.Line, id.Position.Column
+	}
+	if message, ok := value.OrderedMap.Get("message"); ok {
+		entry.Message = c.stringValue(message)
+	}
+	if expression, ok := value.OrderedMap.Get("expression"); ok {
+		entry.Expression = c.stringValue(expression)
+	}
+	if entry.Name == "" && c.suggestIDs {
+		entry.Name = suggestID(path, c.ruleIndex[path]-1)
+		entry.Key = entry.Name
+		entry.Suggested = true
+	}
+	if entry.Name != "" {
+		c.entries = append(c.entries, entry)
+	}
+}
+
+// stringValue returns the text of a string literal as written between double
+// quotes, with its escapes kept. The parser splits single-quoted strings into
+// tokens, losing their spaces, and places them at their closing quote, so
+// these are read from the source line again and their double quotes escaped.
+func (c *ruleCollector) stringValue(lit *proto.Literal) string {
+	if !lit.IsString || lit.QuoteRune != '\'' || lit.Position.Line < 1 || lit.Position.Line > len(c.lines) {
+		return lit.Source
+	}
+	line := []rune(c.lines[lit.Position.Line-1])
+	end := lit.Position.Column - 1
+	if end < 0 || end >= len(line) || line[end] != '\'' {
+		return lit.Source
+	}
+	start := end - 1
+	for ; start >= 0; start-- {
+		if line[start] == '\'' && !escaped(line, start) {
+			break
+		}
+	}
+	if start < 0 {
+		return lit.Source
+	}
+
+	var b strings.Builder
+	text := line[start+1 : end]
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text):
+			if text[i+1] != '\'' {
+				b.WriteRune('\\')
+			}
+			i++
+			b.WriteRune(text[i])
+		case text[i] == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteRune(text[i])
+		}
+	}
+	return b.String()
+}
+
+// escaped reports whether the rune at i is preceded by an odd number of
+// backslashes.
+func escaped(line []rune, i int) bool {
+	n := 0
+	for i--; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
@@ -0,0 +1,840 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Field numbers of the messages of google/protobuf/compiler/plugin.proto and
+// google/protobuf/descriptor.proto read or written in plugin mode.
+const (
+	requestFileToGenerate = 1
+	requestParameter      = 2
+	requestProtoFile      = 15
+
+	responseError             = 1
+	responseSupportedFeatures = 2
+	responseFile              = 15
+	responseFileName          = 1
+	responseFileContent       = 15
+
+	fileName           = 1
+	filePackage        = 2
+	fileMessageType    = 4
+	fileEnumType       = 5
+	fileSourceCodeInfo = 9
+
+	messageName       = 1
+	messageField      = 2
+	messageNestedType = 3
+	messageEnumType   = 4
+
+	fieldDescriptorName    = 1
+	fieldDescriptorOptions = 8
+
+	enumName    = 1
+	enumValue   = 2
+	enumOptions = 3
+
+	enumOptionsAllowAlias = 2
+
+	enumValueName    = 1
+	enumValueNumber  = 2
+	enumValueOptions = 3
+
+	sourceCodeInfoLocation  = 1
+	locationPath            = 1
+	locationSpan            = 2
+	locationLeadingComment  = 3
+	locationTrailingComment = 4
+
+	// Extensions read from options: (buf.validate.field) on fields with its
+	// cel rules, and the options of proto/i18n/i18n.proto. Its standard rules
+	// and (validate.rules) are read by the schemas of descriptorrules.go.
+	extValidateField   = 1159
+	fieldRulesCEL      = 23
+	fieldRulesRepeated = 18
+	fieldRulesMap      = 19
+	repeatedRulesItems = 4
+	mapRulesKeys       = 4
+	mapRulesValues     = 5
+	ruleID             = 1
+	ruleMessage        = 2
+	ruleExpression     = 3
+	extCodeRange       = 1281
+	extGRPCCode        = 1282
+	extMsg             = 1283
+	extLabel           = 1284
+	translationLang    = 1
+	translationText    = 2
+)
+
+// legacyExtensions are the numbers of the options of proto/i18n/i18n.proto
+// before they were renumbered, by their current number. They are still read
+// from descriptors compiled against an older copy of the file.
+var legacyExtensions = map[int]int{extCodeRange: 50001, extGRPCCode: 50002, extMsg: 50003, extLabel: 50004}
+
+// isExtension reports whether a field of an options message is the extension
+// ext, under its current or legacy number.
+func isExtension(num, ext int) bool {
+	return num == ext || num == legacyExtensions[ext]
+}
+
+// setTranslation sets the default translation of an enum value into lang,
+// making the map if needed.
+func setTranslation(translations map[string]string, lang, text string) map[string]string {
+	if translations == nil {
+		translations = make(map[string]string)
+	}
+	translations[lang] = textutil.Escape(text)
+	return translations
+}
+
+// legacyMsgLanguages are the languages of the fields of i18n.Messages, which
+// (i18n.msg) set under its legacy number, by number.
+var legacyMsgLanguages = map[int]string{
+	1: "en", 2: "zh", 3: "ja", 4: "ko", 5: "fr", 6: "de", 7: "es", 8: "pt", 9: "it", 10: "ru",
+	11: "ar", 12: "hi", 13: "zh_hant", 14: "pt_br", 15: "vi", 16: "th", 17: "id", 18: "tr", 19: "nl", 20: "pl",
+}
+
+// pluginOptions are the parameters of a plugin run, given as comma separated
+// name=value pairs, e.g. --i18n-gen_opt=lang=en,lang=zh,format=jsonc.
+type pluginOptions struct {
+	langs      []string
+	format     string
+	emptyValue string
+	sortOrder  string
+	prefix     string
+	suffix     string
+	existing   string // directory of the current language files, merged into the output
+	manifest   string
+	versions   string // versionsUnify or versionsNamespace
+	// commentMessages uses the comments of enum values without a message as
+	// their source text.
+	commentMessages bool
+	// prefixNestedEnums prefixes the keys of the values of nested enums with
+	// the enclosing messages.
+	prefixNestedEnums bool
+	// enumAliases keeps every name of the values of enums with allow_alias.
+	enumAliases bool
+	// skipUnspecified and unspecified skip the sentinel values of enums.
+	skipUnspecified bool
+	unspecified     *regexp.Regexp
+	// enumRegex, enumExclude, keyRegex and keyExclude select the enums and
+	// keys extracted, as extract.Options does.
+	enumRegex, enumExclude *regexp.Regexp
+	keyRegex, keyExclude   *regexp.Regexp
+	// packages and excludePackages select the proto packages extracted.
+	packages, excludePackages []string
+}
+
+// parsePluginParameter parses the parameter of a CodeGeneratorRequest.
+func parsePluginParameter(parameter string) (pluginOptions, error) {
+	opts := pluginOptions{format: "toml", emptyValue: emptySource, commentMessages: true, sortOrder: sortSource, versions: versionsUnify}
+	for _, pair := range strings.Split(parameter, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		switch name {
+		case "lang":
+			opts.langs = append(opts.langs, value)
+		case "format":
+			opts.format = value
+		case "empty_value":
+			opts.emptyValue = value
+		case "comment_messages":
+			opts.commentMessages = value != "false"
+		case "prefix_nested_enums":
+			opts.prefixNestedEnums = value != "false"
+		case "package":
+			opts.packages = append(opts.packages, value)
+		case "exclude_package":
+			opts.excludePackages = append(opts.excludePackages, value)
+		case "skip_unspecified":
+			opts.skipUnspecified = value != "false"
+		case "unspecified_regex", "enum_regex", "enum_exclude", "key_regex", "key_exclude":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			switch name {
+			case "unspecified_regex":
+				opts.unspecified = re
+			case "enum_regex":
+				opts.enumRegex = re
+			case "enum_exclude":
+				opts.enumExclude = re
+			case "key_regex":
+				opts.keyRegex = re
+			case "key_exclude":
+				opts.keyExclude = re
+			}
+		case "enum_aliases":
+			if value != enumAliasesCanonical && value != enumAliasesAll {
+				return opts, fmt.Errorf("unknown enum aliases: %s", value)
+			}
+			opts.enumAliases = value == enumAliasesAll
+		case "sort":
+			opts.sortOrder = value
+		case "prefix":
+			opts.prefix = value
+		case "suffix":
+			opts.suffix = value
+		case "existing":
+			opts.existing = value
+		case "manifest":
+			opts.manifest = value
+		case "version_keys":
+			opts.versions = value
+		default:
+			return opts, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+	if len(opts.langs) == 0 {
+		opts.langs = []string{"en", "zh"}
+	}
+	return opts, nil
+}
+
+// isPluginInvocation reports whether the binary was invoked by protoc as
+// protoc-gen-i18n-gen.
+func isPluginInvocation() bool {
+	return strings.HasPrefix(filepath.Base(os.Args[0]), "protoc-gen-")
+}
+
+// pluginCommand implements the plugin command, reading a CodeGeneratorRequest
+// from stdin and writing a CodeGeneratorResponse with the language files to
+// stdout, for use from protoc or buf generate.
+func pluginCommand(_ *flag.FlagSet) func(args []string) error {
+	return func(_ []string) error {
+		request, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+		if _, err := os.Stdout.Write(runPlugin(request)); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+		return nil
+	}
+}
+
+// runPlugin answers an encoded CodeGeneratorRequest. Failures are reported in
+// the error field of the response, as protoc expects.
+func runPlugin(request []byte) []byte {
+	files, err := pluginFiles(request)
+	response := appendWireVarint(nil, responseSupportedFeatures, 1) // proto3 optional
+	if err != nil {
+		return appendWireBytes(response, responseError, []byte(err.Error()))
+	}
+	for _, name := range sortedFileNames(files) {
+		var file []byte
+		file = appendWireBytes(file, responseFileName, []byte(name))
+		file = appendWireBytes(file, responseFileContent, files[name])
+		response = appendWireBytes(response, responseFile, file)
+	}
+	return response
+}
+
+// pluginFiles generates the language files, and the manifest, for the files
+// to generate of a request. The files are written to a temporary directory,
+// seeded with the existing language files, and returned by name.
+func pluginFiles(request []byte) (map[string][]byte, error) {
+	fields, err := decodeWire(request)
+	if err != nil {
+		return nil, fmt.Errorf("decode request: %w", err)
+	}
+	var (
+		parameter   string
+		toGenerate  = make(map[string]bool)
+		descriptors [][]byte
+	)
+	for _, f := range fields {
+		switch f.num {
+		case requestFileToGenerate:
+			toGenerate[string(f.bytes)] = true
+		case requestParameter:
+			parameter = string(f.bytes)
+		case requestProtoFile:
+			descriptors = append(descriptors, f.bytes)
+		}
+	}
+	opts, err := parsePluginParameter(parameter)
+	if err != nil {
+		return nil, err
+	}
+	outFormat, ok := emit.Formats[opts.format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format: %s", opts.format)
+	}
+	if opts.sortOrder != sortSource && opts.sortOrder != sortAlpha {
+		return nil, fmt.Errorf("unknown sort order: %s", opts.sortOrder)
+	}
+
+	var parsed []extract.File
+	for _, descriptor := range descriptors {
+		entries, name, err := descriptorEntries(descriptor, extract.Options{
+			EnumPrefix:        opts.prefix,
+			EnumSuffix:        opts.suffix,
+			PrefixNestedEnums: opts.prefixNestedEnums,
+			EnumAliases:       opts.enumAliases,
+			SkipUnspecified:   opts.skipUnspecified,
+			Unspecified:       opts.unspecified,
+			EnumRegex:         opts.enumRegex,
+			EnumExclude:       opts.enumExclude,
+			KeyRegex:          opts.keyRegex,
+			KeyExclude:        opts.keyExclude,
+			Packages:          opts.packages,
+			ExcludePackages:   opts.excludePackages,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read descriptor: %w", err)
+		}
+		if toGenerate[name] {
+			parsed = append(parsed, extract.File{Path: name, Entries: entries})
+		}
+	}
+	entries, warnings, err := resolveVersions(extract.Merge(parsed), opts.versions)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		log.Printf("Warning: %s\n", warning)
+	}
+	entries = extract.Unique(entries)
+	if violations := checkCodeRanges(entries); len(violations) > 0 {
+		return nil, errors.New(strings.Join(violations, "\n"))
+	}
+	if err := applyEmptyValuePolicy(entries, opts.emptyValue, opts.commentMessages); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "i18n-gen-plugin")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	files := make(map[string][]byte)
+	for _, lang := range opts.langs {
+		langPath := outFormat.Path(tmp, lang)
+		if opts.existing != "" {
+			if data, err := os.ReadFile(outFormat.Path(opts.existing, lang)); err == nil {
+				if err := os.WriteFile(langPath, data, 0644); err != nil {
+					return nil, err
+				}
+			}
+		}
+		langEntries := seedTranslations(sortEntries(entries, opts.sortOrder, lang, false), lang, nil)
+		if err := outFormat.Generate(langEntries, lang, langPath); err != nil {
+			return nil, fmt.Errorf("generate %s: %w", filepath.Base(langPath), err)
+		}
+		data, err := os.ReadFile(langPath)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Base(langPath)] = data
+	}
+	if opts.manifest != "" {
+		manifestPath := filepath.Join(tmp, opts.manifest)
+		if err := writeManifest(entries, nil, nil, manifestPath); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		files[opts.manifest] = data
+	}
+	return files, nil
+}
+
+// sortedFileNames returns the names of the generated files in byte order.
+func sortedFileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sourceLocation is the line, leading and trailing comments of a declaration,
+// and the directives of its comments, which are left out of the comment.
+type sourceLocation struct {
+	line       int
+	comment    string
+	trailing   string
+	directives extract.Directives
+}
+
+// descriptorEntries extracts the entries of an encoded FileDescriptorProto,
+// like extract.FromFile does from source: enum values first, then the cel rules
+// with an id, each in declaration order. Lines and comments come from the
+// source code info, which protoc includes for the files to generate. The
+// enums and values are selected with the options of extract.Options.
+func descriptorEntries(descriptor []byte, opts extract.Options) ([]extract.Entry, string, error) {
+	fields, err := decodeWire(descriptor)
+	if err != nil {
+		return nil, "", err
+	}
+	var name, pkg string
+	locations := make(map[string]sourceLocation)
+	for _, f := range fields {
+		switch f.num {
+		case fileName:
+			name = string(f.bytes)
+		case filePackage:
+			pkg = string(f.bytes)
+		case fileSourceCodeInfo:
+			if err := readLocations(f.bytes, locations); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	if !opts.SelectPackage(pkg) {
+		return nil, name, nil
+	}
+	d := descriptorReader{file: name, pkg: pkg, opts: opts, locations: locations}
+	var messageIndex, enumIndex int
+	for _, f := range fields {
+		switch f.num {
+		case fileMessageType:
+			err = d.message(f.bytes, nil, []int{fileMessageType, messageIndex})
+			messageIndex++
+		case fileEnumType:
+			err = d.enum(f.bytes, nil, []int{fileEnumType, enumIndex})
+			enumIndex++
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	byLine := func(entries []extract.Entry) {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Line < entries[j].Line })
+	}
+	byLine(d.enums)
+	byLine(d.rules)
+	return opts.SelectKeys(append(d.enums, d.rules...)), name, nil
+}
+
+// readLocations indexes the locations of a SourceCodeInfo by path.
+func readLocations(info []byte, locations map[string]sourceLocation) error {
+	fields, err := decodeWire(info)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num != sourceCodeInfoLocation {
+			continue
+		}
+		locFields, err := decodeWire(f.bytes)
+		if err != nil {
+			return err
+		}
+		var path, span []uint64
+		var loc sourceLocation
+		for _, lf := range locFields {
+			var values []uint64
+			if lf.typ == wireBytes && (lf.num == locationPath || lf.num == locationSpan) {
+				if values, err = decodePackedVarints(lf.bytes); err != nil {
+					return err
+				}
+			} else if lf.typ == wireVarint {
+				values = []uint64{lf.varint}
+			}
+			switch lf.num {
+			case locationPath:
+				path = append(path, values...)
+			case locationSpan:
+				span = append(span, values...)
+			case locationLeadingComment:
+				loc.comment = leadingCommentText(string(lf.bytes))
+			case locationTrailingComment:
+				loc.trailing = leadingCommentText(string(lf.bytes))
+			}
+		}
+		loc.directives, loc.comment = extract.ParseDirectives(loc.comment)
+		if d, _ := extract.ParseDirectives(loc.trailing); d.Ignore || d.Key != "" || d.Group != "" {
+			loc.directives.Ignore = loc.directives.Ignore || d.Ignore
+			if d.Key != "" {
+				loc.directives.Key = d.Key
+			}
+			if d.Group != "" {
+				loc.directives.Group = d.Group
+			}
+		}
+		if len(span) > 0 {
+			loc.line = int(span[0]) + 1
+		}
+		locations[locationKey(path)] = loc
+	}
+	return nil
+}
+
+// locationKey formats a descriptor path as a map key.
+func locationKey[T int | uint64](path []T) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strconv.FormatUint(uint64(p), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// leadingCommentText returns the trimmed, non-empty lines of a leading comment
+// joined by newlines, as extract.FromFile does for parsed comments.
+func leadingCommentText(comment string) string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// descriptorReader collects the entries of one file descriptor.
+type descriptorReader struct {
+	file, pkg    string
+	opts         extract.Options
+	locations    map[string]sourceLocation
+	enums, rules []extract.Entry
+}
+
+// message reads the labels and rules of the fields of a DescriptorProto and recurses
+// into its nested messages and enums.
+func (d *descriptorReader) message(data []byte, names []string, path []int) error {
+	fields, err := decodeWire(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num == messageName {
+			names = append(append([]string{}, names...), string(f.bytes))
+		}
+	}
+	var fieldIndex, nestedIndex, enumIndex int
+	for _, f := range fields {
+		switch f.num {
+		case messageField:
+			err = d.field(f.bytes, names, append(append([]int{}, path...), messageField, fieldIndex))
+			fieldIndex++
+		case messageNestedType:
+			err = d.message(f.bytes, names, append(append([]int{}, path...), messageNestedType, nestedIndex))
+			nestedIndex++
+		case messageEnumType:
+			err = d.enum(f.bytes, names, append(append([]int{}, path...), messageEnumType, enumIndex))
+			enumIndex++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field reads the label declared with (i18n.label), the cel and standard rules
+// declared with (buf.validate.field) and the PGV rules declared with
+// (validate.rules) on a FieldDescriptorProto.
+func (d *descriptorReader) field(data []byte, messages []string, path []int) error {
+	fields, err := decodeWire(data)
+	if err != nil {
+		return err
+	}
+	var name string
+	var label *string
+	var rules, standard, pgv [][]byte
+	for _, f := range fields {
+		switch f.num {
+		case fieldDescriptorName:
+			name = string(f.bytes)
+		case fieldDescriptorOptions:
+			fieldRules, err := extensionMessages(f.bytes, extValidateField)
+			if err != nil {
+				return err
+			}
+			for _, fr := range fieldRules {
+				if rules, err = celRules(fr, rules); err != nil {
+					return err
+				}
+			}
+			standard = append(standard, fieldRules...)
+			pgvRules, err := extensionMessages(f.bytes, extPGVRules)
+			if err != nil {
+				return err
+			}
+			pgv = append(pgv, pgvRules...)
+			labels, err := extensionMessages(f.bytes, extLabel)
+			if err != nil {
+				return err
+			}
+			for _, l := range labels {
+				text := textutil.Escape(string(l))
+				label = &text
+			}
+		}
+	}
+
+	loc := d.locations[locationKey(path)]
+	if loc.directives.Ignore {
+		return nil
+	}
+	line := loc.line
+	fieldPath := strings.Join(append(append([]string{}, messages...), name), ".")
+	keyPath := fieldPath
+	if loc.directives.Key != "" {
+		keyPath = loc.directives.Key
+	}
+	if label != nil {
+		key := keyPath + ".label"
+		d.rules = append(d.rules, extract.Entry{Key: key, Name: key, Kind: extract.KindLabel, Path: fieldPath, Message: *label, Comment: loc.comment, Package: d.pkg, File: d.file, Line: line})
+	}
+	for _, rule := range rules {
+		ruleFields, err := decodeWire(rule)
+		if err != nil {
+			return err
+		}
+		entry := extract.Entry{Kind: extract.KindCEL, Path: fieldPath, Package: d.pkg, File: d.file, Line: line}
+		for _, rf := range ruleFields {
+			switch rf.num {
+			case ruleID:
+				entry.Key, entry.Name = string(rf.bytes), string(rf.bytes)
+			case ruleMessage:
+				entry.Message = textutil.Escape(string(rf.bytes))
+			case ruleExpression:
+				entry.Expression = textutil.Escape(string(rf.bytes))
+			}
+		}
+		if entry.Name != "" {
+			d.rules = append(d.rules, entry)
+		}
+	}
+	for _, set := range []struct {
+		kind     string
+		schema   ruleSchema
+		messages [][]byte
+	}{{extract.KindRule, standardRuleSchema, standard}, {extract.KindPGV, pgvRuleSchema, pgv}} {
+		var values []extract.StandardRule
+		for _, m := range set.messages {
+			if values, err = descriptorRules(m, set.schema, nil, line, values); err != nil {
+				return err
+			}
+		}
+		for _, entry := range extract.StandardRules(fieldPath, keyPath, set.kind, values) {
+			entry.Package, entry.File = d.pkg, d.file
+			d.rules = append(d.rules, entry)
+		}
+	}
+	return nil
+}
+
+// enum reads the values of an EnumDescriptorProto matching the prefix and
+// suffix filters, nested in the messages if any.
+func (d *descriptorReader) enum(data []byte, messages []string, path []int) error {
+	fields, err := decodeWire(data)
+	if err != nil {
+		return err
+	}
+	var name string
+	var codes *extract.CodeRange
+	var allowAlias bool
+	for _, f := range fields {
+		switch f.num {
+		case enumName:
+			name = string(f.bytes)
+		case enumOptions:
+			optionFields, err := decodeWire(f.bytes)
+			if err != nil {
+				return err
+			}
+			for _, of := range optionFields {
+				if of.num == enumOptionsAllowAlias && of.typ == wireVarint {
+					allowAlias = of.varint != 0
+				}
+			}
+			ranges, err := extensionMessages(f.bytes, extCodeRange)
+			if err != nil {
+				return err
+			}
+			for _, r := range ranges {
+				codes = &extract.CodeRange{}
+				rangeFields, err := decodeWire(r)
+				if err != nil {
+					return err
+				}
+				for _, rf := range rangeFields {
+					switch rf.num {
+					case 1:
+						codes.Min = int(int32(rf.varint))
+					case 2:
+						codes.Max = int(int32(rf.varint))
+					}
+				}
+			}
+		}
+	}
+	enumPath := strings.Join(append(slices.Clip(messages), name), ".")
+	if !d.opts.SelectEnum(name, enumPath) {
+		return nil
+	}
+
+	group := d.locations[locationKey(path)].directives.Group
+	canonical := make(map[int]string) // number -> key of its first value
+	valueIndex := 0
+	for _, f := range fields {
+		if f.num != enumValue {
+			continue
+		}
+		valueFields, err := decodeWire(f.bytes)
+		if err != nil {
+			return err
+		}
+		loc := d.locations[locationKey(append(append([]int{}, path...), enumValue, valueIndex))]
+		valueIndex++
+		if loc.directives.Ignore {
+			continue
+		}
+		entry := extract.Entry{Kind: extract.KindEnum, Path: enumPath, Comment: loc.comment, CodeRange: codes, Translations: extract.CommentTranslations(loc.trailing), Group: group, Package: d.pkg, File: d.file, Line: loc.line}
+		for _, vf := range valueFields {
+			switch vf.num {
+			case enumValueName:
+				entry.Key, entry.Name = string(vf.bytes), string(vf.bytes)
+			case enumValueNumber:
+				entry.Number = int(int32(vf.varint))
+			case enumValueOptions:
+				optionFields, err := decodeWire(vf.bytes)
+				if err != nil {
+					return err
+				}
+				for _, of := range optionFields {
+					switch {
+					case isExtension(of.num, extGRPCCode) && of.typ == wireVarint:
+						entry.GRPCCode = strconv.FormatUint(of.varint, 10)
+						if of.varint < uint64(len(grpcCodesByNumber)) {
+							entry.GRPCCode = grpcCodesByNumber[of.varint]
+						}
+					case isExtension(of.num, extMsg) && of.typ == wireBytes:
+						msgFields, err := decodeWire(of.bytes)
+						if err != nil {
+							return err
+						}
+						var lang, text string
+						for _, mf := range msgFields {
+							if mf.typ != wireBytes {
+								continue
+							}
+							if of.num == extMsg {
+								switch mf.num {
+								case translationLang:
+									lang = string(mf.bytes)
+								case translationText:
+									text = string(mf.bytes)
+								}
+							} else if legacyLang, ok := legacyMsgLanguages[mf.num]; ok {
+								entry.Translations = setTranslation(entry.Translations, legacyLang, string(mf.bytes))
+							}
+						}
+						if lang != "" {
+							entry.Translations = setTranslation(entry.Translations, lang, text)
+						}
+					}
+				}
+			}
+		}
+		if d.opts.SkipValue(entry.Name, entry.Number) {
+			continue
+		}
+		if loc.directives.Group != "" {
+			entry.Group = loc.directives.Group
+		}
+		switch {
+		case loc.directives.Key != "":
+			entry.Key = loc.directives.Key
+		case d.opts.PrefixNestedEnums && len(messages) > 0:
+			entry.Key = strings.Join(messages, ".") + "." + entry.Name
+		}
+		if first, aliased := canonical[entry.Number]; aliased {
+			if !d.opts.EnumAliases {
+				continue
+			}
+			entry.Canonical = first
+		} else if allowAlias {
+			canonical[entry.Number] = entry.Key
+		}
+		d.enums = append(d.enums, entry)
+	}
+	return nil
+}
+
+// celRules appends the cel rules of an encoded buf.validate.FieldRules to
+// rules, including those on the items of repeated fields and on the keys and
+// values of maps.
+func celRules(fieldRules []byte, rules [][]byte) ([][]byte, error) {
+	fields, err := decodeWire(fieldRules)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.typ != wireBytes {
+			continue
+		}
+		var nested []int
+		switch f.num {
+		case fieldRulesCEL:
+			rules = append(rules, f.bytes)
+		case fieldRulesRepeated:
+			nested = []int{repeatedRulesItems}
+		case fieldRulesMap:
+			nested = []int{mapRulesKeys, mapRulesValues}
+		}
+		if len(nested) == 0 {
+			continue
+		}
+		inner, err := decodeWire(f.bytes)
+		if err != nil {
+			return nil, err
+		}
+		for _, in := range inner {
+			for _, num := range nested {
+				if in.num == num && in.typ == wireBytes {
+					if rules, err = celRules(in.bytes, rules); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return rules, nil
+}
+
+// extensionMessages returns the encoded messages set on an options message
+// through the message-typed extension ext.
+func extensionMessages(options []byte, ext int) ([][]byte, error) {
+	fields, err := decodeWire(options)
+	if err != nil {
+		return nil, err
+	}
+	var messages [][]byte
+	for _, f := range fields {
+		if isExtension(f.num, ext) && f.typ == wireBytes {
+			messages = append(messages, f.bytes)
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+)
+
+// QA checks of a translation, with the share of a key's score a finding costs.
+const (
+	qaPlaceholders = "placeholders"
+	qaLength       = "length"
+	qaGlossary     = "glossary"
+	qaSpelling     = "spelling"
+	qaConsistency  = "consistency"
+)
+
+var qaWeights = map[string]float64{
+	qaPlaceholders: 1,
+	qaGlossary:     0.5,
+	qaLength:       0.25,
+	qaSpelling:     0.25,
+	qaConsistency:  0.25,
+}
+
+// qaPlaceholderRe matches the placeholders of every output format: {{.Name}},
+// {name}, {0}, { $name }, and printf style %s, %1$s, %d and %@.
+var qaPlaceholderRe = regexp.MustCompile(`\{\{\s*\.\w+\s*\}\}|\{[A-Za-z_]\w*\}|\{\d+\}|\{ *\$\w+ *\}|%(\d+\$)?[sd@]`)
+
+// qaWordRe matches the words checked for spelling.
+var qaWordRe = regexp.MustCompile(`\p{L}+(['’]\p{L}+)*`)
+
+// qaFinding is one problem found in a translation.
+type qaFinding struct {
+	Key     string `json:"key"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// qaReport is the quality of the translations of one language.
+type qaReport struct {
+	Score        float64     `json:"score"`
+	Translations int         `json:"translations"`
+	Findings     []qaFinding `json:"findings"`
+}
+
+// qaOptions configures the checks. A nil glossary or word list disables the
+// check using it.
+type qaOptions struct {
+	maxLengthRatio float64                      // longest translation relative to the source
+	glossary       map[string]map[string]string // term of the source language -> language -> translation
+	words          map[string]bool              // correctly spelled words, lower case
+}
+
+// qaCommand implements the qa command, which checks the translations of every
+// language against the source language and prints a quality score per language
+// with the findings behind it.
+func qaCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	sourceLang := fs.String("source-lang", "", "Language the others are translated from (default: first of -L)")
+	glossaryFile := fs.String("glossary", "", "JSON file mapping terms of the source language to their required translation by language (optional)")
+	wordsDir := fs.String("words", "", "Directory of <lang>.txt word lists, one word per line, to check spelling against (optional)")
+	maxLengthRatio := fs.Float64("max-length-ratio", 2, "Flag translations longer than this many times their source text (0 to disable)")
+	reportName := fs.String("report", "", "Write the scores and findings as JSON to this file (optional)")
+	minQuality := fs.Float64("min-quality", 0, "Fail when the score of a language is below this, from 0 to 100 (0 to disable)")
+
+	return func(_ []string) error {
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+
+		var glossary map[string]map[string]string
+		if *glossaryFile != "" {
+			data, err := os.ReadFile(*glossaryFile)
+			if err != nil {
+				return fmt.Errorf("failed to read glossary: %w", err)
+			}
+			if err := json.Unmarshal(data, &glossary); err != nil {
+				return fmt.Errorf("failed to parse glossary: %w", err)
+			}
+		}
+
+		source := defaultLanguage(*sourceLang, *languages)
+		sourceValues, err := loadLanguage(inFormat, inFormat.Path(*outputDir, source), nil)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", source, err)
+		}
+
+		reports := make(map[string]qaReport)
+		failed := false
+		for _, lang := range splitLanguages(*languages) {
+			if lang == source {
+				continue
+			}
+			langPath := inFormat.Path(*outputDir, lang)
+			translations, err := loadLanguage(inFormat, langPath, nil)
+			if err != nil {
+				log.Printf("Failed to load %s: %v\n", filepath.Base(langPath), err)
+				failed = true
+				continue
+			}
+			options := qaOptions{maxLengthRatio: *maxLengthRatio, glossary: glossary}
+			if *wordsDir != "" {
+				if options.words, err = loadWordList(filepath.Join(*wordsDir, lang+".txt")); err != nil {
+					return fmt.Errorf("failed to load word list: %w", err)
+				}
+			}
+
+			report := checkQuality(sourceValues, translations, lang, options)
+			reports[lang] = report
+			counts := make(map[string]int)
+			for _, f := range report.Findings {
+				counts[f.Check]++
+			}
+			var parts []string
+			for _, check := range []string{qaPlaceholders, qaGlossary, qaLength, qaSpelling, qaConsistency} {
+				parts = append(parts, fmt.Sprintf("%s %d", check, counts[check]))
+			}
+			fmt.Printf("%s: quality %.1f of %d translations; %s\n", lang, report.Score, report.Translations, strings.Join(parts, ", "))
+			for _, f := range report.Findings {
+				fmt.Printf("  %s: %s: %s\n", f.Key, f.Check, f.Message)
+			}
+			if *minQuality > 0 && report.Score < *minQuality {
+				log.Printf("%s: quality %.1f is below the minimum of %.1f\n", lang, report.Score, *minQuality)
+				failed = true
+			}
+		}
+
+		if *reportName != "" {
+			data, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to write QA report: %w", err)
+			}
+			if err := os.WriteFile(*reportName, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write QA report: %w", err)
+			}
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// checkQuality checks the translations of one language against their source
+// texts. The score is the share of translations without findings, from 0 to
+// 100, where each finding costs a key the weight of its check, at most all of
+// it. Empty translations are not scored.
+func checkQuality(sourceValues, translations map[string]string, lang string, options qaOptions) qaReport {
+	keys := make([]string, 0, len(translations))
+	for key, value := range translations {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	report := qaReport{Translations: len(keys), Findings: []qaFinding{}}
+	penalties := make(map[string]float64)
+	add := func(key, check, message string) {
+		report.Findings = append(report.Findings, qaFinding{Key: key, Check: check, Message: message})
+		penalties[key] = math.Min(1, penalties[key]+qaWeights[check])
+	}
+
+	bySource := make(map[string][]string) // source text -> keys translating it
+	for _, key := range keys {
+		text := textutil.Unescape(translations[key])
+		source, ok := sourceValues[key]
+		source = textutil.Unescape(source)
+		if ok && source != "" {
+			bySource[source] = append(bySource[source], key)
+
+			if missing, extra := placeholderDiff(source, text); len(missing) > 0 || len(extra) > 0 {
+				var problems []string
+				if len(missing) > 0 {
+					problems = append(problems, "missing "+strings.Join(missing, " "))
+				}
+				if len(extra) > 0 {
+					problems = append(problems, "unexpected "+strings.Join(extra, " "))
+				}
+				add(key, qaPlaceholders, strings.Join(problems, ", "))
+			}
+
+			sourceLen, textLen := utf8.RuneCountInString(source), utf8.RuneCountInString(text)
+			if options.maxLengthRatio > 0 && float64(textLen) > options.maxLengthRatio*float64(sourceLen) {
+				add(key, qaLength, fmt.Sprintf("%d characters, %.1f times the %d of the source", textLen, float64(textLen)/float64(sourceLen), sourceLen))
+			}
+
+			for _, term := range sortedKeys(options.glossary) {
+				required, ok := options.glossary[term][lang]
+				if !ok || !containsWord(source, term) || strings.Contains(strings.ToLower(text), strings.ToLower(required)) {
+					continue
+				}
+				add(key, qaGlossary, fmt.Sprintf("%q should be translated as %q", term, required))
+			}
+		}
+
+		words := qaWordRe.FindAllString(text, -1)
+		for i := 1; i < len(words); i++ {
+			if strings.EqualFold(words[i], words[i-1]) {
+				add(key, qaSpelling, fmt.Sprintf("repeated word %q", words[i]))
+			}
+		}
+		if options.words != nil {
+			var unknown []string
+			for _, word := range words {
+				if !options.words[strings.ToLower(word)] && !slices.Contains(unknown, word) {
+					unknown = append(unknown, word)
+				}
+			}
+			if len(unknown) > 0 {
+				add(key, qaSpelling, "unknown words "+strings.Join(unknown, ", "))
+			}
+		}
+	}
+
+	for _, source := range sortedKeys(bySource) {
+		group := bySource[source]
+		for _, key := range group[1:] {
+			if translations[key] != translations[group[0]] {
+				add(key, qaConsistency, fmt.Sprintf("translated differently from %s, which has the same source text", group[0]))
+			}
+		}
+	}
+
+	report.Score = 100
+	if len(keys) > 0 {
+		var penalty float64
+		for _, p := range penalties {
+			penalty += p
+		}
+		report.Score = math.Round(1000*(1-penalty/float64(len(keys)))) / 10
+	}
+	slices.SortStableFunc(report.Findings, func(a, b qaFinding) int { return strings.Compare(a.Key, b.Key) })
+	return report
+}
+
+// placeholderDiff returns the placeholders of the source text missing from the
+// translation and those of the translation the source does not have.
+func placeholderDiff(source, text string) (missing, extra []string) {
+	counts := make(map[string]int)
+	for _, p := range qaPlaceholderRe.FindAllString(source, -1) {
+		counts[p]++
+	}
+	for _, p := range qaPlaceholderRe.FindAllString(text, -1) {
+		if counts[p] > 0 {
+			counts[p]--
+		} else {
+			extra = append(extra, p)
+		}
+	}
+	for _, p := range qaPlaceholderRe.FindAllString(source, -1) {
+		if counts[p] > 0 {
+			counts[p]--
+			missing = append(missing, p)
+		}
+	}
+	return missing, extra
+}
+
+// containsWord reports whether text contains term as whole words, ignoring case.
+func containsWord(text, term string) bool {
+	text, term = strings.ToLower(text), strings.ToLower(term)
+	for i := 0; ; {
+		j := strings.Index(text[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsLetter(before) && !unicode.IsLetter(after) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// loadWordList reads a word list with one word per line, ignoring blank lines
+// and lines starting with #. It returns nil when the list does not exist.
+func loadWordList(filePath string) (map[string]bool, error) {
+	data, err := readFile(filePath, nil)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" && !strings.HasPrefix(word, "#") {
+			words[strings.ToLower(word)] = true
+		}
+	}
+	return words, scanner.Err()
+}
+
+// sortedKeys returns the keys of a map in byte order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
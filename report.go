@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// runReport summarizes a run of gen for tooling: how many proto files were
+// parsed and every failure, with its position for syntax errors.
+type runReport struct {
+	Files    int                    `json:"files"`
+	Parsed   int                    `json:"parsed"`           // files parsed without errors
+	Entries  int                    `json:"entries"`          // keys extracted, before deduplication
+	Groups   map[string]int         `json:"groups,omitempty"` // keys extracted by i18n-group
+	Failures []*extract.SyntaxError `json:"failures"`
+}
+
+// writeRunReport writes the report of the parsed files and their entries to a
+// JSON file. Errors other than syntax errors, such as unreadable files, are
+// reported without a position.
+func writeRunReport(parsed []extract.File, entries []extract.Entry, filePath string) error {
+	report := runReport{Files: len(parsed), Entries: len(entries), Failures: []*extract.SyntaxError{}}
+	for _, e := range entries {
+		if e.Group == "" {
+			continue
+		}
+		if report.Groups == nil {
+			report.Groups = make(map[string]int)
+		}
+		report.Groups[e.Group]++
+	}
+	for _, p := range parsed {
+		if p.Err == nil {
+			report.Parsed++
+			continue
+		}
+		syntaxErrs := extract.AsSyntaxErrors(p.Err)
+		if syntaxErrs == nil {
+			syntaxErrs = []*extract.SyntaxError{{File: p.Path, Message: p.Err.Error()}}
+		}
+		report.Failures = append(report.Failures, syntaxErrs...)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode run report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write run report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Review states of a translation, from least to most trusted.
+const (
+	reviewNew      = "new"
+	reviewMachine  = "machine"
+	reviewReviewed = "reviewed"
+	reviewFinal    = "final"
+)
+
+var reviewStates = []string{reviewNew, reviewMachine, reviewReviewed, reviewFinal}
+
+// reviewRank returns the position of a state in reviewStates, or -1.
+func reviewRank(state string) int {
+	for i, s := range reviewStates {
+		if s == state {
+			return i
+		}
+	}
+	return -1
+}
+
+// reviewStore holds the review state of every key by language and key. It is
+// kept next to the language files so that it survives regeneration.
+type reviewStore map[string]map[string]string
+
+// loadReviewStore reads a review file, returning an empty store when it does
+// not exist yet.
+func loadReviewStore(filePath string) (reviewStore, error) {
+	store := make(reviewStore)
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(filePath), err)
+	}
+	return store, nil
+}
+
+// writeReviewStore writes the store as JSON, with languages and keys sorted.
+func writeReviewStore(store reviewStore, filePath string) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, append(data, '\n'), 0644)
+}
+
+// syncReviewStore marks keys that are new to a language as new and forgets the
+// state of keys that are no longer extracted, or no longer ship in it.
+func syncReviewStore(store reviewStore, entries []extract.Entry, langs []string, namespaces namespaceLanguages) {
+	for _, lang := range langs {
+		states := store[lang]
+		if states == nil {
+			states = make(map[string]string)
+			store[lang] = states
+		}
+		keys := make(map[string]bool, len(entries))
+		for _, e := range namespaces.entries(entries, lang) {
+			keys[e.Key] = true
+			if states[e.Key] == "" {
+				states[e.Key] = reviewNew
+			}
+		}
+		for key := range states {
+			if !keys[key] {
+				delete(states, key)
+			}
+		}
+	}
+}
+
+// checkReviewStates reports every key of the languages whose review state is
+// below the required one.
+func checkReviewStates(store reviewStore, entries []extract.Entry, langs []string, namespaces namespaceLanguages, required string) []string {
+	var violations []string
+	for _, lang := range langs {
+		for _, e := range namespaces.entries(entries, lang) {
+			state := store[lang][e.Key]
+			if reviewRank(state) < reviewRank(required) {
+				violations = append(violations, fmt.Sprintf("%s:%d: %s translation of %s is %s, %s required", e.File, e.Line, lang, e.Key, state, required))
+			}
+		}
+	}
+	return violations
+}
+
+// splitLanguages returns the trimmed, non-empty languages of a -L value.
+func splitLanguages(languages string) []string {
+	var langs []string
+	for _, lang := range strings.Split(languages, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// reviewCommand implements the review command, which sets the review state of
+// the given keys, or of all keys when none is given. With -interactive, it
+// walks through the new, outdated and conflicting translations instead.
+func reviewCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	state := fs.String("state", reviewReviewed, "Review state to set (new, machine, reviewed, final)")
+	reviewName := fs.String("review", "", "Name of the review file in the output directory, as gen -review")
+	interactive := fs.Bool("interactive", false, "Walk through the new, outdated and conflicting translations of the given keys, or of all keys, showing the languages side by side to accept, edit or skip each")
+	ef := addExtractFlags(fs)
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns), for -interactive")
+	locksName := fs.String("locks", "locks.json", "Name of the locks file in the output directory (empty to disable), for -interactive")
+	modifiedName := fs.String("modified", "", "Name of the last-modified file in the output directory, to list outdated translations, for -interactive (optional)")
+
+	return func(keys []string) error {
+		if reviewRank(*state) < 0 {
+			return usageErrorf("unknown review state: %s", *state)
+		}
+		if *reviewName == "" {
+			return usageErrorf("missing -review")
+		}
+
+		reviewPath := filepath.Join(*outputDir, *reviewName)
+		store, err := loadReviewStore(reviewPath)
+		if err != nil {
+			return fmt.Errorf("failed to load review states: %w", err)
+		}
+
+		if *interactive {
+			outFormat, ok := emit.Formats[*format]
+			if !ok {
+				return usageErrorf("unknown format: %s", *format)
+			}
+			x, err := ef.extract(false, nil)
+			if err != nil {
+				return err
+			}
+			entries := x.entries
+			if err := applyEmptyValuePolicy(entries, emptySource, true); err != nil {
+				return err
+			}
+			session := &reviewSession{langs: splitLanguages(*languages), store: store, locks: make(lockStore), state: *state, changed: make(map[string]bool)}
+			if *locksName != "" {
+				if session.locks, err = loadLocks(filepath.Join(*outputDir, *locksName)); err != nil {
+					return fmt.Errorf("failed to load locks: %w", err)
+				}
+			}
+			if *modifiedName != "" {
+				if session.modified, err = loadModified(filepath.Join(*outputDir, *modifiedName)); err != nil {
+					return fmt.Errorf("failed to load modification times: %w", err)
+				}
+			}
+			if err := reviewInteractively(entries, keys, outFormat, *outputDir, session, os.Stdin, os.Stdout); err != nil {
+				return fmt.Errorf("failed to review: %w", err)
+			}
+			if session.modified != nil {
+				if err := writeModified(session.modified, filepath.Join(*outputDir, *modifiedName)); err != nil {
+					return fmt.Errorf("failed to write modification times: %w", err)
+				}
+			}
+			if err := writeReviewStore(store, reviewPath); err != nil {
+				return fmt.Errorf("failed to write review states: %w", err)
+			}
+			return nil
+		}
+
+		for _, lang := range splitLanguages(*languages) {
+			states := store[lang]
+			if len(keys) == 0 {
+				for key := range states {
+					states[key] = *state
+				}
+				continue
+			}
+			for _, key := range keys {
+				if _, ok := states[key]; !ok {
+					log.Printf("Warning: unknown key %s for %s\n", key, lang)
+					continue
+				}
+				states[key] = *state
+			}
+		}
+
+		if err := writeReviewStore(store, reviewPath); err != nil {
+			return fmt.Errorf("failed to write review states: %w", err)
+		}
+		return nil
+	}
+}
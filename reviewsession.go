@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Reasons a translation is brought up for review.
+const (
+	reasonNew      = "new"      // its review state is new
+	reasonOutdated = "outdated" // its source message changed since it was translated
+	reasonConflict = "conflict" // it differs from its signed-off value
+)
+
+// reviewItem is an extracted key with at least one translation to review, and
+// why each of them is brought up, by language.
+type reviewItem struct {
+	entry   extract.Entry
+	reasons map[string]string
+}
+
+// reviewSession holds what an interactive review reads and updates: the values
+// of the language files, their review states, locks and modification times.
+type reviewSession struct {
+	langs    []string
+	values   map[string]map[string]string // values by language and key
+	store    reviewStore
+	locks    lockStore
+	modified *modifiedStore // nil when modification times are not tracked
+	state    string         // review state set on accepted and edited translations
+	changed  map[string]bool
+}
+
+// reviewItems returns the entries, in order, with a new, outdated or
+// conflicting translation in any language. With keys, only those are listed.
+func (s *reviewSession) reviewItems(entries []extract.Entry, keys []string) []reviewItem {
+	outdated := make(map[string]map[string]time.Duration, len(s.langs))
+	if s.modified != nil {
+		for _, lang := range s.langs {
+			outdated[lang] = s.modified.outdated(lang)
+		}
+	}
+
+	var items []reviewItem
+	for _, e := range entries {
+		if len(keys) > 0 && !slices.Contains(keys, e.Key) {
+			continue
+		}
+		reasons := make(map[string]string)
+		for _, lang := range s.langs {
+			locked, isLocked := s.locks[lang][e.Key]
+			switch _, isOutdated := outdated[lang][e.Key]; {
+			case isLocked && s.values[lang][e.Key] != locked:
+				reasons[lang] = reasonConflict
+			case isOutdated:
+				reasons[lang] = reasonOutdated
+			case s.store[lang][e.Key] == "" || s.store[lang][e.Key] == reviewNew:
+				reasons[lang] = reasonNew
+			}
+		}
+		if len(reasons) > 0 {
+			items = append(items, reviewItem{entry: e, reasons: reasons})
+		}
+	}
+	return items
+}
+
+// run walks through the items, showing the translations of each side by side
+// and reading an action for it from in until every item is handled or the
+// reviewer quits. It returns the number of items accepted, edited and skipped.
+func (s *reviewSession) run(items []reviewItem, in io.Reader, out io.Writer) (accepted, edited, skipped int) {
+	input := bufio.NewScanner(in)
+	readLine := func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		if !input.Scan() {
+			fmt.Fprintln(out)
+			return "", false
+		}
+		return strings.TrimSpace(input.Text()), true
+	}
+
+	width := 0
+	for _, lang := range s.langs {
+		width = max(width, len(lang))
+	}
+
+	for i, item := range items {
+		e := item.entry
+		fmt.Fprintf(out, "\n[%d/%d] %s  %s:%d\n", i+1, len(items), e.Key, e.File, e.Line)
+		if e.Message != "" {
+			fmt.Fprintf(out, "  %-*s  %-8s  %s\n", width, "", "source", displayValue(e.Message))
+		}
+		for _, lang := range s.langs {
+			fmt.Fprintf(out, "  %-*s  %-8s  %s\n", width, lang, item.reasons[lang], displayValue(s.values[lang][e.Key]))
+		}
+
+	prompt:
+		for {
+			action, ok := readLine("(a)ccept, (e)dit, (s)kip, (q)uit? ")
+			if !ok {
+				return accepted, edited, skipped
+			}
+			switch strings.ToLower(action) {
+			case "a", "accept":
+				for lang := range item.reasons {
+					s.accept(lang, e.Key, s.values[lang][e.Key])
+				}
+				accepted++
+				break prompt
+			case "e", "edit":
+				for _, lang := range s.langs {
+					if item.reasons[lang] == "" {
+						continue
+					}
+					if _, isLocked := s.locks[lang][e.Key]; isLocked {
+						fmt.Fprintf(out, "  %s is locked; keeping its signed-off value\n", lang)
+						s.accept(lang, e.Key, s.values[lang][e.Key])
+						continue
+					}
+					value, ok := readLine(fmt.Sprintf("  %-*s> ", width, lang))
+					if !ok {
+						return accepted, edited, skipped
+					}
+					if value == "" {
+						value = s.values[lang][e.Key]
+					}
+					s.accept(lang, e.Key, value)
+				}
+				edited++
+				break prompt
+			case "s", "skip":
+				skipped++
+				break prompt
+			case "q", "quit":
+				return accepted, edited, skipped
+			}
+		}
+	}
+	return accepted, edited, skipped
+}
+
+// accept sets the translation of a key to value, or to its signed-off value
+// when the key is locked, and marks it reviewed and up to date with its source.
+func (s *reviewSession) accept(lang, key, value string) {
+	if locked, ok := s.locks[lang][key]; ok {
+		value = locked
+	}
+	if s.values[lang][key] != value {
+		s.values[lang][key] = value
+		s.changed[lang] = true
+	}
+	if s.store[lang] == nil {
+		s.store[lang] = make(map[string]string)
+	}
+	s.store[lang][key] = s.state
+	if s.modified != nil {
+		s.modified.confirm(lang, key, value, time.Now().UTC().Truncate(time.Second))
+	}
+}
+
+// displayValue returns a value on a single line, with its line breaks escaped.
+func displayValue(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, "\r", `\r`), "\n", `\n`)
+}
+
+// reviewInteractively runs an interactive review of the language files in
+// outputDir against the entries and writes the reviewed translations back as
+// import does, along with their review states and modification times.
+func reviewInteractively(entries []extract.Entry, keys []string, outFormat emit.Format, outputDir string, s *reviewSession, in io.Reader, out io.Writer) error {
+	s.values = make(map[string]map[string]string, len(s.langs))
+	for _, lang := range s.langs {
+		langPath := outFormat.Path(outputDir, lang)
+		values, err := loadLanguage(outFormat, langPath, nil)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", filepath.Base(langPath), err)
+		}
+		s.values[lang] = values
+	}
+
+	items := s.reviewItems(entries, keys)
+	if len(items) == 0 {
+		log.Printf("Nothing to review.")
+		return nil
+	}
+	accepted, edited, skipped := s.run(items, in, out)
+
+	for _, lang := range s.langs {
+		if !s.changed[lang] {
+			continue
+		}
+		langPath := outFormat.Path(outputDir, lang)
+		if err := outFormat.Write(importEntries(entries, s.values[lang]), lang, langPath, true, emit.Encoding{}, nil); err != nil {
+			return fmt.Errorf("write %s: %w", filepath.Base(langPath), err)
+		}
+	}
+	log.Printf("Reviewed %d of %d key(s): %d accepted, %d edited, %d skipped.", accepted+edited+skipped, len(items), accepted, edited, skipped)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"sort"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+const (
+	sortSource = "source"
+	sortAlpha  = "alpha"
+)
+
+// sortEntries returns the entries in the order selected for a language file:
+// declaration order, or alphabetical by key. Alphabetical order uses byte order
+// unless collated is set, in which case the collation rules of lang apply.
+func sortEntries(entries []extract.Entry, order, lang string, collated bool) []extract.Entry {
+	if order != sortAlpha {
+		return entries
+	}
+
+	sorted := append([]extract.Entry(nil), entries...)
+	if !collated {
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+		return sorted
+	}
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		tag = language.Und
+	}
+	collator := collate.New(tag)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return collator.CompareString(sorted[i].Key, sorted[j].Key) < 0
+	})
+	return sorted
+}
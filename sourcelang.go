@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Modes of updating the values of a language file.
+const (
+	modePreserve  = "preserve"  // keep existing translations, add missing keys
+	modeOverwrite = "overwrite" // replace the values with those derived from the protos
+)
+
+// languageMode returns the update mode of lang: sourceMode for the source
+// language and derivedMode for the others.
+func languageMode(lang, sourceLang, sourceMode, derivedMode string) (string, error) {
+	mode := derivedMode
+	if lang == sourceLang {
+		mode = sourceMode
+	}
+	if mode != modePreserve && mode != modeOverwrite {
+		return "", usageErrorf("unknown update mode: %s", mode)
+	}
+	return mode, nil
+}
+
+// overwriteLanguage prepares a language file to be regenerated from the
+// protos: it returns copies of entries whose fallback is the proto's message,
+// or the existing translation where the proto has none or the key is locked.
+// The file must then be written fresh so that the fallbacks are used.
+func overwriteLanguage(entries []extract.Entry, outFormat emit.Format, langPath string, locked map[string]string) ([]extract.Entry, error) {
+	existing, err := loadLanguage(outFormat, langPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	overwritten := make([]extract.Entry, len(entries))
+	for i, e := range entries {
+		value, ok := existing[e.Key]
+		_, isLocked := locked[e.Key]
+		switch {
+		case ok && (e.Message == "" || isLocked):
+			e.Fallback = value
+		case e.Message != "":
+			e.Fallback = e.Message
+		}
+		overwritten[i] = e
+	}
+	return overwritten, nil
+}
+
+// defaultLanguage returns the language of the default resources: the source
+// language if set, else the first language.
+func defaultLanguage(sourceLang, languages string) string {
+	if sourceLang != "" {
+		return sourceLang
+	}
+	if langs := splitLanguages(languages); len(langs) > 0 {
+		return langs[0]
+	}
+	return ""
+}
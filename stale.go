@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// What happens to the keys of a language file that are no longer extracted
+// from the protos.
+const (
+	staleKeep    = "keep"    // written like the other keys, with their translations
+	staleComment = "comment" // written commented out
+	stalePrune   = "prune"   // dropped
+	staleArchive = "archive" // moved to a language file of the archive directory
+)
+
+// kindStale marks entries for keys kept from an existing language file.
+const kindStale = "stale"
+
+// staleVerbs describes what each stale mode does, for the log.
+var staleVerbs = map[string]string{staleKeep: "keeping", staleComment: "commenting out", stalePrune: "pruning", staleArchive: "archiving"}
+
+// commentFormats lists the formats able to write stale keys commented out.
+var commentFormats = map[string]bool{"toml": true}
+
+// staleEntries returns entries for the keys of an existing language file that
+// are not among the entries, in key order, with their current value as
+// fallback so that they keep it whatever the file's update mode. Keys commented
+// out are marked as removed on the given date.
+func staleEntries(entries []extract.Entry, existing map[string]string, langPath, mode, today string) []extract.Entry {
+	keys := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		keys[e.Key] = true
+	}
+	var stale []extract.Entry
+	for key, value := range existing {
+		if keys[key] {
+			continue
+		}
+		entry := extract.Entry{
+			Key:      key,
+			Name:     key,
+			Kind:     kindStale,
+			Path:     kindStale,
+			Comment:  "No longer extracted from the protos",
+			Fallback: value,
+			File:     langPath,
+		}
+		if mode == staleComment {
+			entry.Comment, entry.Commented = "removed on "+today, true
+		}
+		stale = append(stale, entry)
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Key < stale[j].Key })
+	return stale
+}
+
+// archiveStale moves stale keys to the language file of the archive, which
+// keeps the keys archived before unless they are among the entries again. The
+// file is only created once there is something to archive.
+func archiveStale(stale, entries []extract.Entry, outFormat emit.Format, lang, archivePath string, enc emit.Encoding) error {
+	archived, err := loadLanguage(outFormat, archivePath, nil)
+	if err != nil {
+		return fmt.Errorf("load archive: %w", err)
+	}
+	if _, err := os.Stat(archivePath); os.IsNotExist(err) && len(stale) == 0 {
+		return nil
+	}
+	kept := staleEntries(append(entries[:len(entries):len(entries)], stale...), archived, archivePath, staleKeep, "")
+	all := append(stale[:len(stale):len(stale)], kept...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Key < all[j].Key })
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return fmt.Errorf("create archive directory: %w", err)
+	}
+	return outFormat.Write(all, lang, archivePath, false, enc, nil)
+}
+
+// entryKeys returns the keys of the entries.
+func entryKeys(entries []extract.Entry) []string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/internal/toml"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// kindStatic marks entries read from a static keys file rather than a proto.
+const kindStatic = "static"
+
+// loadStaticKeys reads a supplemental keys file for strings that are not
+// derived from protos, such as UI chrome. It uses the layout of the TOML
+// language files, with the value as the key's default message and the comment
+// lines above a key as its comment:
+//
+//	# Label of the button submitting a form
+//	[ui.submit]
+//	other = "Submit"
+//
+// Entries are returned in file order, with the file's base name as path.
+func loadStaticKeys(filePath string, events *eventStream) ([]extract.Entry, error) {
+	data, err := readFile(filePath, events)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _, err := toml.Parse(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	var entries []extract.Entry
+	for _, item := range items {
+		switch {
+		case item.Header:
+			entries = append(entries, extract.Entry{
+				Key:     item.Key,
+				Name:    item.Key,
+				Kind:    kindStatic,
+				Path:    path,
+				Comment: strings.Join(item.Comments, " "),
+				File:    filePath,
+				Line:    item.Line,
+			})
+		case item.Key != "other" || len(entries) == 0:
+			return nil, fmt.Errorf("%s:%d: expected a [key] table or other = \"message\"", filePath, item.Line)
+		default:
+			entries[len(entries)-1].Message = textutil.Escape(item.Value)
+		}
+	}
+	return entries, nil
+}
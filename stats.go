@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// statsCommand implements the stats command, which prints per language how
+// many keys are translated, how many are outdated and how many are in each
+// review state.
+func statsCommand(fs *flag.FlagSet) func(args []string) error {
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	reviewName := fs.String("review", "", "Name of the review file in the output directory, to count the keys in each review state (optional)")
+	modifiedName := fs.String("modified", "", "Name of the last-modified file in the output directory, to count the translations older than their source message (optional)")
+	listOutdated := fs.Bool("outdated", false, "List the translations older than their source message, most outdated first, with -modified")
+	namespaceLangsFile := fs.String("namespace-langs", "", "CODEOWNERS-style file restricting the keys of proto package patterns to a subset of the languages; counts the keys each language ships (optional)")
+	emptyValue := fs.String("empty-value", emptySource, "Value gen writes for keys without a translation, which are not counted as translated (blank, key, source, todo-prefix)")
+	commentMessages := fs.Bool("comment-messages", true, "As gen -comment-messages, for -empty-value source and todo-prefix")
+	sourceLang := fs.String("source-lang", "", "Language whose values come from the protos, as gen -source-lang (optional)")
+	sourceMode := fs.String("source-mode", modeOverwrite, "How gen updates the source language file (overwrite, preserve); every key of an overwritten file counts as translated")
+	derivedMode := fs.String("derived-mode", modePreserve, "How gen updates the other language files (preserve, overwrite)")
+	ef := addExtractFlags(fs)
+
+	return func(_ []string) error {
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		if *listOutdated && *modifiedName == "" {
+			return usageErrorf("invalid -outdated: the modification times are kept in the last-modified file, set -modified")
+		}
+
+		var err error
+		var store reviewStore
+		if *reviewName != "" {
+			if store, err = loadReviewStore(filepath.Join(*outputDir, *reviewName)); err != nil {
+				return fmt.Errorf("failed to load review states: %w", err)
+			}
+		}
+
+		var modified *modifiedStore
+		if *modifiedName != "" {
+			if modified, err = loadModified(filepath.Join(*outputDir, *modifiedName)); err != nil {
+				return fmt.Errorf("failed to load modification times: %w", err)
+			}
+		}
+
+		var namespaces namespaceLanguages
+		if *namespaceLangsFile != "" {
+			if namespaces, err = loadNamespaceLanguages(*namespaceLangsFile); err != nil {
+				return fmt.Errorf("failed to load namespace languages: %w", err)
+			}
+		}
+		x, err := ef.extract(false, nil)
+		if err != nil {
+			return err
+		}
+		if err := applyEmptyValuePolicy(x.entries, *emptyValue, *commentMessages); err != nil {
+			return err
+		}
+
+		failed := false
+		for _, lang := range splitLanguages(*languages) {
+			langPath := inFormat.Path(*outputDir, lang)
+			translations, err := loadLanguage(inFormat, langPath, nil)
+			if err != nil {
+				log.Printf("Failed to load %s: %v\n", filepath.Base(langPath), err)
+				failed = true
+				continue
+			}
+			mode, err := languageMode(lang, *sourceLang, *sourceMode, *derivedMode)
+			if err != nil {
+				return err
+			}
+			// Only the keys the language ships count, missing ones as untranslated
+			translated, total := languageCoverage(namespaces.entries(x.entries, lang), translations, lang, mode == modeOverwrite)
+			line := fmt.Sprintf("%s: %d/%d translated", lang, translated, total)
+			var outdated map[string]time.Duration
+			if modified != nil {
+				outdated = modified.outdated(lang)
+				line += fmt.Sprintf(", %d outdated", len(outdated))
+			}
+			if store != nil {
+				counts := make(map[string]int)
+				for _, state := range store[lang] {
+					counts[state]++
+				}
+				parts := make([]string, 0, len(reviewStates))
+				for _, state := range reviewStates {
+					parts = append(parts, fmt.Sprintf("%s %d", state, counts[state]))
+				}
+				line += "; " + strings.Join(parts, ", ")
+			}
+			fmt.Println(line)
+			if *listOutdated {
+				keys := make([]string, 0, len(outdated))
+				for key := range outdated {
+					keys = append(keys, key)
+				}
+				sort.Slice(keys, func(i, j int) bool {
+					if outdated[keys[i]] != outdated[keys[j]] {
+						return outdated[keys[i]] > outdated[keys[j]]
+					}
+					return keys[i] < keys[j]
+				})
+				for _, key := range keys {
+					fmt.Printf("  %s: source changed %s ago\n", key, outdated[key].Round(time.Minute))
+				}
+			}
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// languageCoverage returns how many of the keys of a language file are
+// translated once it is written, and how many keys it has: every key is
+// translated when the file is overwritten from the protos, else those with a
+// value in the existing file other than the one written for missing
+// translations, or a default translation. Aliases and commented out keys are
+// left out. The -header of gen and stats both count with it.
+func languageCoverage(entries []extract.Entry, existing map[string]string, lang string, overwrite bool) (int, int) {
+	total, translated := 0, 0
+	for _, e := range entries {
+		if e.Alias != "" || e.Commented {
+			continue
+		}
+		total++
+		value := existing[e.Key]
+		if _, seeded := optionTranslation(e.Translations, lang); overwrite || seeded || (value != "" && value != e.Fallback) {
+			translated++
+		}
+	}
+	return translated, total
+}
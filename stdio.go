@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// stdioPath, given to -P or -O, reads the proto from standard input or writes
+// the language file to standard output.
+const stdioPath = "-"
+
+// stdinName names the proto read from standard input in entries and errors.
+const stdinName = "<stdin>"
+
+// readStdinProto extracts the entries of a proto read from standard input,
+// which only the proto extractor can read.
+func readStdinProto(in io.Reader, opts extract.Options) extract.File {
+	if opts.Extractor != "" && opts.Extractor != extract.DefaultExtractor {
+		return extract.File{Path: stdinName, Err: fmt.Errorf("the %s extractor cannot read standard input", opts.Extractor)}
+	}
+	data, err := io.ReadAll(in)
+	if err == nil {
+		data, _, err = textutil.Decode(data)
+	}
+	if err != nil {
+		return extract.File{Path: stdinName, Err: fmt.Errorf("read standard input: %w", err)}
+	}
+	entries, err := extract.FromSource(stdinName, data, opts)
+	return extract.File{Path: stdinName, Entries: entries, Err: err}
+}
+
+// writeStdout copies a generated language file to standard output.
+func writeStdout(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
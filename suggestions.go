@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// writeSuggestions writes, for every validation rule whose id was derived, the
+// location of the rule and the id line to paste into the proto.
+func writeSuggestions(entries []extract.Entry, filePath string) error {
+	var buffer bytes.Buffer
+	for _, e := range entries {
+		if !e.Suggested {
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("# %s:%d %s\nid: \"%s\"\n\n", e.File, e.Line, e.Path, e.Name))
+	}
+
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write suggestions file: %w", err)
+	}
+	return nil
+}
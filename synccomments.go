@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// syncCommentsCommand implements the sync-comments command, which writes the
+// source-language translation of every enum value back into the proto as the
+// value's leading comment.
+func syncCommentsCommand(fs *flag.FlagSet) func(args []string) error {
+	var protoPatterns stringList
+	fs.Var(&protoPatterns, "P", "Proto files: a glob, ** matching any number of directories, e.g. api/**/*.proto, a directory or a file; may be repeated (default "+defaultProtoPattern+")")
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	sourceLang := fs.String("L", "en", "Language whose translations are written into the proto comments")
+	enumPrefix := fs.String("prefix", "", "Only process enums with this prefix (optional)")
+	enumSuffix := fs.String("suffix", "", "Only process enums with this suffix (optional)")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+
+	return func(_ []string) error {
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+
+		protoFiles, err := findProtoFiles(protoPatterns)
+		if err != nil {
+			return fmt.Errorf("failed to find proto files: %w", err)
+		}
+
+		langPath := inFormat.Path(*outputDir, *sourceLang)
+		translations, err := loadLanguage(inFormat, langPath, nil)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", filepath.Base(langPath), err)
+		}
+
+		failed := false
+		for _, protoFile := range protoFiles {
+			entries, err := extract.FromFile(protoFile, extract.Options{EnumPrefix: *enumPrefix, EnumSuffix: *enumSuffix})
+			if err != nil {
+				log.Printf("Failed to parse proto file %s: %v\n", protoFile, err)
+				failed = true
+				continue
+			}
+			changed, err := syncCommentsFile(protoFile, entries, translations)
+			if err != nil {
+				log.Printf("Failed to update %s: %v\n", protoFile, err)
+				failed = true
+				continue
+			}
+			if changed > 0 {
+				log.Printf("%s updated with %d comment(s).", protoFile, changed)
+			}
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// syncCommentsFile replaces the leading comment of every enum value in the file
+// that has a translation, returning the number of values whose comment changed.
+func syncCommentsFile(filePath string, entries []extract.Entry, translations map[string]string) (int, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("stat proto file: %w", err)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("read proto file: %w", err)
+	}
+	lines := strings.Split(string(data), "\n")
+
+	var values []extract.Entry
+	for _, e := range entries {
+		if e.Kind == extract.KindEnum && translations[e.Key] != "" {
+			values = append(values, e)
+		}
+	}
+	// Edit from the bottom up so earlier line numbers stay valid
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Line > values[j].Line })
+
+	changed := 0
+	for _, e := range values {
+		idx := e.Line - 1
+		if idx < 0 || idx >= len(lines) {
+			continue
+		}
+		line := strings.TrimSuffix(lines[idx], "\r")
+		eol := lines[idx][len(line):]
+		comment := leadingSpace(line) + "// " + translations[e.Key] + eol
+
+		// Replace the contiguous // comment lines directly above the value
+		start := idx
+		for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "//") {
+			start--
+		}
+		if start == idx-1 && lines[start] == comment {
+			continue
+		}
+		lines = append(lines[:start], append([]string{comment}, lines[idx:]...)...)
+		changed++
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+	if err := os.WriteFile(filePath, []byte(strings.Join(lines, "\n")), info.Mode()); err != nil {
+		return 0, fmt.Errorf("write proto file: %w", err)
+	}
+	return changed, nil
+}
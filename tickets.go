@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Issue trackers tickets can be created in.
+const (
+	trackerGitHub = "github"
+	trackerJira   = "jira"
+)
+
+// Ways of grouping the keys of tickets.
+const (
+	ticketPerLanguage  = "language"  // a ticket per language, listing its keys
+	ticketPerNamespace = "namespace" // a ticket per i18n-group or else proto package, listing its keys and their languages
+)
+
+const (
+	defaultTicketTitle = `{{len .Keys}} new i18n key(s) to translate {{if .Language}}into {{.Language}}{{else}}in {{.Namespace}}{{end}}`
+	defaultTicketBody  = `{{range .Keys}}- ` + "`{{.Key}}`" + `{{if .Source}}: {{.Source}}{{end}} ({{.File}}:{{.Line}}{{if $.Namespace}}; {{join .Languages ", "}}{{end}})
+{{if .Comment}}  {{.Comment}}
+{{end}}{{end}}`
+)
+
+// ticketConfig configures the tickets created for new untranslated keys.
+type ticketConfig struct {
+	Tracker   string   `json:"tracker"`    // github or jira
+	URL       string   `json:"url"`        // API URL of the repository for GitHub, e.g. https://api.github.com/repos/acme/app, base URL of the site for Jira
+	Project   string   `json:"project"`    // key of the Jira project
+	IssueType string   `json:"issue_type"` // Jira issue type, Task by default
+	TokenEnv  string   `json:"token_env"`  // environment variable holding the token; email:token for Jira Cloud
+	Group     string   `json:"group"`      // language or namespace
+	Title     string   `json:"title"`      // text/template of the title
+	Body      string   `json:"body"`       // text/template of the body
+	Labels    []string `json:"labels"`
+
+	title, body *template.Template
+}
+
+// ticketKey is a key listed in a ticket.
+type ticketKey struct {
+	Key       string
+	Source    string // default message from the proto
+	Comment   string
+	Package   string
+	Group     string // group set with an i18n-group comment tag
+	File      string
+	Line      int
+	Languages []string // languages the key is untranslated in
+}
+
+// ticketData is what the templates of a ticket render: the keys of one
+// language, or of one namespace. Group is set when the namespace is that of
+// an i18n-group comment tag rather than a proto package.
+type ticketData struct {
+	Language  string
+	Namespace string
+	Group     string
+	Keys      []ticketKey
+}
+
+// loadTicketConfig reads a JSON ticket configuration and parses its
+// templates.
+func loadTicketConfig(filePath string) (*ticketConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	config := &ticketConfig{Group: ticketPerLanguage, IssueType: "Task", Title: defaultTicketTitle, Body: defaultTicketBody}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	switch {
+	case config.Tracker != trackerGitHub && config.Tracker != trackerJira:
+		return nil, fmt.Errorf("%s: unknown tracker %q", filePath, config.Tracker)
+	case config.Group != ticketPerLanguage && config.Group != ticketPerNamespace:
+		return nil, fmt.Errorf("%s: unknown group %q", filePath, config.Group)
+	case config.URL == "":
+		return nil, fmt.Errorf("%s: missing url", filePath)
+	case config.Tracker == trackerJira && config.Project == "":
+		return nil, fmt.Errorf("%s: missing project", filePath)
+	}
+	funcs := template.FuncMap{"join": strings.Join}
+	if config.title, err = template.New("title").Funcs(funcs).Parse(config.Title); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if config.body, err = template.New("body").Funcs(funcs).Parse(config.Body); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return config, nil
+}
+
+// newUntranslatedKeys returns the entries that were not in the language file
+// before the run, and so only have their fallback, unless the language is the
+// source language and they have a value after the run.
+func newUntranslatedKeys(entries []extract.Entry, before, after map[string]string, source bool) []extract.Entry {
+	var keys []extract.Entry
+	for _, e := range entries {
+		if _, ok := before[e.Key]; ok || e.Alias != "" {
+			continue
+		}
+		if value, ok := after[e.Key]; ok && (!source || value == "") {
+			keys = append(keys, e)
+		}
+	}
+	return keys
+}
+
+// ticketsFor groups the new untranslated keys of every language into the
+// tickets to create, in the order of the languages or of the keys.
+func (c *ticketConfig) ticketsFor(langs []string, untranslated map[string][]extract.Entry) []ticketData {
+	var tickets []ticketData
+	if c.Group == ticketPerLanguage {
+		for _, lang := range langs {
+			if len(untranslated[lang]) == 0 {
+				continue
+			}
+			ticket := ticketData{Language: lang}
+			for _, e := range untranslated[lang] {
+				ticket.Keys = append(ticket.Keys, newTicketKey(e, lang))
+			}
+			tickets = append(tickets, ticket)
+		}
+		return tickets
+	}
+
+	index := make(map[string]int)    // namespace -> ticket
+	keyIndex := make(map[string]int) // key -> index in its ticket
+	for _, lang := range langs {
+		for _, e := range untranslated[lang] {
+			namespace := e.Package
+			switch {
+			case e.Group != "":
+				namespace = e.Group
+			case namespace == "":
+				namespace = "(no package)"
+			}
+			i, ok := index[namespace]
+			if !ok {
+				i = len(tickets)
+				index[namespace] = i
+				tickets = append(tickets, ticketData{Namespace: namespace, Group: e.Group})
+			}
+			if j, ok := keyIndex[e.Key]; ok {
+				tickets[i].Keys[j].Languages = append(tickets[i].Keys[j].Languages, lang)
+				continue
+			}
+			keyIndex[e.Key] = len(tickets[i].Keys)
+			tickets[i].Keys = append(tickets[i].Keys, newTicketKey(e, lang))
+		}
+	}
+	return tickets
+}
+
+// newTicketKey describes an entry untranslated in a language for a ticket.
+func newTicketKey(e extract.Entry, lang string) ticketKey {
+	return ticketKey{
+		Key:       e.Key,
+		Source:    textutil.Unescape(e.Message),
+		Comment:   e.Comment,
+		Package:   e.Package,
+		Group:     e.Group,
+		File:      e.File,
+		Line:      e.Line,
+		Languages: []string{lang},
+	}
+}
+
+// createTicket renders a ticket and creates it in the tracker, returning its
+// URL.
+func (c *ticketConfig) createTicket(ticket ticketData) (string, error) {
+	var title, body bytes.Buffer
+	if err := c.title.Execute(&title, ticket); err != nil {
+		return "", fmt.Errorf("render title: %w", err)
+	}
+	if err := c.body.Execute(&body, ticket); err != nil {
+		return "", fmt.Errorf("render body: %w", err)
+	}
+	summary := strings.TrimSpace(title.String())
+	labels := append([]string{}, c.Labels...)
+	if ticket.Group != "" {
+		labels = append(labels, ticket.Group)
+	}
+
+	var payload any
+	endpoint := strings.TrimSuffix(c.URL, "/")
+	if c.Tracker == trackerGitHub {
+		endpoint += "/issues"
+		payload = map[string]any{"title": summary, "body": body.String(), "labels": labels}
+	} else {
+		endpoint += "/rest/api/2/issue"
+		payload = map[string]any{"fields": map[string]any{
+			"project":     map[string]string{"key": c.Project},
+			"issuetype":   map[string]string{"name": c.IssueType},
+			"summary":     summary,
+			"description": body.String(),
+			"labels":      labels,
+		}}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if token := os.Getenv(c.TokenEnv); c.TokenEnv != "" && token != "" {
+		if user, password, ok := strings.Cut(token, ":"); ok && c.Tracker == trackerJira {
+			req.SetBasicAuth(user, password)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"` // GitHub
+		Key     string `json:"key"`      // Jira
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("%s: %w", endpoint, err)
+	}
+	if created.Key != "" {
+		return strings.TrimSuffix(c.URL, "/") + "/browse/" + created.Key, nil
+	}
+	return created.HTMLURL, nil
+}
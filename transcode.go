@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+)
+
+// transcoded remembers the files already reported as transcoded, since a run
+// may read the same file several times, and those written in another encoding
+// than UTF-8 on purpose.
+var transcoded sync.Map
+
+// warnTranscoded warns once per file that it was transcoded from the named
+// encoding, if any.
+func warnTranscoded(events *eventStream, filePath, encoding string) {
+	if encoding != "" {
+		if _, seen := transcoded.LoadOrStore(filePath, true); !seen {
+			events.warnf("%s is %s encoded; transcoded to UTF-8\n", filePath, encoding)
+		}
+	}
+}
+
+// expectTranscoding keeps a file from being warned about, for the language
+// files written with a UTF-16 -encoding.
+func expectTranscoding(filePath string) {
+	transcoded.Store(filePath, true)
+}
+
+// readFile reads a proto or other input file as UTF-8 as textutil.ReadFile
+// does, warning when it was transcoded from another encoding.
+func readFile(filePath string, events *eventStream) ([]byte, error) {
+	data, encoding, err := textutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	warnTranscoded(events, filePath, encoding)
+	return data, nil
+}
+
+// loadLanguage reads the translations of a language file as the Load of its
+// format does, warning when it was transcoded from another encoding.
+func loadLanguage(format emit.Format, filePath string, events *eventStream) (map[string]string, error) {
+	translations, err := format.Load(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, seen := transcoded.Load(filePath); !seen {
+		if encoding, err := emit.FileEncoding(filePath); err == nil {
+			warnTranscoded(events, filePath, encoding)
+		}
+	}
+	return translations, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+// When unset they are taken from the build info embedded by the go command.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// latestVersionURL is the Go module proxy endpoint returning the latest release.
+const latestVersionURL = "https://proxy.golang.org/github.com/protoc-gen/i18n-gen/@latest"
+
+// versionCommand implements the version command, which prints the build
+// information and optionally checks for a newer release.
+func versionCommand(fs *flag.FlagSet) func(args []string) error {
+	checkUpdate := fs.Bool("check-update", false, "Check whether a newer release is available")
+
+	return func(_ []string) error {
+		v, c, d := buildInfo()
+		fmt.Printf("i18n-gen %s (commit %s, built %s, %s)\n", v, c, d, runtime.Version())
+
+		if !*checkUpdate {
+			return nil
+		}
+		latest, err := latestVersion()
+		if err != nil {
+			return fmt.Errorf("failed to check for updates: %w", err)
+		}
+		if compareVersions(latest, v) > 0 {
+			fmt.Printf("A newer release is available: %s (go install github.com/protoc-gen/i18n-gen@%s)\n", latest, latest)
+		} else {
+			fmt.Println("You are running the latest release.")
+		}
+		return nil
+	}
+}
+
+// buildInfo returns the version, commit and build date of the binary.
+func buildInfo() (string, string, string) {
+	v, c, d := version, commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && c == "":
+				c = setting.Value
+			case setting.Key == "vcs.time" && d == "":
+				d = setting.Value
+			}
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	if c == "" {
+		c = "unknown"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+	return v, c, d
+}
+
+// latestVersion asks the Go module proxy for the latest release.
+func latestVersion() (string, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(latestVersionURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var latest struct {
+		Version string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	return latest.Version, nil
+}
+
+// compareVersions compares the major, minor and patch numbers of two vX.Y.Z
+// versions. A version that cannot be parsed, such as (devel), sorts first.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the major, minor and patch numbers of a vX.Y.Z version,
+// or -1s when it cannot be parsed.
+func versionParts(v string) [3]int {
+	parts := [3]int{-1, -1, -1}
+	if !strings.HasPrefix(v, "v") {
+		return parts
+	}
+	v, _, _ = strings.Cut(v[1:], "-")
+	v, _, _ = strings.Cut(v, "+")
+	for i, field := range strings.SplitN(v, ".", 3) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return [3]int{-1, -1, -1}
+		}
+		parts[i] = n
+	}
+	return parts
+}
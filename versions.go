@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// How keys extracted from several versions of a package, such as user.v1 and
+// user.v1beta1, are told apart.
+const (
+	versionsUnify     = "unify"     // one key, from the most stable and latest version
+	versionsNamespace = "namespace" // a key per version, prefixed with the version
+)
+
+// protoVersionRe matches the version segment ending a proto package, e.g. v1,
+// v2beta1 or v1alpha.
+var protoVersionRe = regexp.MustCompile(`^v(\d+)(?:(alpha|beta)(\d*))?$`)
+
+// packageVersion splits a proto package into the package without its version
+// and the version, which is empty for unversioned packages.
+func packageVersion(pkg string) (base, version string) {
+	i := strings.LastIndex(pkg, ".")
+	if !protoVersionRe.MatchString(pkg[i+1:]) {
+		return pkg, ""
+	}
+	if i < 0 {
+		return "", pkg
+	}
+	return pkg[:i], pkg[i+1:]
+}
+
+// comparePackageVersions orders package versions by major version, then stability,
+// alpha before beta before stable, then the number of the pre-release.
+func comparePackageVersions(a, b string) int {
+	rank := func(version string) [3]int {
+		m := protoVersionRe.FindStringSubmatch(version)
+		major, _ := strconv.Atoi(m[1])
+		stability := map[string]int{"alpha": 0, "beta": 1, "": 2}[m[2]]
+		number, _ := strconv.Atoi(m[3])
+		return [3]int{major, stability, number}
+	}
+	ra, rb := rank(a), rank(b)
+	for i := range ra {
+		if ra[i] != rb[i] {
+			return ra[i] - rb[i]
+		}
+	}
+	return 0
+}
+
+// resolveVersions handles keys extracted from more than one version of the same
+// package. With versionsUnify, only the entries of the most stable and latest
+// of those versions are kept, with a warning for every dropped entry whose
+// message differs; with versionsNamespace, the keys of all of them are
+// prefixed with their version, e.g. v1beta1.USER_NOT_FOUND. Other entries are
+// left alone.
+func resolveVersions(entries []extract.Entry, mode string) ([]extract.Entry, []string, error) {
+	if mode != versionsUnify && mode != versionsNamespace {
+		return nil, nil, usageErrorf("unknown version keys mode: %s", mode)
+	}
+
+	best := make(map[string]extract.Entry) // key and package without version -> entry kept
+	versions := make(map[string]string)    // key and package without version -> first version seen
+	repeated := make(map[string]bool)      // key and package without version -> found in several versions
+	for _, e := range entries {
+		base, version := packageVersion(e.Package)
+		if version == "" {
+			continue
+		}
+		group := e.Key + "\x00" + base
+		if first, ok := versions[group]; !ok {
+			versions[group] = version
+		} else if first != version {
+			repeated[group] = true
+		}
+		if kept, ok := best[group]; !ok || comparePackageVersions(version, versionOf(kept)) > 0 {
+			best[group] = e
+		}
+	}
+
+	var (
+		resolved []extract.Entry
+		warnings []string
+	)
+	for _, e := range entries {
+		base, version := packageVersion(e.Package)
+		group := e.Key + "\x00" + base
+		switch {
+		case version == "" || !repeated[group]:
+		case mode == versionsNamespace:
+			e.Key = version + "." + e.Key
+		case versionOf(best[group]) != version:
+			if kept := best[group]; e.Message != kept.Message || e.Comment != kept.Comment {
+				warnings = append(warnings, fmt.Sprintf("%s:%d: %s of %s differs from %s of %s (%s:%d), which is kept", e.File, e.Line, e.Key, e.Package, kept.Key, kept.Package, kept.File, kept.Line))
+			}
+			continue
+		}
+		resolved = append(resolved, e)
+	}
+	return resolved, warnings, nil
+}
+
+// versionOf returns the version of the package of an entry.
+func versionOf(e extract.Entry) string {
+	_, version := packageVersion(e.Package)
+	return version
+}
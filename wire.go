@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// wireField is one field of an encoded protobuf message. Only the value
+// matching its wire type is set.
+type wireField struct {
+	num    int
+	typ    int
+	varint uint64
+	bytes  []byte
+}
+
+// decodeWire splits an encoded protobuf message into its fields, in the order
+// they appear. Nested messages are left encoded in bytes.
+func decodeWire(b []byte) ([]wireField, error) {
+	var fields []wireField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("malformed field tag")
+		}
+		b = b[n:]
+		f := wireField{num: int(tag >> 3), typ: int(tag & 7)}
+		switch f.typ {
+		case wireVarint:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("malformed varint in field %d", f.num)
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.bytes, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", f.typ, f.num)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// decodePackedVarints returns the values of a packed repeated varint field.
+func decodePackedVarints(b []byte) ([]uint64, error) {
+	var values []uint64
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("malformed packed varint")
+		}
+		values, b = append(values, v), b[n:]
+	}
+	return values, nil
+}
+
+// appendWireVarint appends a varint field to b.
+func appendWireVarint(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendWireBytes appends a length-delimited field, such as a string or an
+// encoded message, to b.
+func appendWireBytes(b []byte, num int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// celBlockRe matches the opening of a cel rule, as an option, e.g.
+// (buf.validate.field).cel = {, or within an aggregate, e.g. cel: [{.
+var celBlockRe = regexp.MustCompile(`\bcel\s*[:=]?\s*\[?\s*\{`)
+
+// writeBack inserts derived ids and missing messages into the cel blocks of the
+// proto files the entries were extracted from, and the missing translations
+// into lang of enum values as (i18n.msg) options. messages holds the text used
+// for rules and values that declare none, keyed by entry key.
+func writeBack(entries []extract.Entry, messages map[string]string, lang string, events *eventStream) error {
+	edits := make(map[string][]extract.Entry)
+	var files []string
+	for _, e := range entries {
+		switch e.Kind {
+		case extract.KindCEL:
+			if !e.Suggested && (e.Message != "" || messages[e.Key] == "") {
+				continue
+			}
+		case extract.KindEnum:
+			if _, ok := optionTranslation(e.Translations, lang); ok || messages[e.Key] == "" {
+				continue
+			}
+		default:
+			continue
+		}
+		if edits[e.File] == nil {
+			files = append(files, e.File)
+		}
+		edits[e.File] = append(edits[e.File], e)
+	}
+
+	for _, file := range files {
+		if err := writeBackFile(file, edits[file], messages, lang); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		events.infof("%s updated with %d validation rule(s) and enum value(s).", file, len(edits[file]))
+	}
+	return nil
+}
+
+// writeBackFile applies the edits for a single proto file, leaving every other
+// line untouched.
+func writeBackFile(filePath string, entries []extract.Entry, messages map[string]string, lang string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("stat proto file: %w", err)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read proto file: %w", err)
+	}
+	lines := strings.Split(string(data), "\n")
+
+	// Edit from the bottom up so earlier line numbers stay valid
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Line > entries[j].Line })
+	for _, e := range entries {
+		idx := e.Line - 1
+		if idx < 0 || idx >= len(lines) {
+			continue
+		}
+		if e.Kind == extract.KindEnum {
+			option := fmt.Sprintf("(i18n.msg) = {lang: %q, text: \"%s\"}", lang, textutil.Normalize(messages[e.Key]))
+			lines = insertValueOption(lines, idx, option)
+			continue
+		}
+
+		var fields []string
+		if e.Suggested {
+			fields = append(fields, fmt.Sprintf("id: %q", e.Name))
+		}
+		if e.Message == "" && messages[e.Key] != "" {
+			fields = append(fields, fmt.Sprintf("message: \"%s\"", textutil.Normalize(messages[e.Key])))
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		line := strings.TrimSuffix(lines[idx], "\r")
+		eol := lines[idx][len(line):]
+
+		// Single-line block: insert right after the opening brace
+		if loc := celBlockRe.FindStringIndex(line); loc != nil && !strings.HasSuffix(strings.TrimSpace(line), "{") {
+			at := loc[1]
+			if runes := []rune(line); e.Column > 0 && e.Column <= len(runes) {
+				// The brace opening the rule is the last one before its field
+				if brace := strings.LastIndex(string(runes[:e.Column-1]), "{"); brace >= 0 {
+					at = brace + 1
+				}
+			}
+			lines[idx] = line[:at] + strings.Join(fields, ", ") + ", " + strings.TrimLeft(line[at:], " ") + eol
+			continue
+		}
+
+		indent := blockIndent(lines, idx)
+		inserted := make([]string, len(fields))
+		for i, field := range fields {
+			inserted[i] = indent + field + eol
+		}
+		lines = append(lines[:idx+1], append(inserted, lines[idx+1:]...)...)
+	}
+
+	if err := os.WriteFile(filePath, []byte(strings.Join(lines, "\n")), info.Mode()); err != nil {
+		return fmt.Errorf("write proto file: %w", err)
+	}
+	return nil
+}
+
+// insertValueOption adds an option to the enum value declared from lines[idx]:
+// after the last of its options, on a line of its own if they are written one
+// per line, or in brackets before the semicolon ending it.
+func insertValueOption(lines []string, idx int, option string) []string {
+	for i := idx; i < len(lines); i++ {
+		end, ok := statementEnd(lines[i])
+		if !ok {
+			continue
+		}
+		before := strings.TrimRight(lines[i][:end], " \t")
+		bracket := len(before) - 1
+		switch {
+		case !strings.HasSuffix(before, "]"):
+			lines[i] = before + " [" + option + "]" + lines[i][end:]
+		case strings.TrimSpace(before[:bracket]) != "" || i == idx:
+			lines[i] = before[:bracket] + ", " + option + lines[i][bracket:]
+		default:
+			// The bracket closes options written one per line
+			prev := lines[i-1]
+			code, _ := statementEnd(prev)
+			code = len(strings.TrimRight(prev[:code], " \t"))
+			lines[i-1] = prev[:code] + "," + prev[code:]
+			inserted := leadingSpace(prev) + option
+			return append(lines[:i], append([]string{inserted}, lines[i:]...)...)
+		}
+		return lines
+	}
+	return lines
+}
+
+// statementEnd returns the index of the semicolon ending a statement on a line,
+// outside strings and comments, and whether there is one. Without one, the
+// index is that of the comment ending the line, or its length.
+func statementEnd(line string) (int, bool) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && strings.HasPrefix(line[i:], "//"):
+			return i, false
+		case c == ';':
+			return i, true
+		}
+	}
+	return len(line), false
+}
+
+// blockIndent returns the indentation for a field inserted after lines[idx]: the
+// indentation of the line itself if it is a field of the block, otherwise that
+// of the block's first field (or the opening line's plus two spaces).
+func blockIndent(lines []string, idx int) string {
+	indent := leadingSpace(lines[idx])
+	if !strings.HasSuffix(strings.TrimSpace(lines[idx]), "{") {
+		return indent
+	}
+	if idx+1 < len(lines) {
+		if next := leadingSpace(lines[idx+1]); len(next) > len(indent) {
+			return next
+		}
+	}
+	return indent + "  "
+}
+
+// leadingSpace returns the leading whitespace of a line.
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
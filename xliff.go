@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/protoc-gen/i18n-gen/internal/textutil"
+	"github.com/protoc-gen/i18n-gen/pkg/emit"
+	"github.com/protoc-gen/i18n-gen/pkg/extract"
+)
+
+// Segment states of XLIFF 2.0 by review state, and back.
+var (
+	xliffStates = map[string]string{
+		reviewNew:      "initial",
+		reviewMachine:  "translated",
+		reviewReviewed: "reviewed",
+		reviewFinal:    "final",
+	}
+	reviewStatesByXLIFF = map[string]string{
+		"initial":    reviewNew,
+		"translated": reviewMachine,
+		"reviewed":   reviewReviewed,
+		"final":      reviewFinal,
+	}
+)
+
+// xliffExportCommand implements the xliff-export command, which writes an
+// XLIFF 2.0 file per target language for CAT tools, with the review state of
+// every translation as segment state.
+func xliffExportCommand(fs *flag.FlagSet) func(args []string) error {
+	ef := addExtractFlags(fs)
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	languages := fs.String("L", "en,zh", "Comma-separated list of languages")
+	sourceLang := fs.String("source-lang", "en", "Language of the source text")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	reviewName := fs.String("review", "", "Name of the review file in the output directory, whose states are exported as segment states (optional)")
+	xliffDir := fs.String("o", "./xliff/", "Directory the <lang>.xlf files are written to")
+
+	return func(_ []string) error {
+		inFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		x, err := ef.extract(false, nil)
+		if err != nil {
+			return err
+		}
+		entries := x.entries
+		sources, err := loadLanguage(inFormat, inFormat.Path(*outputDir, *sourceLang), nil)
+		if err != nil {
+			return fmt.Errorf("failed to load source language: %w", err)
+		}
+		store := make(reviewStore)
+		if *reviewName != "" {
+			if store, err = loadReviewStore(filepath.Join(*outputDir, *reviewName)); err != nil {
+				return fmt.Errorf("failed to load review states: %w", err)
+			}
+		}
+		if err := os.MkdirAll(*xliffDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		failed := false
+		for _, lang := range splitLanguages(*languages) {
+			if lang == *sourceLang {
+				continue
+			}
+			langPath := inFormat.Path(*outputDir, lang)
+			targets, err := loadLanguage(inFormat, langPath, nil)
+			if err != nil {
+				log.Printf("Failed to load %s: %v\n", filepath.Base(langPath), err)
+				failed = true
+				continue
+			}
+			xliffPath := filepath.Join(*xliffDir, lang+".xlf")
+			if err := writeXLIFF(entries, *sourceLang, lang, sources, targets, store[lang], xliffPath); err != nil {
+				log.Printf("Failed to write %s: %v\n", filepath.Base(xliffPath), err)
+				failed = true
+				continue
+			}
+			log.Printf("%s exported successfully.", filepath.Base(xliffPath))
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// writeXLIFF writes one unit per entry, with the source text taken from the
+// source language, or the proto's message or key, and the proto location,
+// comment and group as notes.
+func writeXLIFF(entries []extract.Entry, srcLang, trgLang string, sources, targets, states map[string]string, filePath string) error {
+	var buffer bytes.Buffer
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	buffer.WriteString(fmt.Sprintf("<xliff xmlns=\"urn:oasis:names:tc:xliff:document:2.0\" version=\"2.0\" srcLang=\"%s\" trgLang=\"%s\">\n", textutil.EscapeXML(srcLang), textutil.EscapeXML(trgLang)))
+	buffer.WriteString("  <file id=\"f1\">\n")
+	for _, entry := range entries {
+		source := sources[entry.Key]
+		if source == "" {
+			source = entry.Message
+		}
+		if source == "" {
+			source = textutil.Escape(entry.Key)
+		}
+		target := targets[entry.Key]
+		state := xliffStates[states[entry.Key]]
+		if state == "" {
+			state = xliffStates[reviewNew]
+			if target != "" {
+				state = xliffStates[reviewMachine]
+			}
+		}
+
+		buffer.WriteString(fmt.Sprintf("    <unit id=\"%s\">\n", textutil.EscapeXML(entry.Key)))
+		buffer.WriteString("      <notes>\n")
+		buffer.WriteString(fmt.Sprintf("        <note category=\"location\">%s:%d</note>\n", textutil.EscapeXML(entry.File), entry.Line))
+		if entry.Comment != "" {
+			buffer.WriteString(fmt.Sprintf("        <note category=\"description\">%s</note>\n", textutil.EscapeXML(entry.Comment)))
+		}
+		if entry.Group != "" {
+			buffer.WriteString(fmt.Sprintf("        <note category=\"group\">%s</note>\n", textutil.EscapeXML(entry.Group)))
+		}
+		buffer.WriteString("      </notes>\n")
+		buffer.WriteString(fmt.Sprintf("      <segment state=\"%s\">\n", state))
+		buffer.WriteString(fmt.Sprintf("        <source>%s</source>\n", textutil.EscapeXML(textutil.Unescape(source))))
+		if target != "" {
+			buffer.WriteString(fmt.Sprintf("        <target>%s</target>\n", textutil.EscapeXML(textutil.Unescape(target))))
+		}
+		buffer.WriteString("      </segment>\n")
+		buffer.WriteString("    </unit>\n")
+	}
+	buffer.WriteString("  </file>\n</xliff>\n")
+	return os.WriteFile(filePath, buffer.Bytes(), 0644)
+}
+
+// xliffUnit is a translated unit read from an XLIFF 2.0 file.
+type xliffUnit struct {
+	key    string
+	target string
+	state  string
+}
+
+// loadXLIFF reads the target language and the units with a target of an
+// XLIFF 2.0 file.
+func loadXLIFF(filePath string) (string, []xliffUnit, error) {
+	data, err := readFile(filePath, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	var doc struct {
+		TrgLang string `xml:"trgLang,attr"`
+		Files   []struct {
+			Units []struct {
+				ID       string `xml:"id,attr"`
+				Segments []struct {
+					State  string `xml:"state,attr"`
+					Target *struct {
+						Text string `xml:",chardata"`
+					} `xml:"target"`
+				} `xml:"segment"`
+			} `xml:"unit"`
+		} `xml:"file"`
+	}
+	if err := textutil.UnmarshalXML(data, &doc); err != nil {
+		return "", nil, fmt.Errorf("parse XLIFF: %w", err)
+	}
+	if doc.TrgLang == "" {
+		return "", nil, fmt.Errorf("%s has no trgLang", filePath)
+	}
+	var units []xliffUnit
+	for _, file := range doc.Files {
+		for _, unit := range file.Units {
+			for _, segment := range unit.Segments {
+				if segment.Target == nil || segment.Target.Text == "" {
+					continue
+				}
+				units = append(units, xliffUnit{key: unit.ID, target: textutil.Escape(segment.Target.Text), state: segment.State})
+			}
+		}
+	}
+	return doc.TrgLang, units, nil
+}
+
+// xliffImportCommand implements the xliff-import command, which merges the
+// translations of XLIFF 2.0 files back into the language files by key and
+// records their segment states as review states. Locked keys are kept and
+// differing translations reported.
+func xliffImportCommand(fs *flag.FlagSet) func(args []string) error {
+	ef := addExtractFlags(fs)
+	outputDir := fs.String("O", "./i18n/", "Path to the directory containing the language files")
+	format := fs.String("format", "toml", "Format of the language files (toml, jsonc, resx, ts, yaml, yaml-nested, i18next, po, fluent, android, ios, i18next-ns)")
+	reviewName := fs.String("review", "", "Name of the review file in the output directory the segment states are recorded in (optional)")
+	locksName := fs.String("locks", "locks.json", "Name of the locks file in the output directory (empty to disable)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: i18n-gen xliff-import [flags] <file>.xlf ...\n")
+		fs.PrintDefaults()
+	}
+
+	return func(args []string) error {
+		outFormat, ok := emit.Formats[*format]
+		if !ok {
+			return usageErrorf("unknown format: %s", *format)
+		}
+		if len(args) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		x, err := ef.extract(false, nil)
+		if err != nil {
+			return err
+		}
+		extracted := x.entries
+		if err := applyEmptyValuePolicy(extracted, emptySource, true); err != nil {
+			return err
+		}
+
+		locks := make(lockStore)
+		if *locksName != "" {
+			if locks, err = loadLocks(filepath.Join(*outputDir, *locksName)); err != nil {
+				return fmt.Errorf("failed to load locks: %w", err)
+			}
+		}
+		reviewPath := filepath.Join(*outputDir, *reviewName)
+		store := make(reviewStore)
+		if *reviewName != "" {
+			if store, err = loadReviewStore(reviewPath); err != nil {
+				return fmt.Errorf("failed to load review states: %w", err)
+			}
+		}
+
+		failed := false
+		for _, xliffPath := range args {
+			lang, units, err := loadXLIFF(xliffPath)
+			if err != nil {
+				log.Printf("Failed to load %s: %v\n", xliffPath, err)
+				failed = true
+				continue
+			}
+			langPath := outFormat.Path(*outputDir, lang)
+			values, err := loadLanguage(outFormat, langPath, nil)
+			if err != nil {
+				log.Printf("Failed to load %s: %v\n", filepath.Base(langPath), err)
+				failed = true
+				continue
+			}
+
+			imported := 0
+			for _, unit := range units {
+				if locked, ok := locks[lang][unit.key]; ok && unit.target != locked {
+					log.Printf("Conflict: %s translation of locked key %s differs from its signed-off value %q; keeping the locked value\n", lang, unit.key, locked)
+					continue
+				}
+				values[unit.key] = unit.target
+				imported++
+				if state, ok := reviewStatesByXLIFF[unit.state]; ok && *reviewName != "" {
+					if store[lang] == nil {
+						store[lang] = make(map[string]string)
+					}
+					store[lang][unit.key] = state
+				}
+			}
+
+			if err := outFormat.Write(importEntries(extracted, values), lang, langPath, true, emit.Encoding{}, nil); err != nil {
+				log.Printf("Failed to write %s: %v\n", filepath.Base(langPath), err)
+				failed = true
+				continue
+			}
+			log.Printf("%s imported into %s (%d translations).", xliffPath, filepath.Base(langPath), imported)
+		}
+
+		if *reviewName != "" {
+			if err := writeReviewStore(store, reviewPath); err != nil {
+				return fmt.Errorf("failed to write review states: %w", err)
+			}
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// importEntries returns the entries to rewrite a language file with: the
+// extracted entries with their value as fallback, followed by the keys of the
+// file that were not extracted, so that importing never prunes.
+func importEntries(extracted []extract.Entry, values map[string]string) []extract.Entry {
+	entries := make([]extract.Entry, 0, len(values))
+	seen := make(map[string]bool, len(extracted))
+	for _, e := range extracted {
+		seen[e.Key] = true
+		if value, ok := values[e.Key]; ok {
+			e.Fallback = value
+		}
+		entries = append(entries, e)
+	}
+	var extra []string
+	for key := range values {
+		if !seen[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		entries = append(entries, extract.Entry{Key: key, Name: key, Fallback: values[key]})
+	}
+	return entries
+}